@@ -0,0 +1,250 @@
+// Package bench implements the bench command for the command line tool.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/bench/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new bench command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new bench
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured bench command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "bench",
+		Short: "Measure the registration throughput of the updater against a real or fake API server.",
+		Long:  "Measure the registration throughput of the updater against a real or fake API server.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().IntVar(&f.Concurrency, "bench.concurrency", 10, "Number of registrations executed in parallel.")
+	newCommand.cobraCommand.PersistentFlags().BoolVar(&f.Fake, "bench.fake", false, "Whether to run against an in-process fake API server instead of a real cluster.")
+	newCommand.cobraCommand.PersistentFlags().IntVar(&f.Requests, "bench.requests", 1000, "Total number of registrations to execute.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kind, "updater.kind", updater.KindAnnotation, "Resources registrations are written to. One of annotation, endpoints, endpointslice or both.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Cluster.Namespace, "service.kubernetes.cluster.namespace", "default", "Namespace of the pod or service used for registrations.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Service, "service.kubernetes.cluster.service", "k8s-endpoint-updater-bench", "Name of the service which endpoints registrations are added to. Not used with the annotation kind.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", "", "Name of the existing pod used for registrations. Not required when using the fake API server.")
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context) error {
+	var err error
+
+	podName := f.Kubernetes.Pod.Name
+	if podName == "" {
+		podName = "k8s-endpoint-updater-bench"
+	}
+
+	var k8sClient kubernetes.Interface
+	if f.Fake {
+		k8sClient = k8s.NewMockClient(f.Kubernetes.Cluster.Namespace, podName)
+	} else {
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		// The Endpoints and EndpointSlices of the service are created by the
+		// first registration. The ports are fixed, so that registrations do
+		// not read the service spec and only the write path is measured.
+		updaterConfig.CreateMissing = true
+		updaterConfig.Kind, _ = updater.ResourceKind(f.Kind)
+		updaterConfig.Ports = []corev1.EndpointPort{
+			{
+				Name:     "https",
+				Port:     443,
+				Protocol: corev1.ProtocolTCP,
+			},
+		}
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	register := func(ip net.IP) error {
+		if f.Kind == updater.KindAnnotation {
+			return newUpdater.AddAnnotations(f.Kubernetes.Cluster.Namespace, "", podName, ip)
+		}
+
+		return newUpdater.Create(ctx, f.Kubernetes.Cluster.Namespace, f.Service, []net.IP{ip})
+	}
+
+	var failures int
+	var firstErr error
+	var latencies []time.Duration
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	requests := make(chan int)
+
+	start := time.Now()
+
+	for i := 0; i < f.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for r := range requests {
+				ip := net.IPv4(10, byte(r>>16), byte(r>>8), byte(r))
+
+				s := time.Now()
+				err := register(ip)
+				d := time.Since(s)
+
+				mutex.Lock()
+				if err != nil {
+					failures++
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					latencies = append(latencies, d)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for r := 0; r < f.Requests; r++ {
+		requests <- r
+	}
+	close(requests)
+
+	wg.Wait()
+
+	// Failures usually share their cause, so only the first one is logged
+	// rather than flooding the report.
+	if firstErr != nil {
+		_ = c.logger.Log("warning", fmt.Sprintf("%d of %d registrations failed, the first one with: %#v", failures, f.Requests, microerror.Mask(firstErr)))
+	}
+
+	printReport(time.Since(start), latencies, failures)
+
+	return nil
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(float64(len(sorted)-1) * p)
+
+	return sorted[i]
+}
+
+func printReport(elapsed time.Duration, latencies []time.Duration, failures int) {
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var max time.Duration
+	if len(latencies) != 0 {
+		max = latencies[len(latencies)-1]
+	}
+
+	fmt.Printf("Concurrency:    %d\n", f.Concurrency)
+	fmt.Printf("Requests:       %d\n", f.Requests)
+	fmt.Printf("Updater kind:   %s\n", f.Kind)
+	fmt.Printf("Succeeded:      %d\n", len(latencies))
+	fmt.Printf("Failed:         %d\n", failures)
+	fmt.Printf("Elapsed:        %s\n", elapsed)
+	fmt.Printf("Throughput:     %.2f registrations/s\n", float64(len(latencies))/elapsed.Seconds())
+	fmt.Printf("Latency p50:    %s\n", percentile(latencies, 0.50))
+	fmt.Printf("Latency p90:    %s\n", percentile(latencies, 0.90))
+	fmt.Printf("Latency p99:    %s\n", percentile(latencies, 0.99))
+	fmt.Printf("Latency max:    %s\n", max)
+}
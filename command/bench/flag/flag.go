@@ -0,0 +1,43 @@
+package flag
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+)
+
+type Flag struct {
+	Concurrency int
+	Fake        bool
+	Kind        string
+	Kubernetes  kubernetes.Kubernetes
+	Requests    int
+	Service     string
+}
+
+func (f *Flag) Validate() error {
+	if f.Concurrency <= 0 {
+		return microerror.Maskf(invalidFlagsError, "concurrency must be greater than zero")
+	}
+	if f.Requests <= 0 {
+		return microerror.Maskf(invalidFlagsError, "requests must be greater than zero")
+	}
+
+	if f.Kubernetes.Cluster.Namespace == "" {
+		return microerror.Maskf(invalidFlagsError, "guest cluster namespace must not be empty")
+	}
+
+	switch f.Kind {
+	case "annotation", "both", "endpoints", "endpointslice":
+	default:
+		return microerror.Maskf(invalidFlagsError, "updater kind must be one of annotation, both, endpoints or endpointslice")
+	}
+	if f.Kind == "annotation" && !f.Fake && f.Kubernetes.Pod.Name == "" {
+		return microerror.Maskf(invalidFlagsError, "pod name must not be empty with the annotation kind when not using the fake API server")
+	}
+	if f.Kind != "annotation" && f.Service == "" {
+		return microerror.Maskf(invalidFlagsError, "service must not be empty unless the updater kind is annotation")
+	}
+
+	return nil
+}
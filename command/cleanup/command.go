@@ -0,0 +1,163 @@
+// Package cleanup implements the cleanup command for the command line tool.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/cleanup/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/cleaner"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new cleanup command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new cleanup
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured cleanup command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "cleanup",
+		Short: "Remove orphaned endpoint IPs cluster-wide. Meant to run as CronJob.",
+		Long:  "Remove orphaned endpoint IPs cluster-wide. Meant to run as CronJob. Endpoint IPs are orphaned when the pod claiming them in the ownership annotation of the Endpoints or EndpointSlices does not exist anymore.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().BoolVar(&f.DryRun, "cleanup.dryRun", false, "Whether to only report orphaned endpoint IPs without removing them.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Cluster.Namespace, "service.kubernetes.cluster.namespace", "", "Namespace to scan for orphaned endpoint IPs. All namespaces are scanned when empty.")
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context) error {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		// Orphaned IPs might have been published using any kind, and
+		// cleaning up a kind not in use is a no-op.
+		updaterConfig.Kind = updater.KindBoth
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newCleaner *cleaner.Cleaner
+	{
+		cleanerConfig := cleaner.DefaultConfig()
+
+		cleanerConfig.K8sClient = k8sClient
+		cleanerConfig.Logger = c.logger
+		cleanerConfig.Updater = newUpdater
+
+		cleanerConfig.DryRun = f.DryRun
+
+		newCleaner, err = cleaner.New(cleanerConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	orphans, err := newCleaner.Cleanup(ctx, f.Kubernetes.Cluster.Namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var ips int
+	for _, o := range orphans {
+		ips += len(o.IPs)
+	}
+
+	_ = c.logger.Log("info", fmt.Sprintf("cleaned up %d orphaned endpoint IPs", ips), "dryRun", f.DryRun)
+
+	return nil
+}
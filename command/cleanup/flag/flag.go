@@ -0,0 +1,14 @@
+package flag
+
+import (
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+)
+
+type Flag struct {
+	DryRun     bool
+	Kubernetes kubernetes.Kubernetes
+}
+
+func (f *Flag) Validate() error {
+	return nil
+}
@@ -5,16 +5,32 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/giantswarm/microerror"
-	"github.com/giantswarm/micrologger"
 
+	"github.com/giantswarm/k8s-endpoint-updater/command/bench"
+	"github.com/giantswarm/k8s-endpoint-updater/command/cleanup"
+	"github.com/giantswarm/k8s-endpoint-updater/command/delete"
+	"github.com/giantswarm/k8s-endpoint-updater/command/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/list"
+	"github.com/giantswarm/k8s-endpoint-updater/command/lookup"
+	"github.com/giantswarm/k8s-endpoint-updater/command/prestop"
+	"github.com/giantswarm/k8s-endpoint-updater/command/reap"
+	"github.com/giantswarm/k8s-endpoint-updater/command/render"
+	"github.com/giantswarm/k8s-endpoint-updater/command/status"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update"
+	"github.com/giantswarm/k8s-endpoint-updater/command/verify"
 	"github.com/giantswarm/k8s-endpoint-updater/command/version"
+	"github.com/giantswarm/k8s-endpoint-updater/command/watch"
+	"github.com/giantswarm/k8s-endpoint-updater/service/logging"
+)
+
+var (
+	f = &flag.Flag{}
 )
 
 // Config represents the configuration used to create a new root command.
 type Config struct {
 	// Dependencies.
-	Logger micrologger.Logger
+	Logger *logging.Logger
 
 	// Settings.
 	Description string
@@ -40,18 +56,124 @@ func DefaultConfig() Config {
 
 // New creates a new root command.
 func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
 	var err error
 
+	var benchCommand *bench.Command
+	{
+		benchConfig := bench.DefaultConfig()
+		benchConfig.Logger = config.Logger
+		benchCommand, err = bench.New(benchConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var cleanupCommand *cleanup.Command
+	{
+		cleanupConfig := cleanup.DefaultConfig()
+		cleanupConfig.Logger = config.Logger
+		cleanupCommand, err = cleanup.New(cleanupConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var deleteCommand *delete.Command
+	{
+		deleteConfig := delete.DefaultConfig()
+		deleteConfig.Logger = config.Logger
+		deleteCommand, err = delete.New(deleteConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var listCommand *list.Command
+	{
+		listConfig := list.DefaultConfig()
+		listConfig.Logger = config.Logger
+		listCommand, err = list.New(listConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var lookupCommand *lookup.Command
+	{
+		lookupConfig := lookup.DefaultConfig()
+		lookupConfig.Logger = config.Logger
+		lookupCommand, err = lookup.New(lookupConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var prestopCommand *prestop.Command
+	{
+		prestopConfig := prestop.DefaultConfig()
+		prestopConfig.Logger = config.Logger
+		prestopCommand, err = prestop.New(prestopConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var reapCommand *reap.Command
+	{
+		reapConfig := reap.DefaultConfig()
+		reapConfig.Logger = config.Logger
+		reapCommand, err = reap.New(reapConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var renderCommand *render.Command
+	{
+		renderConfig := render.DefaultConfig()
+		renderConfig.Logger = config.Logger
+		renderCommand, err = render.New(renderConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	var statusCommand *status.Command
+	{
+		statusConfig := status.DefaultConfig()
+		statusConfig.Logger = config.Logger
+		statusCommand, err = status.New(statusConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var updateCommand *update.Command
 	{
 		updateConfig := update.DefaultConfig()
 		updateConfig.Logger = config.Logger
+		updateConfig.GitCommit = config.GitCommit
 		updateCommand, err = update.New(updateConfig)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
 	}
 
+	var verifyCommand *verify.Command
+	{
+		verifyConfig := verify.DefaultConfig()
+		verifyConfig.Logger = config.Logger
+		verifyCommand, err = verify.New(verifyConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	var versionCommand *version.Command
 	{
 		versionConfig := version.DefaultConfig()
@@ -67,45 +189,186 @@ func New(config Config) (*Command, error) {
 		}
 	}
 
+	var watchCommand *watch.Command
+	{
+		watchConfig := watch.DefaultConfig()
+		watchConfig.Logger = config.Logger
+		watchCommand, err = watch.New(watchConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
 	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
 		// Internals.
+		benchCommand:   benchCommand,
+		cleanupCommand: cleanupCommand,
 		cobraCommand:   nil,
+		deleteCommand:  deleteCommand,
+		listCommand:    listCommand,
+		lookupCommand:  lookupCommand,
+		prestopCommand: prestopCommand,
+		reapCommand:    reapCommand,
+		renderCommand:  renderCommand,
+		statusCommand:  statusCommand,
 		updateCommand:  updateCommand,
+		verifyCommand:  verifyCommand,
 		versionCommand: versionCommand,
+		watchCommand:   watchCommand,
 	}
 
 	newCommand.cobraCommand = &cobra.Command{
-		Use:   config.Name,
-		Short: config.Description,
-		Long:  config.Description,
-		Run:   newCommand.Execute,
+		Use:               config.Name,
+		Short:             config.Description,
+		Long:              config.Description,
+		PersistentPreRunE: newCommand.PersistentPreRunE,
+		Run:               newCommand.Execute,
 	}
 
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Log.Format, "log.format", logging.FormatJSON, "Format of the log lines. Must be one of json or logfmt.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Log.Level, "log.level", logging.LevelInfo, "Lowest level being logged. Must be one of debug, info, warn or error.")
+
+	newCommand.cobraCommand.AddCommand(newCommand.benchCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.cleanupCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.deleteCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.listCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.lookupCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.prestopCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.reapCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.renderCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.statusCommand.CobraCommand())
 	newCommand.cobraCommand.AddCommand(newCommand.updateCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.verifyCommand.CobraCommand())
 	newCommand.cobraCommand.AddCommand(newCommand.versionCommand.CobraCommand())
+	newCommand.cobraCommand.AddCommand(newCommand.watchCommand.CobraCommand())
 
 	return newCommand, nil
 }
 
 type Command struct {
+	// Dependencies.
+	logger *logging.Logger
+
 	// Internals.
+	benchCommand   *bench.Command
+	cleanupCommand *cleanup.Command
 	cobraCommand   *cobra.Command
+	deleteCommand  *delete.Command
+	listCommand    *list.Command
+	lookupCommand  *lookup.Command
+	prestopCommand *prestop.Command
+	reapCommand    *reap.Command
+	renderCommand  *render.Command
+	statusCommand  *status.Command
 	updateCommand  *update.Command
+	verifyCommand  *verify.Command
 	versionCommand *version.Command
+	watchCommand   *watch.Command
+}
+
+func (c *Command) BenchCommand() *bench.Command {
+	return c.benchCommand
+}
+
+func (c *Command) CleanupCommand() *cleanup.Command {
+	return c.cleanupCommand
 }
 
 func (c *Command) CobraCommand() *cobra.Command {
 	return c.cobraCommand
 }
 
+func (c *Command) DeleteCommand() *delete.Command {
+	return c.deleteCommand
+}
+
+// PersistentPreRunE applies the flags of the root command before any command
+// is executed.
+func (c *Command) PersistentPreRunE(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	err = c.logger.SetFormat(f.Log.Format)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	err = c.logger.SetLevel(f.Log.Level)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Errors returned by the commands have already been logged, so cobra
+	// only prints errors which occur before, e.g. when parsing flags.
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	return nil
+}
+
 func (c *Command) Execute(cmd *cobra.Command, args []string) {
 	cmd.HelpFunc()(cmd, nil)
 }
 
+// ExitCode returns the exit code of the process for the given error returned
+// by the executed command. The verify command distinguishes drift from
+// failures, so that scripts are able to tell them apart. The update command
+// exits with a distinct code once it gave up retrying.
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case verify.IsDriftDetected(err):
+		return verify.ExitCodeDrift
+	case verify.IsVerificationFailed(err):
+		return verify.ExitCodeFailure
+	case update.IsRetryBudgetExhausted(err):
+		return update.ExitCodeRetryBudgetExhausted
+	default:
+		return 1
+	}
+}
+
+func (c *Command) ListCommand() *list.Command {
+	return c.listCommand
+}
+
+func (c *Command) LookupCommand() *lookup.Command {
+	return c.lookupCommand
+}
+
+func (c *Command) PrestopCommand() *prestop.Command {
+	return c.prestopCommand
+}
+
+func (c *Command) ReapCommand() *reap.Command {
+	return c.reapCommand
+}
+
+func (c *Command) RenderCommand() *render.Command {
+	return c.renderCommand
+}
+
+func (c *Command) StatusCommand() *status.Command {
+	return c.statusCommand
+}
+
 func (c *Command) UpdateCommand() *update.Command {
 	return c.updateCommand
 }
 
+func (c *Command) VerifyCommand() *verify.Command {
+	return c.verifyCommand
+}
+
 func (c *Command) VersionCommand() *version.Command {
 	return c.versionCommand
 }
+
+func (c *Command) WatchCommand() *watch.Command {
+	return c.watchCommand
+}
@@ -0,0 +1,230 @@
+// Package delete implements the delete command for the command line tool.
+package delete
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/delete/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new delete command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new delete
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured delete command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "delete",
+		Short: "Withdraw endpoint IPs from services.",
+		Long:  "Withdraw endpoint IPs from services, e.g. from a preStop hook or for manual cleanup. The IPs are looked up using the configured provider unless given explicitly. With a pod name only the IPs claimed by that pod are removed, otherwise the given IPs are removed regardless of their owners.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().BoolVar(&f.DryRun, "dry-run", false, "Whether to only print the requests which would be sent, without mutating the cluster.")
+	newCommand.cobraCommand.PersistentFlags().StringSliceVar(&f.IPs, "ip", nil, "Endpoint IP to remove. Can be repeated or comma separated. The IPs are looked up using the configured provider when not given.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kind, "updater.kind", updater.KindBoth, "Resources the endpoint IP is removed from. One of annotation, endpoints, endpointslice or both.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Namespace, "namespace", "default", "Namespace of the services. It is the one of the KVM pod as well, unless the pod namespace is given.")
+	newCommand.cobraCommand.PersistentFlags().StringSliceVar(&f.Services, "service", nil, "Name of the service the endpoint IPs are removed from. Can be repeated or comma separated.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod whose endpoint IPs are removed. Defaults to the value of POD_NAME environment variable.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Pod.Namespace, "service.kubernetes.pod.namespace", downward.PodNamespace(), "Namespace of the guest cluster kvm Kubernetes pod. Defaults to the value of POD_NAMESPACE environment variable or the service account namespace. The namespace of the services is used when empty.")
+
+	cmdprovider.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Provider)
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context) error {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		updaterConfig.DryRun = f.DryRun
+
+		var managed bool
+		updaterConfig.Kind, managed = updater.ResourceKind(f.Kind)
+
+		if managed {
+			// Only the claims of the given pod are released, so that IPs
+			// other pods still publish are kept. It has to be identified
+			// the same way the update command does.
+			if f.Kubernetes.Pod.Name != "" {
+				pod, err := k8sClient.CoreV1().Pods(f.PodNamespace()).Get(f.Kubernetes.Pod.Name, metav1.GetOptions{})
+				if err != nil {
+					return microerror.Mask(err)
+				}
+
+				updaterConfig.Owner = string(pod.UID)
+			}
+		}
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if f.Kind == updater.KindAnnotation {
+		err := newUpdater.RemoveAnnotations(f.PodNamespace(), f.Kubernetes.Pod.Name)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_ = c.logger.Log("info", fmt.Sprintf("removed annotations from the KVM pod '%s'", f.Kubernetes.Pod.Name))
+
+		return nil
+	}
+
+	// Annotations are removed without knowing the IPs, so the provider is
+	// only asked for the other kinds and a broken provider does not prevent
+	// removing annotations.
+	ips, err := c.ips(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// All services are updated, even if some of them fail, so that a single
+	// broken service does not affect the others.
+	var failed int
+	for _, service := range f.Services {
+		err := newUpdater.Delete(ctx, f.Namespace, service, ips)
+		if err != nil {
+			failed++
+			_ = c.logger.Log("warning", fmt.Sprintf("withdrawing endpoint IP for service '%s' failed: %#v", service, microerror.Mask(err)))
+			continue
+		}
+
+		_ = c.logger.Log("info", fmt.Sprintf("withdrew endpoint IP for service '%s'", service), "ips", updater.JoinIPs(ips), "dryRun", f.DryRun)
+	}
+
+	if failed != 0 {
+		return microerror.Maskf(executionFailedError, "withdrawing endpoint IP failed for %d of %d services", failed, len(f.Services))
+	}
+
+	return nil
+}
+
+// ips returns the endpoint IPs to remove. Explicitly given IPs take precedence
+// over the provider lookup.
+func (c *Command) ips(ctx context.Context) ([]net.IP, error) {
+	if len(f.IPs) != 0 {
+		var ips []net.IP
+		for _, s := range f.IPs {
+			ips = append(ips, net.ParseIP(s))
+		}
+
+		return ips, nil
+	}
+
+	providerConfig := cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.Kubernetes.Pod.Name,
+	}
+
+	newProvider, err := cmdprovider.New(providerConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ips, err := cmdprovider.LookupAll(ctx, newProvider)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ips, nil
+}
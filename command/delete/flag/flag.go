@@ -0,0 +1,61 @@
+package flag
+
+import (
+	"net"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+)
+
+type Flag struct {
+	DryRun     bool
+	IPs        []string
+	Kind       string
+	Kubernetes kubernetes.Kubernetes
+	Namespace  string
+	Provider   provider.Provider
+	Services   []string
+}
+
+func (f *Flag) Validate() error {
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagsError, "namespace must not be empty")
+	}
+	if len(f.Services) == 0 {
+		return microerror.Maskf(invalidFlagsError, "service must not be empty")
+	}
+	for _, s := range f.Services {
+		if s == "" {
+			return microerror.Maskf(invalidFlagsError, "service must not be empty")
+		}
+	}
+
+	for _, ip := range f.IPs {
+		if net.ParseIP(ip) == nil {
+			return microerror.Maskf(invalidFlagsError, "ip %#q must be a valid IP address", ip)
+		}
+	}
+
+	switch f.Kind {
+	case "annotation", "both", "endpoints", "endpointslice":
+	default:
+		return microerror.Maskf(invalidFlagsError, "updater kind must be one of annotation, both, endpoints or endpointslice")
+	}
+	if f.Kind == "annotation" && f.Kubernetes.Pod.Name == "" {
+		return microerror.Maskf(invalidFlagsError, "pod name must not be empty with the annotation kind")
+	}
+
+	return nil
+}
+
+// PodNamespace returns the namespace of the KVM pod. It defaults to the
+// namespace of the services, like the update command does.
+func (f *Flag) PodNamespace() string {
+	if f.Kubernetes.Pod.Namespace != "" {
+		return f.Kubernetes.Pod.Namespace
+	}
+
+	return f.Namespace
+}
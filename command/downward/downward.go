@@ -0,0 +1,48 @@
+// Package downward implements the defaults of the pod identity flags based on
+// the downward API. Running as a sidecar of the KVM pod, the pod name,
+// namespace, UID and node name are conventionally exposed as environment
+// variables, the namespace is also part of the mounted service account.
+package downward
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	// NodeNameEnv is the environment variable conventionally holding
+	// spec.nodeName of the pod.
+	NodeNameEnv = "NODE_NAME"
+	// PodNameEnv is the environment variable conventionally holding
+	// metadata.name of the pod.
+	PodNameEnv = "POD_NAME"
+	// PodNamespaceEnv is the environment variable conventionally holding
+	// metadata.namespace of the pod.
+	PodNamespaceEnv = "POD_NAMESPACE"
+	// PodUIDEnv is the environment variable conventionally holding
+	// metadata.uid of the pod.
+	PodUIDEnv = "POD_UID"
+)
+
+const (
+	// NamespaceFile is the file of the mounted service account holding the
+	// namespace of the pod.
+	NamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// PodNamespace returns the namespace of the pod this process runs in. The
+// environment variable takes precedence over the service account namespace.
+// It is empty when running outside of Kubernetes.
+func PodNamespace() string {
+	if n := os.Getenv(PodNamespaceEnv); n != "" {
+		return n
+	}
+
+	b, err := ioutil.ReadFile(NamespaceFile)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(b))
+}
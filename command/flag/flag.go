@@ -0,0 +1,25 @@
+package flag
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/flag/log"
+	"github.com/giantswarm/k8s-endpoint-updater/service/logging"
+)
+
+type Flag struct {
+	Log log.Log
+}
+
+func (f *Flag) Validate() error {
+	err := logging.ValidateFormat(f.Log.Format)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "log format must be one of json or logfmt")
+	}
+	err = logging.ValidateLevel(f.Log.Level)
+	if err != nil {
+		return microerror.Maskf(invalidFlagsError, "log level must be one of debug, info, warn or error")
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package k8s implements the construction of Kubernetes clients based on the
+// Kubernetes flags shared by the subcommands of the command line tool.
+package k8s
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/giantswarm/k8sclient"
+	"github.com/giantswarm/k8sclient/k8srestconfig"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/pflag"
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	// Register the auth provider plugins, e.g. gcp, azure and oidc, which
+	// kubeconfig files of managed clusters refer to.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	flag "github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+)
+
+const (
+	// KubeconfigEnv is the environment variable conventionally holding the
+	// kubeconfig file paths.
+	KubeconfigEnv = "KUBECONFIG"
+	// NoProxyEnv is the environment variable conventionally holding the hosts
+	// which must not be connected to through a proxy.
+	NoProxyEnv = "NO_PROXY"
+)
+
+// Config represents the configuration used to create a new Kubernetes client.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+	Flag flag.Kubernetes
+}
+
+// AddFlags registers the flags used to connect to Kubernetes on the given flag
+// set.
+func AddFlags(flags *pflag.FlagSet, f *flag.Kubernetes) {
+	flags.StringVar(&f.Address, "service.kubernetes.address", "http://127.0.0.1:6443", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
+	flags.BoolVar(&f.InCluster, "service.kubernetes.inCluster", false, "Whether to use the in-cluster config to authenticate with Kubernetes.")
+	flags.StringVar(&f.TLS.CaFile, "service.kubernetes.tls.caFile", "", "Certificate authority file path to use to authenticate with Kubernetes.")
+	flags.StringVar(&f.TLS.CrtFile, "service.kubernetes.tls.crtFile", "", "Certificate file path to use to authenticate with Kubernetes.")
+	flags.StringVar(&f.TLS.KeyFile, "service.kubernetes.tls.keyFile", "", "Key file path to use to authenticate with Kubernetes.")
+	flags.StringVar(&f.TLS.TokenFile, "service.kubernetes.tls.tokenFile", "", "Bearer token file path to use to authenticate with Kubernetes.")
+	flags.StringVar(&f.Token, "service.kubernetes.token", "", "Bearer token to use to authenticate with Kubernetes. Prefer the token file, since flags are visible in the process list.")
+	flags.StringVar(&f.Exec.APIVersion, "service.kubernetes.exec.apiVersion", "client.authentication.k8s.io/v1beta1", "API version of the exec credential plugin.")
+	flags.StringSliceVar(&f.Exec.Args, "service.kubernetes.exec.args", nil, "Arguments passed to the exec credential plugin.")
+	flags.StringVar(&f.Exec.Command, "service.kubernetes.exec.command", "", "Exec credential plugin used to obtain credentials to authenticate with Kubernetes, e.g. aws-iam-authenticator.")
+	flags.StringVar(&f.Proxy.NoProxy, "service.kubernetes.proxy.noProxy", os.Getenv(NoProxyEnv), "Comma separated hosts, domains and CIDRs connected to without proxy. Defaults to the value of NO_PROXY environment variable.")
+	flags.StringVar(&f.Proxy.URL, "service.kubernetes.proxy.url", "", "URL of the HTTP(S) proxy used to connect to Kubernetes. HTTPS_PROXY and NO_PROXY environment variables are honored when empty.")
+	flags.Float32Var(&f.QPS, "service.kubernetes.qps", 0, "Maximum queries per second of the Kubernetes client rate limiter. The client default is used when zero.")
+	flags.IntVar(&f.Burst, "service.kubernetes.burst", 0, "Maximum burst of the Kubernetes client rate limiter. The client default is used when zero.")
+	flags.StringVar(&f.Kubeconfig, "kubeconfig", os.Getenv(KubeconfigEnv), "Kubeconfig file paths to use to connect to Kubernetes, separated like PATH. Takes precedence over the address and TLS flags. Defaults to the value of KUBECONFIG environment variable.")
+	flags.StringVar(&f.Context, "context", "", "Kubeconfig context to use. Defaults to the current context.")
+}
+
+// NewRestConfig creates a new rest config based on the given Kubernetes flags.
+func NewRestConfig(config Config) (*rest.Config, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	var err error
+
+	var restConfig *rest.Config
+	if config.Flag.Kubeconfig != "" {
+		restConfig, err = newKubeconfigRestConfig(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	} else {
+		restConfig, err = newFlagRestConfig(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	// Bearer tokens authenticate with clusters which do not issue client
+	// certificates. They take precedence over any credentials configured
+	// otherwise.
+	if config.Flag.Token != "" {
+		restConfig.BearerToken = config.Flag.Token
+		restConfig.BearerTokenFile = ""
+	} else if config.Flag.TLS.TokenFile != "" {
+		restConfig.BearerToken = ""
+		restConfig.BearerTokenFile = config.Flag.TLS.TokenFile
+	}
+
+	// Exec credential plugins obtain short lived credentials of managed
+	// clusters, e.g. using aws-iam-authenticator. Kubeconfig files may
+	// configure them as well.
+	if config.Flag.Exec.Command != "" {
+		restConfig.ExecProvider = &clientcmdapi.ExecConfig{
+			APIVersion: config.Flag.Exec.APIVersion,
+			Args:       config.Flag.Exec.Args,
+			Command:    config.Flag.Exec.Command,
+		}
+	}
+
+	// The client rate limiter defaults differ between the kubeconfig and flag
+	// based configs. They are only overwritten when configured explicitly.
+	if config.Flag.QPS > 0 {
+		restConfig.QPS = config.Flag.QPS
+	}
+	if config.Flag.Burst > 0 {
+		restConfig.Burst = config.Flag.Burst
+	}
+
+	// The Kubernetes transport honors HTTPS_PROXY and NO_PROXY already. An
+	// explicitly configured proxy takes precedence over the environment.
+	if config.Flag.Proxy.URL != "" {
+		_, err := url.Parse(config.Flag.Proxy.URL)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "proxy URL %#q must be a valid URL: %s", config.Flag.Proxy.URL, err.Error())
+		}
+
+		proxyConfig := &httpproxy.Config{
+			HTTPProxy:  config.Flag.Proxy.URL,
+			HTTPSProxy: config.Flag.Proxy.URL,
+			NoProxy:    config.Flag.Proxy.NoProxy,
+		}
+		proxyFunc := proxyConfig.ProxyFunc()
+
+		restConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			t, ok := rt.(*http.Transport)
+			if !ok {
+				return rt
+			}
+
+			t = t.Clone()
+			t.Proxy = func(r *http.Request) (*url.URL, error) {
+				return proxyFunc(r.URL)
+			}
+
+			return t
+		})
+	}
+
+	return restConfig, nil
+}
+
+// newFlagRestConfig creates the rest config based on the address, in-cluster
+// and TLS flags.
+func newFlagRestConfig(config Config) (*rest.Config, error) {
+	c := k8srestconfig.Config{
+		Logger: config.Logger,
+
+		Address:   config.Flag.Address,
+		InCluster: config.Flag.InCluster,
+		TLS: k8srestconfig.ConfigTLS{
+			CAFile:  config.Flag.TLS.CaFile,
+			CrtFile: config.Flag.TLS.CrtFile,
+			KeyFile: config.Flag.TLS.KeyFile,
+		},
+	}
+
+	restConfig, err := k8srestconfig.New(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return restConfig, nil
+}
+
+// newKubeconfigRestConfig loads the rest config from the kubeconfig files
+// given in the flags the same way kubectl does, optionally using another
+// than the current context.
+func newKubeconfigRestConfig(config Config) (*rest.Config, error) {
+	loadingRules := &clientcmd.ClientConfigLoadingRules{
+		Precedence: filepath.SplitList(config.Flag.Kubeconfig),
+	}
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: config.Flag.Context,
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_ = config.Logger.Log("debug", "loaded kubeconfig", "context", config.Flag.Context, "host", restConfig.Host)
+
+	return restConfig, nil
+}
+
+// NewClient creates a new Kubernetes client based on the given Kubernetes
+// flags. In case the mock flag is set an in-process fake API server is used
+// instead of a real cluster.
+func NewClient(config Config) (kubernetes.Interface, error) {
+	if config.Flag.Mock {
+		if config.Logger != nil {
+			_ = config.Logger.Log("info", "using in-process mock API server")
+		}
+
+		namespace := config.Flag.Pod.Namespace
+		if namespace == "" {
+			namespace = config.Flag.Cluster.Namespace
+		}
+
+		return NewMockClient(namespace, config.Flag.Pod.Name), nil
+	}
+
+	restConfig, err := NewRestConfig(config)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c := k8sclient.ClientsConfig{
+		Logger: config.Logger,
+
+		RestConfig: restConfig,
+	}
+
+	k8sClients, err := k8sclient.NewClients(c)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return k8sClients.K8sClient(), nil
+}
+
+// NewMockClient creates a fake clientset backed Kubernetes client which is
+// seeded with the given pod, if any, so that the update code paths can be
+// exercised without cluster access.
+func NewMockClient(namespace, podName string) kubernetes.Interface {
+	if podName == "" {
+		return fake.NewSimpleClientset()
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+		},
+	}
+
+	return fake.NewSimpleClientset(pod)
+}
@@ -0,0 +1,180 @@
+// Package list implements the list command for the command line tool.
+package list
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/command/list/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new list command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new list
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured list command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "list",
+		Short: "List the Endpoints managed by updater instances.",
+		Long:  "List the Endpoints managed by updater instances across namespaces, along with their addresses and when they were last updated, to audit what the fleet of updaters maintains.",
+		RunE:  newCommand.Execute,
+	}
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Cluster.Namespace, "service.kubernetes.cluster.namespace", "", "Namespace to list managed Endpoints of. All namespaces are listed when empty.")
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	err = c.execute(os.Stdout)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(w io.Writer) error {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	list, err := k8sClient.CoreV1().Endpoints(f.Kubernetes.Cluster.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var managed []corev1.Endpoints
+	for _, endpoints := range list.Items {
+		if isManaged(endpoints) {
+			managed = append(managed, endpoints)
+		}
+	}
+
+	sort.Slice(managed, func(i, j int) bool {
+		if managed[i].Namespace != managed[j].Namespace {
+			return managed[i].Namespace < managed[j].Namespace
+		}
+		return managed[i].Name < managed[j].Name
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "NAMESPACE\tSERVICE\tADDRESSES\tLAST UPDATED")
+	for _, endpoints := range managed {
+		var addresses []string
+		for _, subset := range endpoints.Subsets {
+			for _, a := range subset.Addresses {
+				addresses = append(addresses, a.IP)
+			}
+			for _, a := range subset.NotReadyAddresses {
+				addresses = append(addresses, a.IP+" (not ready)")
+			}
+		}
+		if len(addresses) == 0 {
+			addresses = []string{"<none>"}
+		}
+
+		updated := "-"
+		if t := updater.LastUpdated(&endpoints); !t.IsZero() {
+			updated = duration.HumanDuration(time.Since(t)) + " ago"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", endpoints.Namespace, endpoints.Name, strings.Join(addresses, ","), updated)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// isManaged returns whether the given Endpoints are maintained by updater
+// instances, which record their ownership or updates in annotations.
+func isManaged(endpoints corev1.Endpoints) bool {
+	for _, k := range []string{updater.AnnotationOwners, updater.AnnotationUpdated} {
+		if _, ok := endpoints.Annotations[k]; ok {
+			return true
+		}
+	}
+
+	return false
+}
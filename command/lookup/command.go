@@ -0,0 +1,177 @@
+// Package lookup implements the lookup command for the command line tool.
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/lookup/flag"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/output"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new lookup command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new lookup
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured lookup command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "lookup",
+		Short: "Print the endpoint IPs looked up by the provider.",
+		Long:  "Print the endpoint IPs looked up by the configured provider, one per line or as JSON. No Kubernetes credentials are needed, so scripts and init containers can reuse the lookup.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Output, "output", output.FormatText, "Format of the looked up IPs. One of text or json.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.PodName, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod, which the etcd provider looks up. Defaults to the value of POD_NAME environment variable.")
+
+	cmdprovider.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Provider)
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx, os.Stdout)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// lookupResult is the JSON output of the lookup command.
+type lookupResult struct {
+	IPs  []string    `json:"ips"`
+	Pods []lookupPod `json:"pods"`
+}
+
+// lookupPod is the pod info returned by the provider. Fields the provider is
+// not able to observe are omitted.
+type lookupPod struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	NodeName  string `json:"nodeName,omitempty"`
+}
+
+func (c *Command) execute(ctx context.Context, w io.Writer) error {
+	providerConfig := cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.PodName,
+	}
+
+	newProvider, err := cmdprovider.New(providerConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	pods, err := newProvider.Lookup(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if len(pods) == 0 {
+		return microerror.Maskf(executionFailedError, "provider returned no pods")
+	}
+
+	result := lookupResult{IPs: []string{}, Pods: []lookupPod{}}
+	for _, p := range pods {
+		result.IPs = append(result.IPs, p.IP.String())
+
+		pod := lookupPod{
+			IP:        p.IP.String(),
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			NodeName:  p.NodeName,
+		}
+		if p.MAC != nil {
+			pod.MAC = p.MAC.String()
+		}
+		result.Pods = append(result.Pods, pod)
+	}
+
+	if f.Output == output.FormatJSON {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	for _, ip := range result.IPs {
+		_, err := fmt.Fprintln(w, ip)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
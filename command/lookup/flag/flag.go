@@ -0,0 +1,26 @@
+package flag
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+)
+
+type Flag struct {
+	Output   string
+	PodName  string
+	Provider provider.Provider
+}
+
+func (f *Flag) Validate() error {
+	switch f.Output {
+	case "json", "text":
+	default:
+		return microerror.Maskf(invalidFlagsError, "output format must be one of json or text")
+	}
+	if f.Provider.Kind == "" {
+		return microerror.Maskf(invalidFlagsError, "provider kind must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package prestop implements the prestop command for the command line tool.
+package prestop
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/command/prestop/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new prestop command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new prestop
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured prestop command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "prestop",
+		Short: "Withdraw the endpoint IPs of the KVM pod from a preStop hook.",
+		Long:  "Withdraw the endpoint IPs of the KVM pod from a preStop hook. The IPs are published as not ready addresses for the configured drain duration first, so that clients stop using them before they are removed and the main process receives SIGTERM. Only the IPs claimed by the pod are removed. The services default to the ones recorded on the pod when the cleanup finalizer is enabled.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().DurationVar(&f.DrainDuration, "prestop.drainDuration", 10*time.Second, "Time the endpoint IPs are published as not ready addresses, so that connections drain, before they are removed. Has to stay below the termination grace period of the pod.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kind, "updater.kind", updater.KindBoth, "Resources the endpoint IPs are removed from. One of annotation, endpoints, endpointslice or both.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Namespace, "namespace", "default", "Namespace of the services and the KVM pod.")
+	newCommand.cobraCommand.PersistentFlags().StringSliceVar(&f.Services, "service", nil, "Name of the service the endpoint IPs are removed from. Can be repeated or comma separated. Services outside of the namespace are given as namespace/service. Defaults to the services recorded on the KVM pod.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod whose endpoint IPs are removed. Defaults to the value of POD_NAME environment variable.")
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context) error {
+	newUpdater, k8sClient, err := c.newUpdater()
+	if err != nil {
+		c.drain(ctx)
+		return microerror.Mask(err)
+	}
+
+	// Annotations have no notion of readiness, so they are removed right
+	// away. The drain duration is waited for even if anything failed, so that
+	// the main process is not terminated any earlier than configured.
+	if f.Kind == updater.KindAnnotation {
+		err := newUpdater.RemoveAnnotations(f.Namespace, f.Kubernetes.Pod.Name)
+		if err == nil {
+			_ = c.logger.Log("info", fmt.Sprintf("removed annotations from the KVM pod '%s'", f.Kubernetes.Pod.Name))
+		}
+
+		c.drain(ctx)
+
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	// The pod is identified by its UID the same way the update command claims
+	// endpoint IPs, so that IPs other pods still publish are kept.
+	pod, err := k8sClient.CoreV1().Pods(f.Namespace).Get(f.Kubernetes.Pod.Name, metav1.GetOptions{})
+	if err != nil {
+		c.drain(ctx)
+		return microerror.Mask(err)
+	}
+
+	services := f.Services
+	if len(services) == 0 {
+		for _, s := range strings.Split(pod.GetAnnotations()[updater.AnnotationService], ",") {
+			if s != "" {
+				services = append(services, s)
+			}
+		}
+	}
+	if len(services) == 0 {
+		c.drain(ctx)
+		return microerror.Maskf(executionFailedError, "no services given and none recorded on the KVM pod '%s'", f.Kubernetes.Pod.Name)
+	}
+
+	// Removing the endpoint IPs right away would drop the connections still
+	// using them. They are published as not ready addresses first, so that
+	// no new connections are routed to them while the existing ones migrate
+	// during the drain duration. A failed demotion does not prevent removing
+	// the endpoint IPs afterwards.
+	demoteErr := c.forEachService(services, "demoting", "demoted", func(namespace, service string) ([]net.IP, error) {
+		return newUpdater.DemoteOwned(ctx, namespace, service, string(pod.UID))
+	})
+
+	c.drain(ctx)
+
+	err = c.forEachService(services, "withdrawing", "withdrew", func(namespace, service string) ([]net.IP, error) {
+		return newUpdater.DeleteOwned(ctx, namespace, service, string(pod.UID))
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if demoteErr != nil {
+		return microerror.Mask(demoteErr)
+	}
+
+	return nil
+}
+
+// drain waits for the configured drain duration or until the given context is
+// cancelled.
+func (c *Command) drain(ctx context.Context) {
+	_ = c.logger.Log("info", fmt.Sprintf("waiting %s for connections to drain", f.DrainDuration))
+	select {
+	case <-time.After(f.DrainDuration):
+	case <-ctx.Done():
+	}
+}
+
+// forEachService applies the given change to the endpoint IPs of all given
+// services. All services are changed, even if some of them fail, so that a
+// single broken service does not affect the others.
+func (c *Command) forEachService(services []string, verb, past string, change func(namespace, service string) ([]net.IP, error)) error {
+	var failed int
+	for _, service := range services {
+		namespace := f.Namespace
+		if i := strings.Index(service, "/"); i >= 0 {
+			namespace, service = service[:i], service[i+1:]
+		}
+
+		ips, err := change(namespace, service)
+		if err != nil {
+			failed++
+			_ = c.logger.Log("warning", fmt.Sprintf("%s endpoint IP for service '%s/%s' failed: %#v", verb, namespace, service, microerror.Mask(err)))
+			continue
+		}
+
+		_ = c.logger.Log("info", fmt.Sprintf("%s endpoint IP for service '%s/%s'", past, namespace, service), "ips", updater.JoinIPs(ips))
+	}
+
+	if failed != 0 {
+		return microerror.Maskf(executionFailedError, "%s endpoint IP failed for %d of %d services", verb, failed, len(services))
+	}
+
+	return nil
+}
+
+// newUpdater creates the updater removing the endpoint IPs of the KVM pod
+// along with the Kubernetes client it uses.
+func (c *Command) newUpdater() (*updater.Updater, kubernetes.Interface, error) {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		updaterConfig.Kind, _ = updater.ResourceKind(f.Kind)
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+	}
+
+	return newUpdater, k8sClient, nil
+}
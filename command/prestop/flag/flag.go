@@ -0,0 +1,42 @@
+package flag
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+)
+
+type Flag struct {
+	DrainDuration time.Duration
+	Kind          string
+	Kubernetes    kubernetes.Kubernetes
+	Namespace     string
+	Services      []string
+}
+
+func (f *Flag) Validate() error {
+	if f.DrainDuration < 0 {
+		return microerror.Maskf(invalidFlagsError, "drain duration must not be negative")
+	}
+	if f.Kubernetes.Pod.Name == "" {
+		return microerror.Maskf(invalidFlagsError, "pod name must not be empty")
+	}
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagsError, "namespace must not be empty")
+	}
+	for _, s := range f.Services {
+		if s == "" {
+			return microerror.Maskf(invalidFlagsError, "service must not be empty")
+		}
+	}
+
+	switch f.Kind {
+	case "annotation", "both", "endpoints", "endpointslice":
+	default:
+		return microerror.Maskf(invalidFlagsError, "updater kind must be one of annotation, both, endpoints or endpointslice")
+	}
+
+	return nil
+}
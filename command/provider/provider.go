@@ -0,0 +1,532 @@
+// Package provider implements the construction of endpoint IP providers based
+// on the provider flags shared by the subcommands of the command line tool.
+package provider
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	k8sflag "github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+	flag "github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/arp"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/bpf"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/bridge"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/chain"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/composite"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/conntrack"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/dns"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/env"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/etcd"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/exec"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/fdb"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/file"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/http"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/kvmconfig"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/netneighbor"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/route"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/scan"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/static"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/vrrp"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/whereabouts"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+	Flag flag.Provider
+	// PodName is the name of the KVM pod, which the etcd provider looks up.
+	PodName string
+}
+
+// AddFlags registers the provider flags on the given flag set.
+func AddFlags(flags *pflag.FlagSet, f *flag.Provider) {
+	flags.StringVar(&f.ARP.Interface, "provider.arp.interface", "", "Bridge interface whose neighbor entries the arp provider considers.")
+	flags.StringVar(&f.ARP.MACPrefix, "provider.arp.macPrefix", "", "MAC prefix neighbor entries have to match for the arp provider, e.g. 52:54:00 for QEMU guests. All entries match when empty.")
+	flags.StringVar(&f.ARP.Path, "provider.arp.path", "/proc/net/arp", "Path of the IPv4 neighbor table exposed by the kernel.")
+	flags.StringVar(&f.BPF.Interface, "provider.bpf.interface", "", "Bridge interface observed for guest VM traffic.")
+	flags.StringVar(&f.BPF.MAC, "provider.bpf.mac", "", "MAC address of the guest VM used to filter observed traffic.")
+	flags.DurationVar(&f.BPF.Timeout, "provider.bpf.timeout", 30*time.Second, "Maximum time a single lookup waits for guest VM traffic.")
+	flags.StringSliceVar(&f.Bridge.Names, "provider.bridge.name", nil, "Bridge name of the guest cluster VM on the host network. Can be repeated or comma separated and may be a glob pattern like br-* to publish the IPs of all guest VMs on the host.")
+	flags.IntVar(&f.Bridge.Offset, "provider.bridge.offset", 1, "Offset added to the bridge IP to derive the guest VM IP. May be negative.")
+	flags.StringVar(&f.Bridge.Pattern, "provider.bridge.pattern", "", "Regular expression bridge names have to match entirely, e.g. br-.*, as an alternative to listing the bridges by name.")
+	flags.StringVar(&f.Bridge.PreferredCIDR, "provider.bridge.preferredCIDR", "", "Network, e.g. 10.0.0.0/8, the bridge IP is preferably chosen from in case the bridge has multiple addresses assigned.")
+	flags.DurationVar(&f.Bridge.WaitTimeout, "provider.bridge.waitTimeout", 0, "Maximum time to wait for the bridges to appear and have an address assigned. The lookup fails immediately when zero.")
+	flags.BoolVar(&f.Bridge.Watch, "provider.bridge.watch", false, "Whether to subscribe to link and address changes via netlink and publish changed IPs immediately.")
+	flags.BoolVar(&f.Conntrack.Confirm, "provider.conntrack.confirm", false, "Whether to confirm the IP found by the configured provider against the host conntrack table.")
+	flags.StringVar(&f.Conntrack.Interface, "provider.conntrack.interface", "", "Bridge interface whose subnet limits the flows considered by the conntrack provider.")
+	flags.StringVar(&f.Conntrack.Path, "provider.conntrack.path", "/proc/net/nf_conntrack", "Path of the conntrack table exposed by the kernel.")
+	flags.StringSliceVar(&f.DNS.Hostnames, "provider.dns.hostnames", nil, "Hostnames the dns provider resolves to A and AAAA records. Can be repeated or comma separated.")
+	flags.StringVar(&f.DNS.Server, "provider.dns.server", "", "Address of the DNS server queried by the dns provider, e.g. 10.0.0.53:53. The system resolver is used when empty.")
+	flags.StringVar(&f.DNS.SRV, "provider.dns.srv", "", "SRV record whose targets the dns provider resolves, e.g. _https._tcp.master.abc12.example.com.")
+	flags.DurationVar(&f.DNS.Timeout, "provider.dns.timeout", 5*time.Second, "Maximum duration of a single lookup of the dns provider.")
+	flags.StringVar(&f.Env.Format, "provider.env.format", env.FormatIndexed, "Format the env provider reads pods in. Either indexed, reading variables like K8S_ENDPOINT_UPDATER_POD_0_IP, or json, reading a JSON array of pods from a single variable.")
+	flags.StringVar(&f.Env.Prefix, "provider.env.prefix", "K8S_ENDPOINT_UPDATER_POD_", "Prefix of the indexed environment variables the env provider reads pods from, e.g. K8S_ENDPOINT_UPDATER_POD_0_IP.")
+	flags.StringVar(&f.Env.Variable, "provider.env.variable", "K8S_ENDPOINT_UPDATER_PODS", "Environment variable the env provider reads a JSON array of pods like [{\"name\": \"master-0\", \"ip\": \"10.0.0.1\"}] from in json format.")
+	flags.StringVar(&f.Etcd.Address, "provider.etcd.address", "", "Address used to connect to etcd.")
+	flags.StringVar(&f.Etcd.Kind, "provider.etcd.kind", etcd.KindV3, "Etcd storage client version to use. Only etcdv3 is supported.")
+	flags.StringVar(&f.Etcd.Prefix, "provider.etcd.prefix", "", "Prefix of etcd keys named after pods providing their IPs.")
+	flags.StringVar(&f.Etcd.TLS.CaFile, "provider.etcd.tls.caFile", "", "Certificate authority file path to use to authenticate with etcd.")
+	flags.StringVar(&f.Etcd.TLS.CrtFile, "provider.etcd.tls.crtFile", "", "Certificate file path to use to authenticate with etcd.")
+	flags.StringVar(&f.Etcd.TLS.KeyFile, "provider.etcd.tls.keyFile", "", "Key file path to use to authenticate with etcd.")
+	flags.BoolVar(&f.Etcd.Watch, "provider.etcd.watch", false, "Whether to watch the etcd key and publish changed IPs immediately.")
+	flags.StringArrayVar(&f.Exec.Args, "provider.exec.args", nil, "Argument passed to the command of the exec provider. Can be given multiple times.")
+	flags.StringVar(&f.Exec.Command, "provider.exec.command", "", "Command run by the exec provider, printing one IP per line or a JSON array of pods to stdout.")
+	flags.DurationVar(&f.Exec.Timeout, "provider.exec.timeout", 10*time.Second, "Maximum duration of a single run of the exec provider command.")
+	flags.StringVar(&f.FDB.Bridge, "provider.fdb.bridge", "", "Bridge whose forwarding database and neighbor entries the fdb provider reads.")
+	flags.StringVar(&f.FDB.Device, "provider.fdb.device", "", "Tap device of the guest VM the fdb provider looks up the learned MAC for.")
+	flags.StringVar(&f.File.Path, "provider.file.path", "", "JSON or YAML file the file provider reads an array of pods from.")
+	flags.BoolVar(&f.File.Watch, "provider.file.watch", false, "Whether to watch the file of the file provider and publish changed IPs immediately.")
+	flags.DurationVar(&f.HTTP.Timeout, "provider.http.timeout", 5*time.Second, "Maximum duration of a single request of the http provider.")
+	flags.StringVar(&f.HTTP.TLS.CaFile, "provider.http.tls.caFile", "", "Certificate authority file path to use to verify the server of the http provider.")
+	flags.StringVar(&f.HTTP.TLS.CrtFile, "provider.http.tls.crtFile", "", "Certificate file path to use to authenticate with the server of the http provider.")
+	flags.StringVar(&f.HTTP.TLS.KeyFile, "provider.http.tls.keyFile", "", "Key file path to use to authenticate with the server of the http provider.")
+	flags.StringVar(&f.HTTP.TLS.TokenFile, "provider.http.tls.tokenFile", "", "Bearer token file path to use to authenticate with the server of the http provider. Read for every request.")
+	flags.StringVar(&f.HTTP.URL, "provider.http.url", "", "URL the http provider fetches a JSON array of pods from.")
+	flags.StringVar(&f.Route.Device, "provider.route.device", "", "Tap or veth device of the guest VM the host route points to.")
+	flags.StringVar(&f.Route.Path, "provider.route.path", "/proc/net/route", "Path of the IPv4 routing table exposed by the kernel.")
+	flags.StringVar(&f.Scan.CIDR, "provider.scan.cidr", "", "Network the scan provider probes, e.g. 10.0.4.0/24. Must not contain more than 65536 addresses.")
+	flags.IntVar(&f.Scan.Concurrency, "provider.scan.concurrency", 64, "Maximum number of probes of the scan provider in flight.")
+	flags.IntVar(&f.Scan.Port, "provider.scan.port", 6443, "TCP port the scan provider probes.")
+	flags.DurationVar(&f.Scan.Timeout, "provider.scan.timeout", time.Second, "Maximum duration of a single probe of the scan provider.")
+	flags.StringSliceVar(&f.Static.IPs, "provider.static.ips", nil, "Endpoint IPs the static provider returns, e.g. 10.0.4.2,10.0.4.3. Meant to pin endpoint IPs explicitly in break-glass scenarios.")
+	flags.StringVar(&f.VRRP.Interface, "provider.vrrp.interface", "", "Interface the VIP has to be assigned to while the node is MASTER. Not checked when empty.")
+	flags.DurationVar(&f.VRRP.PollInterval, "provider.vrrp.pollInterval", 5*time.Second, "Interval in which the keepalived state is checked for transitions.")
+	flags.StringVar(&f.VRRP.StateFile, "provider.vrrp.stateFile", "", "File keepalived writes its current state to using a notify script.")
+	flags.StringVar(&f.VRRP.VIP, "provider.vrrp.vip", "", "Virtual IP managed by keepalived.")
+	flags.StringVar(&f.Whereabouts.Interface, "provider.whereabouts.interface", "", "Pod interface, e.g. net1, the whereabouts provider looks up the reservation of. All interfaces match when empty.")
+	flags.StringVar(&f.Whereabouts.Kubeconfig, "provider.whereabouts.kubeconfig", "", "Kubeconfig file used to read the Whereabouts IPPools. The in-cluster config is used when empty.")
+	flags.StringVar(&f.Whereabouts.Namespace, "provider.whereabouts.namespace", "kube-system", "Namespace Whereabouts keeps its IPPools in.")
+	flags.StringVar(&f.Whereabouts.PodNamespace, "provider.whereabouts.podNamespace", downward.PodNamespace(), "Namespace of the KVM pod the whereabouts provider looks up the reservation of. Defaults to the value of POD_NAMESPACE environment variable or the service account namespace.")
+	flags.StringVar(&f.Kind, "provider.kind", bridge.Kind, "Provider used to lookup pod IPs. Multiple comma separated providers, e.g. bridge,etcd, are tried in the given order until one of them finds the pod IP.")
+	flags.StringVar(&f.Mode, "provider.mode", chain.Mode, "How multiple comma separated providers are combined. Either fallback, trying them in order until one finds the pod IP, or composite, running them concurrently and merging their results.")
+	flags.StringVar(&f.KVMConfig.APIVersion, "provider.kvmconfig.apiVersion", "provider.giantswarm.io/v1alpha1", "API version of the custom resource the kvmconfig provider reads.")
+	flags.StringVar(&f.KVMConfig.Kubeconfig, "provider.kvmconfig.kubeconfig", "", "Kubeconfig file used to read the custom resource. The in-cluster config is used when empty.")
+	flags.StringVar(&f.KVMConfig.Name, "provider.kvmconfig.name", "", "Name of the custom resource the kvmconfig provider reads, usually the guest cluster ID.")
+	flags.StringVar(&f.KVMConfig.Namespace, "provider.kvmconfig.namespace", "default", "Namespace of the custom resource the kvmconfig provider reads.")
+	flags.StringVar(&f.KVMConfig.Path, "provider.kvmconfig.path", "", "Dot separated path of the field holding the guest node IPs, e.g. status.cluster.nodes.ip. Arrays along the path are traversed.")
+	flags.StringVar(&f.KVMConfig.Resource, "provider.kvmconfig.resource", "kvmconfigs", "Plural resource name of the custom resource the kvmconfig provider reads.")
+	flags.StringVar(&f.NetNeighbor.InterfaceAlias, "provider.netneighbor.interfaceAlias", "", "Alias of the Windows host interface the guest VM is attached to.")
+	flags.StringVar(&f.NetNeighbor.MAC, "provider.netneighbor.mac", "", "MAC address of the guest VM used to select the neighbor entry.")
+	flags.StringVar(&f.NetNeighbor.Source, "provider.netneighbor.source", netneighbor.SourceCmdlet, "Source used to query the Windows neighbor table. Either cmdlet or wmi.")
+}
+
+// New creates the provider configured by the given provider flags. A comma
+// separated provider kind, e.g. bridge,etcd, combines multiple providers
+// according to the provider mode. In fallback mode they are tried in the
+// given order until one of them finds the endpoint IP, in composite mode they
+// run concurrently and their results are merged.
+func New(config Config) (provider.Provider, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	kinds := Kinds(config.Flag.Kind)
+	if len(kinds) == 1 {
+		newProvider, err := newProviderOfKind(config, kinds[0])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return newProvider, nil
+	}
+
+	var providers []provider.Provider
+	for _, kind := range kinds {
+		newProvider, err := newProviderOfKind(config, kind)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		providers = append(providers, newProvider)
+	}
+
+	switch config.Flag.Mode {
+	case chain.Mode, "":
+		chainConfig := chain.DefaultConfig()
+
+		chainConfig.Logger = config.Logger
+		chainConfig.Providers = providers
+
+		chainConfig.Kinds = kinds
+
+		chainProvider, err := chain.New(chainConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return chainProvider, nil
+	case composite.Mode:
+		compositeConfig := composite.DefaultConfig()
+
+		compositeConfig.Logger = config.Logger
+		compositeConfig.Providers = providers
+
+		compositeConfig.Kinds = kinds
+
+		compositeProvider, err := composite.New(compositeConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return compositeProvider, nil
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "unsupported provider mode %#q", config.Flag.Mode)
+	}
+}
+
+// Watch returns whether the changes notified by the provider configured by the
+// given provider flags should be followed. Only single providers are watched.
+func Watch(f flag.Provider) bool {
+	switch f.Kind {
+	case bridge.Kind:
+		return f.Bridge.Watch
+	case etcd.Kind:
+		return f.Etcd.Watch
+	case file.Kind:
+		return f.File.Watch
+	default:
+		return false
+	}
+}
+
+// Kinds splits the given comma separated provider kind.
+func Kinds(kind string) []string {
+	var kinds []string
+	for _, k := range strings.Split(kind, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		kinds = append(kinds, k)
+	}
+
+	return kinds
+}
+
+func newProviderOfKind(config Config, kind string) (provider.Provider, error) {
+	var err error
+
+	var newProvider provider.Provider
+	switch kind {
+	case arp.Kind:
+		arpConfig := arp.DefaultConfig()
+
+		arpConfig.Logger = config.Logger
+
+		arpConfig.Interface = config.Flag.ARP.Interface
+		arpConfig.MACPrefix = config.Flag.ARP.MACPrefix
+		arpConfig.Path = config.Flag.ARP.Path
+
+		newProvider, err = arp.New(arpConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case bpf.Kind:
+		bpfConfig := bpf.DefaultConfig()
+
+		bpfConfig.Logger = config.Logger
+
+		bpfConfig.Interface = config.Flag.BPF.Interface
+		bpfConfig.MAC = config.Flag.BPF.MAC
+		bpfConfig.Timeout = config.Flag.BPF.Timeout
+
+		newProvider, err = bpf.New(bpfConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case bridge.Kind:
+		bridgeConfig := bridge.DefaultConfig()
+
+		bridgeConfig.Logger = config.Logger
+
+		bridgeConfig.BridgeNames = config.Flag.Bridge.Names
+		bridgeConfig.BridgePattern = config.Flag.Bridge.Pattern
+		bridgeConfig.PreferredCIDR = config.Flag.Bridge.PreferredCIDR
+		bridgeConfig.WaitTimeout = config.Flag.Bridge.WaitTimeout
+		bridgeConfig.Offset = config.Flag.Bridge.Offset
+
+		newProvider, err = bridge.New(bridgeConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case conntrack.Kind:
+		newProvider, err = NewConntrack(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case dns.Kind:
+		dnsConfig := dns.DefaultConfig()
+
+		dnsConfig.Logger = config.Logger
+
+		dnsConfig.Hostnames = config.Flag.DNS.Hostnames
+		dnsConfig.Server = config.Flag.DNS.Server
+		dnsConfig.SRV = config.Flag.DNS.SRV
+		dnsConfig.Timeout = config.Flag.DNS.Timeout
+
+		newProvider, err = dns.New(dnsConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case env.Kind:
+		envConfig := env.DefaultConfig()
+
+		envConfig.Logger = config.Logger
+
+		envConfig.Format = config.Flag.Env.Format
+		envConfig.Prefix = config.Flag.Env.Prefix
+		envConfig.Variable = config.Flag.Env.Variable
+
+		newProvider, err = env.New(envConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case etcd.Kind:
+		etcdConfig := etcd.DefaultConfig()
+
+		etcdConfig.Logger = config.Logger
+
+		etcdConfig.Address = config.Flag.Etcd.Address
+		etcdConfig.CaFile = config.Flag.Etcd.TLS.CaFile
+		etcdConfig.CrtFile = config.Flag.Etcd.TLS.CrtFile
+		etcdConfig.KeyFile = config.Flag.Etcd.TLS.KeyFile
+		etcdConfig.Kind = config.Flag.Etcd.Kind
+		etcdConfig.PodName = config.PodName
+		etcdConfig.Prefix = config.Flag.Etcd.Prefix
+
+		newProvider, err = etcd.New(etcdConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case exec.Kind:
+		execConfig := exec.DefaultConfig()
+
+		execConfig.Logger = config.Logger
+
+		execConfig.Args = config.Flag.Exec.Args
+		execConfig.Command = config.Flag.Exec.Command
+		execConfig.Timeout = config.Flag.Exec.Timeout
+
+		newProvider, err = exec.New(execConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case fdb.Kind:
+		fdbConfig := fdb.DefaultConfig()
+
+		fdbConfig.Logger = config.Logger
+
+		fdbConfig.Bridge = config.Flag.FDB.Bridge
+		fdbConfig.Device = config.Flag.FDB.Device
+
+		newProvider, err = fdb.New(fdbConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case file.Kind:
+		fileConfig := file.DefaultConfig()
+
+		fileConfig.Logger = config.Logger
+
+		fileConfig.Path = config.Flag.File.Path
+
+		newProvider, err = file.New(fileConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case http.Kind:
+		httpConfig := http.DefaultConfig()
+
+		httpConfig.Logger = config.Logger
+
+		httpConfig.CaFile = config.Flag.HTTP.TLS.CaFile
+		httpConfig.CrtFile = config.Flag.HTTP.TLS.CrtFile
+		httpConfig.KeyFile = config.Flag.HTTP.TLS.KeyFile
+		httpConfig.Timeout = config.Flag.HTTP.Timeout
+		httpConfig.TokenFile = config.Flag.HTTP.TLS.TokenFile
+		httpConfig.URL = config.Flag.HTTP.URL
+
+		newProvider, err = http.New(httpConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case kvmconfig.Kind:
+		k8sClient, err := newK8sClient(config, config.Flag.KVMConfig.Kubeconfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		kvmconfigConfig := kvmconfig.DefaultConfig()
+
+		kvmconfigConfig.K8sClient = k8sClient
+		kvmconfigConfig.Logger = config.Logger
+
+		kvmconfigConfig.APIVersion = config.Flag.KVMConfig.APIVersion
+		kvmconfigConfig.Name = config.Flag.KVMConfig.Name
+		kvmconfigConfig.Namespace = config.Flag.KVMConfig.Namespace
+		kvmconfigConfig.Path = config.Flag.KVMConfig.Path
+		kvmconfigConfig.Resource = config.Flag.KVMConfig.Resource
+
+		newProvider, err = kvmconfig.New(kvmconfigConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case netneighbor.Kind:
+		netNeighborConfig := netneighbor.DefaultConfig()
+
+		netNeighborConfig.Logger = config.Logger
+
+		netNeighborConfig.InterfaceAlias = config.Flag.NetNeighbor.InterfaceAlias
+		netNeighborConfig.MAC = config.Flag.NetNeighbor.MAC
+		netNeighborConfig.Source = config.Flag.NetNeighbor.Source
+
+		newProvider, err = netneighbor.New(netNeighborConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case route.Kind:
+		routeConfig := route.DefaultConfig()
+
+		routeConfig.Logger = config.Logger
+
+		routeConfig.Device = config.Flag.Route.Device
+		routeConfig.Path = config.Flag.Route.Path
+
+		newProvider, err = route.New(routeConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case scan.Kind:
+		scanConfig := scan.DefaultConfig()
+
+		scanConfig.Logger = config.Logger
+
+		scanConfig.CIDR = config.Flag.Scan.CIDR
+		scanConfig.Concurrency = config.Flag.Scan.Concurrency
+		scanConfig.Port = config.Flag.Scan.Port
+		scanConfig.Timeout = config.Flag.Scan.Timeout
+
+		newProvider, err = scan.New(scanConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case static.Kind:
+		staticConfig := static.DefaultConfig()
+
+		staticConfig.Logger = config.Logger
+
+		staticConfig.IPs = config.Flag.Static.IPs
+
+		newProvider, err = static.New(staticConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case vrrp.Kind:
+		vrrpConfig := vrrp.DefaultConfig()
+
+		vrrpConfig.Logger = config.Logger
+
+		vrrpConfig.Interface = config.Flag.VRRP.Interface
+		vrrpConfig.StateFile = config.Flag.VRRP.StateFile
+		vrrpConfig.VIP = config.Flag.VRRP.VIP
+
+		newProvider, err = vrrp.New(vrrpConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	case whereabouts.Kind:
+		k8sClient, err := newK8sClient(config, config.Flag.Whereabouts.Kubeconfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		whereaboutsConfig := whereabouts.DefaultConfig()
+
+		whereaboutsConfig.K8sClient = k8sClient
+		whereaboutsConfig.Logger = config.Logger
+
+		whereaboutsConfig.Interface = config.Flag.Whereabouts.Interface
+		whereaboutsConfig.Namespace = config.Flag.Whereabouts.Namespace
+		whereaboutsConfig.PodName = config.PodName
+		whereaboutsConfig.PodNamespace = config.Flag.Whereabouts.PodNamespace
+
+		newProvider, err = whereabouts.New(whereaboutsConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "unsupported provider kind %#q", kind)
+	}
+
+	return newProvider, nil
+}
+
+// NewConntrack creates the conntrack provider configured by the given provider
+// flags. It is used on its own to confirm the IPs found by other providers.
+func NewConntrack(config Config) (*conntrack.Provider, error) {
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	conntrackConfig := conntrack.DefaultConfig()
+
+	conntrackConfig.Logger = config.Logger
+
+	conntrackConfig.Interface = config.Flag.Conntrack.Interface
+	conntrackConfig.Path = config.Flag.Conntrack.Path
+
+	conntrackProvider, err := conntrack.New(conntrackConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return conntrackProvider, nil
+}
+
+// LookupAll resolves the endpoint IPs using the given provider. All IPs are
+// looked up in case the provider supports multiple families.
+func LookupAll(ctx context.Context, p provider.Provider) ([]net.IP, error) {
+	pods, err := p.Lookup(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "provider returned no pods")
+	}
+
+	return provider.IPs(pods), nil
+}
+
+// newK8sClient creates the Kubernetes client of providers reading custom
+// resources. They use their own client, so that they can be used by commands
+// which do not talk to Kubernetes otherwise.
+func newK8sClient(config Config, kubeconfig string) (kubernetes.Interface, error) {
+	k8sConfig := k8s.Config{
+		Logger: config.Logger,
+
+		Flag: k8sflag.Kubernetes{
+			InCluster:  kubeconfig == "",
+			Kubeconfig: kubeconfig,
+		},
+	}
+
+	k8sClient, err := k8s.NewClient(k8sConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return k8sClient, nil
+}
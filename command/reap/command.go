@@ -0,0 +1,185 @@
+// Package reap implements the reap command for the command line tool.
+package reap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/command/reap/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/metrics"
+	"github.com/giantswarm/k8s-endpoint-updater/service/reaper"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new reap command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new reap
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured reap command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "reap",
+		Short: "Remove stale endpoint IPs cluster-wide. Meant to run as CronJob.",
+		Long:  "Remove stale endpoint IP annotations of KVM pods cluster-wide. Meant to run as CronJob. Endpoint IPs are stale when their pod terminated, or when their pod is not running and their heartbeat is older than the threshold. Running pods are never reaped. Endpoints and EndpointSlices are only scanned for deleted pods carrying the cleanup finalizer, see the cleanup command for the others.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().BoolVar(&f.DryRun, "reap.dryRun", false, "Whether to only report stale endpoint IPs without removing them.")
+	newCommand.cobraCommand.PersistentFlags().BoolVar(&f.PrintMetrics, "reap.printMetrics", false, "Whether to print the reaper metrics in Prometheus text format when done.")
+	newCommand.cobraCommand.PersistentFlags().DurationVar(&f.Threshold, "reap.threshold", time.Hour, "Age after which the heartbeat of a pod which is not running is considered expired.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Cluster.Namespace, "service.kubernetes.cluster.namespace", "", "Namespace to scan for stale endpoint IPs. All namespaces are scanned when empty.")
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context) error {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		// Deleted pods might have published their endpoint IPs using any
+		// kind, and cleaning up a kind not in use is a no-op.
+		updaterConfig.Kind = updater.KindBoth
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newReaper *reaper.Reaper
+	{
+		reaperConfig := reaper.DefaultConfig()
+
+		reaperConfig.K8sClient = k8sClient
+		reaperConfig.Logger = c.logger
+		reaperConfig.Updater = newUpdater
+
+		reaperConfig.DryRun = f.DryRun
+		reaperConfig.Threshold = f.Threshold
+
+		newReaper, err = reaper.New(reaperConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	reaped, err := newReaper.Reap(ctx, f.Kubernetes.Cluster.Namespace)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = c.logger.Log("info", fmt.Sprintf("reaped %d stale endpoint IPs", len(reaped)), "dryRun", f.DryRun)
+
+	if f.PrintMetrics {
+		families, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		for _, family := range families {
+			if !strings.HasPrefix(family.GetName(), metrics.Namespace+"_reaper_") {
+				continue
+			}
+
+			_, err := expfmt.MetricFamilyToText(os.Stdout, family)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package flag
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+)
+
+type Flag struct {
+	DryRun       bool
+	Kubernetes   kubernetes.Kubernetes
+	PrintMetrics bool
+	Threshold    time.Duration
+}
+
+func (f *Flag) Validate() error {
+	if f.Threshold <= 0 {
+		return microerror.Maskf(invalidFlagsError, "threshold must be greater than zero")
+	}
+
+	return nil
+}
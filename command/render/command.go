@@ -0,0 +1,182 @@
+// Package render implements the render command for the command line tool.
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/render/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/service/logging"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new render command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new render
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured render command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "render",
+		Short: "Print the Endpoints manifest for the looked up endpoint IPs.",
+		Long:  "Print the Endpoints and EndpointSlice manifests for the endpoint IPs looked up by the configured provider as YAML, e.g. to pipe them into kubectl apply or a GitOps repository. The cluster is neither read nor written, so no Kubernetes credentials are needed.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kind, "updater.kind", updater.KindEndpoints, "Resources which are rendered. One of endpoints, endpointslice or both.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Namespace, "namespace", "default", "Namespace of the service.")
+	newCommand.cobraCommand.PersistentFlags().StringArrayVar(&f.Ports, "port", nil, "Endpoint port formatted as name:port[:protocol], e.g. https:443:TCP. Can be given multiple times.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Service, "service", "", "Name of the service.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.PodName, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod, which the etcd provider looks up. Defaults to the value of POD_NAME environment variable.")
+
+	cmdprovider.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Provider)
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	// The manifests are meant to be piped, so stdout must not contain
+	// anything else. The structured logs go to stderr instead.
+	c.logger, err = logging.Redirect(c.logger, os.Stderr)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx, os.Stdout)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context, w io.Writer) error {
+	var err error
+
+	providerConfig := cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.PodName,
+	}
+
+	newProvider, err := cmdprovider.New(providerConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	ips, err := cmdprovider.LookupAll(ctx, newProvider)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		// The objects are rendered against an in-memory fake, the client
+		// is only required to construct the updater.
+		updaterConfig.K8sClient = fake.NewSimpleClientset()
+		updaterConfig.Logger = c.logger
+
+		updaterConfig.Kind = f.Kind
+
+		for _, s := range f.Ports {
+			port, err := updater.ParsePort(s)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+			updaterConfig.Ports = append(updaterConfig.Ports, port)
+		}
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	objects, err := newUpdater.Render(f.Namespace, f.Service, ips)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for i, o := range objects {
+		b, err := yaml.Marshal(o)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if i > 0 {
+			b = append([]byte("---\n"), b...)
+		}
+
+		_, err = w.Write(b)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package flag
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+)
+
+type Flag struct {
+	Kind      string
+	Namespace string
+	PodName   string
+	Ports     []string
+	Provider  provider.Provider
+	Service   string
+}
+
+func (f *Flag) Validate() error {
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagsError, "namespace must not be empty")
+	}
+	if f.Service == "" {
+		return microerror.Maskf(invalidFlagsError, "service must not be empty")
+	}
+
+	switch f.Kind {
+	case "both", "endpoints", "endpointslice":
+	default:
+		return microerror.Maskf(invalidFlagsError, "updater kind must be one of both, endpoints or endpointslice")
+	}
+
+	if f.Provider.Kind == "" {
+		return microerror.Maskf(invalidFlagsError, "provider kind must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+// Sequence is the cleanup run once the process is asked to terminate. Steps
+// run in reverse order of their registration, like deferred calls, so that
+// steps depending on earlier ones run first. The sequence runs exactly once,
+// no matter how often Run is called, e.g. once explicitly after a signal and
+// once deferred for early returns.
+type Sequence struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	err   error
+	mutex sync.Mutex
+	once  sync.Once
+	steps []step
+}
+
+type step struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// NewSequence creates an empty shutdown sequence.
+func NewSequence(logger micrologger.Logger) *Sequence {
+	return &Sequence{
+		// Dependencies.
+		logger: logger,
+
+		// Internals.
+		err:   nil,
+		mutex: sync.Mutex{},
+		once:  sync.Once{},
+		steps: nil,
+	}
+}
+
+// Add registers the given step. Steps added once the sequence ran are ignored.
+func (s *Sequence) Add(name string, run func(ctx context.Context) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.steps = append(s.steps, step{name: name, run: run})
+}
+
+// Run executes all registered steps, even if some of them fail, and returns
+// the first failure. The given context bounds the whole sequence. Subsequent
+// calls return the result of the first one without running any step again.
+func (s *Sequence) Run(ctx context.Context) error {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		steps := s.steps
+		s.steps = nil
+		s.mutex.Unlock()
+
+		for i := len(steps) - 1; i >= 0; i-- {
+			_ = s.logger.Log("debug", "running shutdown step", "step", steps[i].name)
+
+			err := steps[i].run(ctx)
+			if err != nil {
+				_ = s.logger.Log("warning", fmt.Sprintf("shutdown step failed: %#v", microerror.Mask(err)), "step", steps[i].name)
+				if s.err == nil {
+					s.err = err
+				}
+			}
+		}
+	})
+
+	if s.err != nil {
+		return microerror.Mask(s.err)
+	}
+
+	return nil
+}
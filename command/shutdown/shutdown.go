@@ -0,0 +1,41 @@
+// Package shutdown wires the termination signals of the process into context
+// cancellation, so that commands abort in-flight work cleanly.
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/giantswarm/micrologger"
+)
+
+// Signals are the signals asking the process to terminate. SIGKILL cannot be
+// caught and is therefore not part of them.
+var Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// Context returns a context which is cancelled once the process receives one
+// of the termination signals, i.e. SIGINT or SIGTERM. The default signal
+// handling is restored afterwards, so that a second signal terminates the
+// process immediately. Calling the returned cancel function releases the
+// signal handling as well.
+func Context(logger micrologger.Logger) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, Signals...)
+
+	go func() {
+		select {
+		case s := <-signals:
+			_ = logger.Log("info", "received signal, shutting down", "signal", s.String())
+		case <-ctx.Done():
+		}
+
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	return ctx, cancel
+}
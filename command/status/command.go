@@ -0,0 +1,219 @@
+// Package status implements the status command for the command line tool.
+package status
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/command/status/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new status command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new status
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured status command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "status",
+		Short: "Print the endpoint IPs currently published for a service.",
+		Long:  "Print the endpoint IPs currently published for a service in its Endpoints and EndpointSlices, which of them were added by updater instances and when, and whether they still match the provider lookup.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Namespace, "namespace", "default", "Namespace of the service.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Service, "service", "", "Name of the service.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod, which the etcd provider looks up. Defaults to the value of POD_NAME environment variable.")
+
+	cmdprovider.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Provider)
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx, os.Stdout)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (c *Command) execute(ctx context.Context, w io.Writer) error {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		// The service might be published using any kind, so we show both.
+		updaterConfig.Kind = updater.KindBoth
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	statuses, err := newUpdater.Status(f.Namespace, f.Service)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Failing lookups do not prevent showing what is published, the match
+	// is reported as unknown in this case.
+	lookupIPs, err := c.lookup(ctx)
+	if err != nil {
+		_ = c.logger.Log("warning", fmt.Sprintf("looking up endpoint IPs failed: %#v", microerror.Mask(err)))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "RESOURCE\tIP\tREADY\tOWNERS\tAGE\tMATCHES PROVIDER")
+	for _, s := range statuses {
+		owners := "-"
+		if len(s.Owners) != 0 {
+			owners = strings.Join(s.Owners, ",")
+		}
+
+		age := "-"
+		if !s.Added.IsZero() {
+			age = duration.HumanDuration(time.Since(s.Added))
+		}
+
+		matches := "unknown"
+		if lookupIPs != nil {
+			matches = "no"
+			if containsIP(lookupIPs, s.IP) {
+				matches = "yes"
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\t%s\n", s.Resource, s.IP, s.Ready, owners, age, matches)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// lookup resolves the endpoint IPs using the configured provider.
+func (c *Command) lookup(ctx context.Context) ([]net.IP, error) {
+	providerConfig := cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.Kubernetes.Pod.Name,
+	}
+
+	newProvider, err := cmdprovider.New(providerConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ips, err := cmdprovider.LookupAll(ctx, newProvider)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ips, nil
+}
+
+func containsIP(ips []net.IP, s string) bool {
+	for _, ip := range ips {
+		if ip.Equal(net.ParseIP(s)) {
+			return true
+		}
+	}
+
+	return false
+}
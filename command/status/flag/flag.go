@@ -0,0 +1,26 @@
+package flag
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+)
+
+type Flag struct {
+	Kubernetes kubernetes.Kubernetes
+	Namespace  string
+	Provider   provider.Provider
+	Service    string
+}
+
+func (f *Flag) Validate() error {
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagsError, "namespace must not be empty")
+	}
+	if f.Service == "" {
+		return microerror.Maskf(invalidFlagsError, "service must not be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/telemetry"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// batchFile describes the services published by a batch run, e.g.
+//
+//	entries:
+//	- namespace: abc12
+//	  service: master
+//	  ports:
+//	  - https:443
+//	  provider:
+//	    kind: bridge
+//	    bridge.name: br-abc12
+//
+// Provider settings are given like the provider flags and default to the
+// flags given on the command line. Namespace and ports do so as well.
+type batchFile struct {
+	Entries []batchEntry `yaml:"entries"`
+}
+
+type batchEntry struct {
+	Namespace string                      `yaml:"namespace"`
+	Ports     []string                    `yaml:"ports"`
+	Provider  map[interface{}]interface{} `yaml:"provider"`
+	Service   string                      `yaml:"service"`
+}
+
+// runBatch publishes the endpoint IPs of all services listed in the batch
+// file once. All entries are processed, even if some of them fail, unless the
+// given context is cancelled.
+func (c *Command) runBatch(ctx context.Context, flags *pflag.FlagSet, reporter *telemetry.Reporter) error {
+	b, err := ioutil.ReadFile(f.Batch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var file batchFile
+	err = yaml.UnmarshalStrict(b, &file)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "invalid batch file %#q: %s", f.Batch, err.Error())
+	}
+
+	k8sClient, err := k8s.NewClient(k8s.Config{Logger: c.logger, Flag: f.Kubernetes})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Every entry starts from the flags given on the command line.
+	baseCluster := f.Kubernetes.Cluster
+	baseProvider := f.Provider
+	baseLogger := c.logger
+	defer func() {
+		f.Kubernetes.Cluster = baseCluster
+		f.Provider = baseProvider
+		c.logger = baseLogger
+	}()
+
+	var failed int
+	for i, e := range file.Entries {
+		if ctx.Err() != nil {
+			return microerror.Maskf(cancelledError, "processing batch entries")
+		}
+
+		f.Kubernetes.Cluster = baseCluster
+		f.Provider = baseProvider
+		c.logger = baseLogger
+
+		if e.Namespace != "" {
+			f.Kubernetes.Cluster.Namespaces = []string{e.Namespace}
+		}
+		if e.Ports != nil {
+			f.Kubernetes.Cluster.Ports = e.Ports
+		}
+		f.Kubernetes.Cluster.Services = []string{e.Service}
+
+		c.printer.Start(fmt.Sprintf("entry %d", i))
+
+		ips, err := c.runBatchEntry(ctx, k8sClient, e, flags, reporter)
+		if err != nil {
+			failed++
+			c.printer.Fail(err)
+			_ = c.logger.Log("warning", fmt.Sprintf("publishing endpoint IP for batch entry %d failed: %#v", i, microerror.Mask(err)), "service", e.Service)
+			continue
+		}
+
+		c.printer.Done(fmt.Sprintf("published %s for services %s", updater.JoinIPs(ips), serviceNames(targets())))
+		for _, t := range targets() {
+			c.printer.AddRow(t.Namespace, t.Service, f.Kubernetes.Pod.Name, updater.JoinIPs(ips))
+		}
+	}
+
+	if failed != 0 {
+		return microerror.Maskf(executionFailedError, "publishing endpoint IP failed for %d of %d batch entries", failed, len(file.Entries))
+	}
+
+	return nil
+}
+
+func (c *Command) runBatchEntry(ctx context.Context, k8sClient kubernetes.Interface, e batchEntry, flags *pflag.FlagSet, reporter *telemetry.Reporter) ([]net.IP, error) {
+	if e.Service == "" {
+		return nil, microerror.Maskf(invalidConfigError, "service must not be empty")
+	}
+
+	err := flag.SetValues("provider", e.Provider, flags)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = f.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c.logger = c.logger.With(logFields()...)
+
+	newProvider, err := c.newProvider(f)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newUpdater, err := c.newUpdater(k8sClient)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newEndpointUpdater, err := c.newEndpointUpdater(k8sClient, newProvider, newUpdater, nil, reporter)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ips, err := newEndpointUpdater.Lookup(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = newEndpointUpdater.Publish(ctx, ips)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ips, nil
+}
@@ -2,26 +2,47 @@
 package update
 
 import (
+	"context"
 	"fmt"
-	"net"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/giantswarm/backoff"
-	"github.com/giantswarm/k8sclient"
-	"github.com/giantswarm/k8sclient/k8srestconfig"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/rest"
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
 
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/audit"
+	"github.com/giantswarm/k8s-endpoint-updater/service/debug"
+	"github.com/giantswarm/k8s-endpoint-updater/service/endpointupdater"
+	"github.com/giantswarm/k8s-endpoint-updater/service/health"
+	"github.com/giantswarm/k8s-endpoint-updater/service/logging"
+	"github.com/giantswarm/k8s-endpoint-updater/service/metrics"
+	"github.com/giantswarm/k8s-endpoint-updater/service/output"
+	"github.com/giantswarm/k8s-endpoint-updater/service/probe"
 	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
-	"github.com/giantswarm/k8s-endpoint-updater/service/provider/bridge"
+	"github.com/giantswarm/k8s-endpoint-updater/service/systemd"
+	"github.com/giantswarm/k8s-endpoint-updater/service/telemetry"
+	"github.com/giantswarm/k8s-endpoint-updater/service/tracing"
 	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
 )
 
 const (
-	podNameEnv = "POD_NAME"
+	// ExitCodeRetryBudgetExhausted is the exit code of the process in case it
+	// gave up publishing the endpoint IP, so that hard misconfigurations like
+	// denied RBAC permissions can be told apart from other failures.
+	ExitCodeRetryBudgetExhausted = 3
 )
 
 var (
@@ -32,6 +53,9 @@ var (
 type Config struct {
 	// Dependencies.
 	Logger micrologger.Logger
+
+	// Settings.
+	GitCommit string
 }
 
 // DefaultConfig provides a default configuration to create a new update
@@ -40,6 +64,9 @@ func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
 		Logger: nil,
+
+		// Settings.
+		GitCommit: "",
 	}
 }
 
@@ -55,171 +82,755 @@ func New(config Config) (*Command, error) {
 		logger: config.Logger,
 
 		// Internals.
-		cobraCommand: nil,
+		cobraCommand:    nil,
+		endpointUpdater: nil,
+		explicitFlags:   nil,
+		hangups:         nil,
+		healthServer:    nil,
+		notifier:        nil,
+		printer:         nil,
+		providers:       nil,
+
+		// Settings.
+		gitCommit: config.GitCommit,
 	}
 
 	newCommand.cobraCommand = &cobra.Command{
 		Use:   "update",
 		Short: "Update annotations on KVM pod based on given configuration.",
 		Long:  "Update annotations on KVM pod based on given configuration.",
-		Run:   newCommand.Execute,
+		RunE:  newCommand.Execute,
 	}
 
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.Address, "service.kubernetes.address", "http://127.0.0.1:6443", "Address used to connect to Kubernetes. When empty in-cluster config is created.")
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.Cluster.Namespace, "service.kubernetes.cluster.namespace", "default", "Namespace of the guest cluster which endpoints should be updated.")
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.Cluster.Service, "service.kubernetes.cluster.service", "", "Name of the service which endpoints should be updated.")
-	newCommand.CobraCommand().PersistentFlags().BoolVar(&f.Kubernetes.InCluster, "service.kubernetes.inCluster", false, "Whether to use the in-cluster config to authenticate with Kubernetes.")
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.TLS.CaFile, "service.kubernetes.tls.caFile", "", "Certificate authority file path to use to authenticate with Kubernetes.")
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.TLS.CrtFile, "service.kubernetes.tls.crtFile", "", "Certificate file path to use to authenticate with Kubernetes.")
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.TLS.KeyFile, "service.kubernetes.tls.keyFile", "", "Key file path to use to authenticate with Kubernetes.")
-	newCommand.CobraCommand().PersistentFlags().StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", os.Getenv(podNameEnv), "Name of the guest cluster kvm Kubernetes pod. Defaults to the value of POD_NAME environment variable.")
-
-	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Provider.Bridge.Name, "provider.bridge.name", "", "Bridge name of the guest cluster VM on the host network.")
-	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Provider.Env.Prefix, "provider.env.prefix", "K8S_ENDPOINT_UPDATER_POD_", "Prefix of environment variables providing pod names.")
-	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Provider.Etcd.Address, "provider.etcd.address", "", "Address used to connect to etcd.")
-	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Provider.Etcd.Kind, "provider.etcd.kind", "etcdv2", "Etcd storage client version to use.")
-	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Provider.Etcd.Prefix, "provider.etcd.prefix", "", "Prefix of etcd paths providing pod names.")
-	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Provider.Kind, "provider.kind", "env", "Provider used to lookup pod IPs.")
+	addFlags(newCommand.cobraCommand.PersistentFlags(), f)
 
 	return newCommand, nil
 }
 
+// addFlags registers all flags of the update command with the given flag set,
+// bound to the given flags.
+func addFlags(flags *pflag.FlagSet, f *flag.Flag) {
+	flags.StringVar(&f.Batch, "batch", "", "YAML or JSON file listing services to publish once, each with its own namespace, ports and provider settings. The service flag is not required in this case.")
+	flags.StringVarP(&f.Filename, "filename", "f", "", "File with update specs to publish once, given as JSON lines of {namespace, service, ips}. Reads stdin when -. The service flag is not required and no provider is used in this case.")
+	flags.StringVar(&f.Audit.File, "audit.file", "", "File every create, update and delete of Endpoints and EndpointSlices is appended to as JSON line, including the previous and new addresses and the API response. Disabled when empty.")
+	flags.StringVar(&f.Config, "config", "", "YAML file providing flag values, e.g. mounted from a ConfigMap. Keys are flag names. Flags given on the command line take precedence.")
+
+	k8s.AddFlags(flags, &f.Kubernetes)
+	flags.StringSliceVar(&f.Kubernetes.Cluster.Namespaces, "service.kubernetes.cluster.namespace", []string{"default"}, "Namespace of the guest cluster which endpoints should be updated. Can be repeated or comma separated to update the services in multiple namespaces. The first namespace is the one of the KVM pod, unless the pod namespace is given.")
+	flags.StringArrayVar(&f.Kubernetes.Cluster.Ports, "service.kubernetes.cluster.ports", nil, "Endpoint port formatted as name:port[:protocol], e.g. https:443:TCP. Can be given multiple times. Ports are derived from the service spec when not given, which requires its target ports to be numeric.")
+	flags.StringSliceVar(&f.Kubernetes.Cluster.Services, "service.kubernetes.cluster.service", nil, "Name of the service which endpoints should be updated. Can be repeated or comma separated to update multiple services with the same endpoint IP. Services given as namespace/service are only updated in the given namespace.")
+	flags.BoolVar(&f.Kubernetes.Mock, "mock-apiserver", false, "Whether to route all Kubernetes operations through an in-process fake API server. Meant for local development only.")
+	flags.StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod. Defaults to the value of POD_NAME environment variable.")
+	flags.StringVar(&f.Kubernetes.Pod.Namespace, "service.kubernetes.pod.namespace", downward.PodNamespace(), "Namespace of the guest cluster kvm Kubernetes pod. Defaults to the value of POD_NAMESPACE environment variable or the service account namespace. The guest cluster namespace is used when empty.")
+	flags.StringVar(&f.Kubernetes.Pod.NodeName, "service.kubernetes.pod.nodeName", os.Getenv(downward.NodeNameEnv), "Node name of the guest cluster kvm Kubernetes pod, used in case the pod cannot be read. Defaults to the value of NODE_NAME environment variable.")
+	flags.StringVar(&f.Kubernetes.Pod.UID, "service.kubernetes.pod.uid", os.Getenv(downward.PodUIDEnv), "UID of the guest cluster kvm Kubernetes pod, used in case the pod cannot be read. Defaults to the value of POD_UID environment variable.")
+
+	cmdprovider.AddFlags(flags, &f.Provider)
+
+	flags.DurationVar(&f.Reconcile.Interval, "reconcile.interval", time.Minute, "Interval in which the provider lookup is re-run and the published endpoint IP is reconciled, repairing drift caused by restarts or manual edits. The endpoint IP is only published once at startup when zero.")
+	flags.Float64Var(&f.Reconcile.Jitter, "reconcile.jitter", 0.1, "Maximum factor of the reconcile interval randomly added to each wait, so that many updater instances do not reconcile in lockstep. Disabled when zero.")
+
+	// The daemon flags predate reconciling by default and are only kept as
+	// aliases.
+	flags.BoolVar(&f.Daemon.Enabled, "daemon.enabled", false, "Whether to periodically re-run the provider lookup and reconcile the published endpoint IP.")
+	flags.DurationVar(&f.Reconcile.Interval, "daemon.interval", time.Minute, "Interval in which the provider lookup is re-run in daemon mode.")
+	flags.Float64Var(&f.Reconcile.Jitter, "daemon.jitter", 0.1, "Maximum factor of the daemon interval randomly added to each wait.")
+	_ = flags.MarkDeprecated("daemon.enabled", "reconciling is enabled by default, see --reconcile.interval")
+	_ = flags.MarkDeprecated("daemon.interval", "use --reconcile.interval instead")
+	_ = flags.MarkDeprecated("daemon.jitter", "use --reconcile.jitter instead")
+
+	flags.BoolVar(&f.DryRun, "dry-run", false, "Whether to only look up the endpoint IP and print the requests which would be sent, without mutating the cluster. The command terminates afterwards.")
+
+	flags.StringVar(&f.IPFamily, "ip-family", updater.FamilyIPv4, "IP family policy of the published endpoint IPs. One of ipv4, ipv6 or dual. Dual requires the updater kind to not be annotation.")
+
+	flags.StringVar(&f.Reachability.Probe, "reachability.probe", "", "Probe looked up endpoint IPs have to pass before being published at all, e.g. 'icmp://' or 'tcp://:6443'. ICMP requires the CAP_NET_RAW capability. Disabled when empty.")
+	flags.DurationVar(&f.Reachability.Timeout, "reachability.timeout", 2*time.Second, "Maximum duration of a single reachability probe.")
+
+	flags.BoolVar(&f.Readiness.Demote, "readiness.demote", false, "Whether to keep probing ready endpoint IPs and publish them as not ready addresses again once their probe keeps failing, e.g. when the guest cluster API server becomes unhealthy.")
+	flags.IntVar(&f.Readiness.FailureThreshold, "readiness.failureThreshold", 3, "Number of consecutive probe failures after which a ready endpoint IP is demoted.")
+	flags.DurationVar(&f.Readiness.Interval, "readiness.interval", 5*time.Second, "Interval in which not ready endpoint IPs are probed.")
+	flags.StringVar(&f.Readiness.Probe, "readiness.probe", "", "Probe endpoint IPs have to pass before being published as ready addresses, e.g. 'tcp://:6443' or 'https://:6443/healthz'. Disabled when empty.")
+	flags.DurationVar(&f.Readiness.Timeout, "readiness.timeout", 5*time.Second, "Maximum duration of a single readiness probe.")
+
+	flags.DurationVar(&f.ReassertInterval, "reassert-interval", 0, "Interval in which the endpoint IP is re-applied even when no change is detected. Disabled when zero.")
+
+	flags.DurationVar(&f.Retry.InitialInterval, "retry.initialInterval", 500*time.Millisecond, "Wait before the first retry of the initial lookup and publication of the endpoint IP. The wait between all retries with the constant strategy.")
+	flags.DurationVar(&f.Retry.MaxElapsedTime, "retry.maxElapsedTime", 10*time.Minute, "Duration after which retrying the initial lookup and publication gives up. Retrying never gives up when zero.")
+	flags.DurationVar(&f.Retry.MaxInterval, "retry.maxInterval", time.Minute, "Maximum wait between retries.")
+	flags.IntVar(&f.Retry.MaxRetries, "retry.maxRetries", 0, "Number of retries after which the updater gives up, emits a Kubernetes event and exits with code 3. Unlimited when zero.")
+	flags.Float64Var(&f.Retry.Multiplier, "retry.multiplier", 1.5, "Factor the wait between retries grows with.")
+	flags.StringVar(&f.Retry.Strategy, "retry.strategy", endpointupdater.RetryStrategyExponential, "How retries are spaced. One of exponential, constant or immediate. Immediate does not retry at all, so that Jobs and hooks fail fast.")
+
+	flags.DurationVar(&f.Shutdown.Timeout, "shutdown.timeout", 20*time.Second, "Maximum duration the cleanup on shutdown, e.g. withdrawing the endpoint IP, may take. Should be lower than the termination grace period of the pod.")
+	flags.BoolVar(&f.Shutdown.Withdraw, "shutdown.withdraw", false, "Whether to withdraw the published endpoint IP once the process is asked to terminate.")
+
+	flags.StringVar(&f.Health.Address, "health.address", "", "Address the /healthz and /readyz server listens on, e.g. ':8080'. The server is disabled when empty.")
+	flags.DurationVar(&f.Health.FailureThreshold, "health.failureThreshold", 5*time.Minute, "Duration reconciliation may keep failing before /healthz reports unhealthy and the ready file is removed.")
+	flags.StringVar(&f.Health.ReadyFile, "ready-file", "", "Path of the file created once the endpoint IP has been published initially and removed while reconciliation keeps failing, e.g. for exec readiness probes. Disabled when empty.")
+
+	flags.BoolVar(&f.LeaderElection.Enabled, "leaderElection.enabled", false, "Whether to elect a leader using a coordination.k8s.io Lease, so that only one of multiple replicas publishes the endpoint IP.")
+	flags.DurationVar(&f.LeaderElection.LeaseDuration, "leaderElection.leaseDuration", 15*time.Second, "Duration standby replicas wait before taking over an expired lease.")
+	flags.StringVar(&f.LeaderElection.Name, "leaderElection.name", "", "Name of the Lease. Defaults to the service name suffixed with -k8s-endpoint-updater.")
+	flags.StringVar(&f.LeaderElection.Namespace, "leaderElection.namespace", "", "Namespace of the Lease. Defaults to the namespace of the service.")
+	flags.DurationVar(&f.LeaderElection.RenewDeadline, "leaderElection.renewDeadline", 10*time.Second, "Duration the leader retries renewing the lease before giving it up.")
+	flags.DurationVar(&f.LeaderElection.RetryPeriod, "leaderElection.retryPeriod", 2*time.Second, "Interval in which acquiring and renewing the lease is attempted.")
+
+	flags.StringVar(&f.Debug.Address, "debug.address", "", "Loopback address the net/http/pprof debug server listens on, e.g. '127.0.0.1:6060'. The server is disabled when empty.")
+
+	flags.StringVar(&f.Metrics.Address, "metrics.address", "", "Address the Prometheus metrics server listens on, e.g. ':8000'. The server is disabled when empty.")
+
+	flags.StringVar(&f.Output.Format, "output", output.FormatText, "Format of the result. One of text or json. JSON prints a single machine-readable summary to stdout once done, logs go to stderr.")
+	flags.StringVar(&f.Output.Interactive, "output.interactive", output.InteractiveAuto, "Whether to print human-friendly progress instead of structured logs. One of auto, always or never. Auto uses it when attached to a terminal.")
+
+	flags.BoolVar(&f.Updater.CreateMissing, "create-missing", false, "Whether to create the Endpoints object of the service in case it does not exist yet. Requires the updater kind to not be annotation.")
+	flags.BoolVar(&f.Updater.OwnerReference.Enabled, "updater.ownerReference.enabled", false, "Whether to set an owner reference on created Endpoints and EndpointSlices, so that they are garbage collected with their owner. The owner defaults to the KVM pod. All services have to be published in the namespace of the owner.")
+	flags.StringVar(&f.Updater.OwnerReference.APIVersion, "updater.ownerReference.apiVersion", "", "API version of the owner of created Endpoints and EndpointSlices, e.g. apps/v1.")
+	flags.StringVar(&f.Updater.OwnerReference.Kind, "updater.ownerReference.kind", "", "Kind of the owner of created Endpoints and EndpointSlices, e.g. Deployment.")
+	flags.StringVar(&f.Updater.OwnerReference.Name, "updater.ownerReference.name", "", "Name of the owner of created Endpoints and EndpointSlices. The KVM pod is the owner when empty. The owner has to live in the namespace of the service.")
+	flags.StringVar(&f.Updater.OwnerReference.UID, "updater.ownerReference.uid", "", "UID of the owner of created Endpoints and EndpointSlices.")
+	flags.StringVar(&f.Updater.PatchStrategy, "patch-strategy", updater.PatchStrategyUpdate, "How existing Endpoints and EndpointSlices are written. One of json (JSON patch testing the resource version), merge (strategic merge patch) or update (full update retried on conflicts).")
+	flags.BoolVar(&f.Updater.Finalizer, "updater.finalizer", false, "Whether to put a finalizer on the KVM pod, so that the reap command removes its endpoint IPs once the pod is deleted, even if the updater was killed. Requires the updater kind to not be annotation.")
+	flags.StringVar(&f.Updater.Kind, "updater.kind", updater.KindAnnotation, "Resources the endpoint IP is published with. One of annotation, endpoints, endpointslice or both.")
+	flags.BoolVar(&f.Updater.Repair, "updater.repair", false, "Whether to watch the Endpoints of the service and publish the endpoint IP again as soon as another actor removed it. Requires the updater kind endpoints or both.")
+	flags.BoolVar(&f.Updater.ServerSideApply, "updater.serverSideApply", false, "Whether to write Endpoints and EndpointSlices using server-side apply with the k8s-endpoint-updater field manager. Requires the updater kind to not be annotation.")
+
+	flags.BoolVar(&f.Telemetry.Enabled, "telemetry.enabled", false, "Whether to opt in to sending anonymous, aggregate usage counters.")
+	flags.StringVar(&f.Telemetry.Endpoint, "telemetry.endpoint", "", "URL anonymous usage counters are sent to when telemetry is enabled.")
+
+	flags.StringVar(&f.Tracing.Endpoint, "tracing.endpoint", "", "Address of the OTLP gRPC collector OpenTelemetry spans of lookups, Kubernetes API writes and reconciliations are exported to, e.g. otel-collector:4317. Disabled when empty.")
+	flags.BoolVar(&f.Tracing.Insecure, "tracing.insecure", false, "Whether to connect to the OTLP collector without TLS.")
+}
+
 type Command struct {
 	// Dependencies.
 	logger micrologger.Logger
 
 	// Internals.
+	// auditLog records the mutations of Endpoints and EndpointSlices. It is
+	// nil when disabled.
+	auditLog     *audit.Log
 	cobraCommand *cobra.Command
+	// endpointUpdater maintains the endpoint IP published by the run. It is
+	// nil until the run created it.
+	endpointUpdater *endpointupdater.EndpointUpdater
+	// explicitFlags are the names of the flags given on the command line,
+	// which take precedence over the config file when it is reloaded.
+	explicitFlags map[string]bool
+	// hangups receives SIGHUP, which triggers an immediate reconciliation.
+	hangups chan os.Signal
+	// healthServer serves the liveness and readiness endpoints. It is nil
+	// when disabled.
+	healthServer *health.Server
+	// notifier notifies systemd when running as Type=notify unit. It is nil
+	// otherwise.
+	notifier *systemd.Notifier
+	// printer prints human-friendly progress in interactive runs. It is nil
+	// otherwise.
+	printer *output.Printer
+	// providers are all providers created by the run, including the ones
+	// created when reloading the config file.
+	providers      []provider.Provider
+	providersMutex sync.Mutex
+	// jsonPrinter prints the machine-readable summary of the run. It is nil
+	// unless the JSON output format is used.
+	jsonPrinter *output.JSONPrinter
+
+	// Settings.
+	gitCommit string
 }
 
 func (c *Command) CobraCommand() *cobra.Command {
 	return c.cobraCommand
 }
 
-func (c *Command) Execute(cmd *cobra.Command, args []string) {
-	_ = c.logger.Log("info", "start adding annotations to KVM pod")
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	c.explicitFlags = flag.ExplicitFlags(cmd.Flags())
+	if f.Config != "" {
+		err := flag.LoadFile(f.Config, cmd.Flags())
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+	}
 
-	err := f.Validate()
-	if err != nil {
-		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
-		os.Exit(1)
+	// The KVM pod, the lease and the mock API server live in the first
+	// namespace.
+	if len(f.Kubernetes.Cluster.Namespaces) != 0 {
+		f.Kubernetes.Cluster.Namespace = f.Kubernetes.Cluster.Namespaces[0]
 	}
 
-	err = c.execute()
+	err := f.Validate()
 	if err != nil {
 		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
-		os.Exit(1)
+		return microerror.Mask(err)
 	}
 
-	_ = c.logger.Log("info", "finished adding annotations to KVM pod")
-}
+	// JSON output is meant to be consumed by scripts, so stdout must not
+	// contain anything else. The structured logs go to stderr instead.
+	if f.Output.Format == output.FormatJSON {
+		c.jsonPrinter, err = output.NewJSON(output.DefaultConfig())
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
 
-func (c *Command) execute() error {
-	var err error
+		c.logger, err = logging.Redirect(c.logger, os.Stderr)
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+	}
 
-	var k8sClients *k8sclient.Clients
-	{
-		var restConfig *rest.Config
-		{
-			c := k8srestconfig.Config{
-				Logger: c.logger,
-
-				Address:   f.Kubernetes.Address,
-				InCluster: f.Kubernetes.InCluster,
-				TLS: k8srestconfig.ConfigTLS{
-					CAFile:  f.Kubernetes.TLS.CaFile,
-					CrtFile: f.Kubernetes.TLS.CrtFile,
-					KeyFile: f.Kubernetes.TLS.KeyFile,
-				},
+	// Interactive runs print human-friendly progress. The structured logs are
+	// discarded in this case, since they would only clutter the terminal.
+	if c.jsonPrinter == nil {
+		interactive, err := output.IsInteractive(f.Output.Interactive)
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+
+		if interactive {
+			c.printer, err = output.New(output.DefaultConfig())
+			if err != nil {
+				_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+				return microerror.Mask(err)
 			}
 
-			restConfig, err = k8srestconfig.New(c)
+			c.logger, err = logging.Redirect(c.logger, ioutil.Discard)
 			if err != nil {
+				_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
 				return microerror.Mask(err)
 			}
 		}
+	}
 
-		k8sConfig := k8sclient.ClientsConfig{
-			Logger: c.logger,
+	// Batch files and update specs name their own services, so the fields are
+	// attached per entry in this case.
+	if f.Batch == "" && f.Filename == "" {
+		c.logger = c.logger.With(logFields()...)
+	}
+
+	_ = c.logger.Log("info", "start adding annotations to KVM pod")
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	// Everything set up below which has to be torn down is registered with
+	// the shutdown sequence. It runs once the process is asked to terminate,
+	// or when returning early because of a failure.
+	sequence := shutdown.NewSequence(c.logger)
+	defer func() {
+		_ = c.cleanup(sequence)
+	}()
+
+	// The audit log is closed last, so that the mutations of all other
+	// cleanup steps, e.g. withdrawing the endpoint IP, are recorded.
+	if f.Audit.File != "" {
+		auditConfig := audit.DefaultConfig()
 
-			RestConfig: restConfig,
+		auditConfig.Path = f.Audit.File
+
+		c.auditLog, err = audit.New(auditConfig)
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
 		}
 
-		k8sClients, err = k8sclient.NewClients(k8sConfig)
+		sequence.Add("close audit log", func(ctx context.Context) error {
+			return c.auditLog.Close()
+		})
+	}
+
+	// Lookups in flight might use the providers until the endpoint updater
+	// got shut down while withdrawing the endpoint IP, so they are closed late.
+	sequence.Add("close providers", c.closeProviders)
+
+	// The spans of all other cleanup steps are exported before tracing stops.
+	if f.Tracing.Endpoint != "" {
+		tracingConfig := tracing.DefaultConfig()
+
+		tracingConfig.Logger = c.logger
+
+		tracingConfig.Endpoint = f.Tracing.Endpoint
+		tracingConfig.Insecure = f.Tracing.Insecure
+		tracingConfig.Name = "k8s-endpoint-updater"
+		tracingConfig.Version = c.gitCommit
+
+		tracer, err := tracing.New(tracingConfig)
 		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
 			return microerror.Mask(err)
 		}
+
+		sequence.Add("stop tracing", tracer.Stop)
 	}
 
-	var newProvider provider.Provider
-	{
-		bridgeConfig := bridge.DefaultConfig()
+	// SIGHUP is caught right away, so that it does not terminate the process
+	// while starting up. It is handled once the endpoint IP got published.
+	c.hangups = make(chan os.Signal, 1)
+	signal.Notify(c.hangups, syscall.SIGHUP)
+	defer signal.Stop(c.hangups)
+
+	if f.Metrics.Address != "" {
+		metricsConfig := metrics.DefaultConfig()
 
-		bridgeConfig.Logger = c.logger
+		metricsConfig.Logger = c.logger
 
-		bridgeConfig.BridgeName = f.Provider.Bridge.Name
+		metricsConfig.Address = f.Metrics.Address
+		metricsConfig.GitCommit = c.gitCommit
 
-		newProvider, err = bridge.New(bridgeConfig)
+		metricsServer, err := metrics.New(metricsConfig)
 		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
 			return microerror.Mask(err)
 		}
+
+		metricsServer.Boot()
+		sequence.Add("stop metrics server", func(ctx context.Context) error {
+			metricsServer.Stop()
+			return nil
+		})
 	}
 
-	// We need to create the updater which is able to update Kubernetes endpoints.
-	var newUpdater *updater.Updater
-	{
-		updaterConfig := updater.DefaultConfig()
+	if f.Debug.Address != "" {
+		debugConfig := debug.DefaultConfig()
+
+		debugConfig.Logger = c.logger
+
+		debugConfig.Address = f.Debug.Address
+
+		debugServer, err := debug.New(debugConfig)
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+
+		debugServer.Boot()
+		sequence.Add("stop debug server", func(ctx context.Context) error {
+			debugServer.Stop()
+			return nil
+		})
+	}
+
+	if f.Health.Address != "" || f.Health.ReadyFile != "" {
+		healthConfig := health.DefaultConfig()
 
-		updaterConfig.K8sClient = k8sClients.K8sClient()
-		updaterConfig.Logger = c.logger
+		healthConfig.Logger = c.logger
 
-		newUpdater, err = updater.New(updaterConfig)
+		healthConfig.Address = f.Health.Address
+		healthConfig.FailureThreshold = f.Health.FailureThreshold
+		healthConfig.ReadyFile = f.Health.ReadyFile
+
+		c.healthServer, err = health.New(healthConfig)
 		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
 			return microerror.Mask(err)
 		}
+
+		c.healthServer.Boot()
+		sequence.Add("stop health server", func(ctx context.Context) error {
+			c.healthServer.Stop()
+			return nil
+		})
 	}
 
-	// Here we lookup the VM IP we are interested in.
-	var podIP net.IP
+	if f.Shutdown.Withdraw {
+		sequence.Add("withdraw endpoint IP", c.withdraw)
+	}
+
+	// Under systemd the service manager is told about the shutdown before
+	// any other cleanup step runs.
+	if os.Getenv(systemd.SocketEnv) != "" {
+		c.notifier, err = c.newNotifier()
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+
+		sequence.Add("notify systemd", func(ctx context.Context) error {
+			c.notifier.Stopping()
+			return nil
+		})
+	}
+
+	var reporter *telemetry.Reporter
 	{
-		action := func() error {
-			podIP, err = newProvider.Lookup()
+		telemetryConfig := telemetry.DefaultConfig()
 
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		telemetryConfig.Logger = c.logger
 
+		telemetryConfig.Enabled = f.Telemetry.Enabled
+		telemetryConfig.Endpoint = f.Telemetry.Endpoint
+		telemetryConfig.Version = c.gitCommit
+
+		reporter, err = telemetry.New(telemetryConfig)
+		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+	}
+
+	// In batch mode all services listed in the batch file, or all update specs,
+	// are published once and the process terminates afterwards.
+	if f.Batch != "" || f.Filename != "" {
+		if f.Batch != "" {
+			err = c.runBatch(ctx, cmd.Flags(), reporter)
+		} else {
+			err = c.runSpecs(ctx, reporter)
+		}
+		if IsCancelled(err) {
+			_ = c.logger.Log("info", "cancelled adding annotations to KVM pod")
 			return nil
+		} else if err != nil {
+			reporter.Inc("execution_failure")
+			c.report(reporter)
+			c.printer.Error(err)
+			c.printSummary(err)
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
 		}
 
-		err := backoff.Retry(action, backoff.NewExponential(backoff.MediumMaxWait, backoff.LongMaxInterval))
+		reporter.Inc("execution_success")
+		c.report(reporter)
+
+		c.printer.Table()
+		c.printSummary(nil)
+
+		return nil
+	}
+
+	// With leader election only the replica holding the lease publishes the
+	// endpoint IP. Standby replicas block until they acquire the lease.
+	if f.LeaderElection.Enabled && !f.DryRun {
+		// A failing run gives up the lease, so that the failure is returned
+		// once the leader election stopped.
+		var runErr error
+		leaderCtx, leaderCancel := context.WithCancel(ctx)
+		defer leaderCancel()
+
+		err = c.runLeaderElection(leaderCtx, func(ctx context.Context) {
+			runErr = c.run(ctx, reporter)
+			if runErr != nil {
+				leaderCancel()
+				return
+			}
+
+			_ = c.logger.Log("debug", "waiting until leader lease is lost")
+		})
+		if err == nil {
+			err = runErr
+		}
+		if err != nil {
+			c.printSummary(err)
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+			return microerror.Mask(err)
+		}
+	} else {
+		err = c.run(ctx, reporter)
 		if err != nil {
+			_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
 			return microerror.Mask(err)
 		}
+	}
+
+	if f.DryRun {
+		return nil
+	}
 
-		_ = c.logger.Log("debug", fmt.Sprintf("found pod info for service '%s'", f.Kubernetes.Cluster.Service), "ip", podIP.String())
+	// The endpoint IP is maintained in the background until the process is
+	// asked to terminate.
+	_ = c.logger.Log("debug", "waiting for shutdown")
+	<-ctx.Done()
+	_ = c.logger.Log("info", "stopped maintaining endpoint IP")
 
+	err = c.cleanup(sequence)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
 	}
 
-	// Use the updater to actually add annotations to the kvm pod.
+	return nil
+}
+
+// cleanup runs the given shutdown sequence bounded by the shutdown timeout.
+// The signal which cancelled the run does not bound it, since the cleanup has
+// to happen after it.
+func (c *Command) cleanup(sequence *shutdown.Sequence) error {
+	ctx, cancel := context.WithTimeout(context.Background(), f.Shutdown.Timeout)
+	defer cancel()
+
+	err := sequence.Run(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// withdraw removes the endpoint IP published by the run until the given
+// context expires.
+func (c *Command) withdraw(ctx context.Context) error {
+	if c.endpointUpdater == nil {
+		return nil
+	}
+
+	_ = c.logger.Log("info", "withdrawing endpoint IP before exiting", "timeout", f.Shutdown.Timeout.String())
+
+	err := c.endpointUpdater.Shutdown(ctx)
+	if endpointupdater.IsTimeout(err) {
+		_ = c.logger.Log("error", "withdrawing endpoint IP did not finish within the shutdown timeout, the endpoint IP remains published", "timeout", f.Shutdown.Timeout.String())
+		return microerror.Mask(err)
+	} else if err != nil {
+		_ = c.logger.Log("error", "withdrawing endpoint IP failed, the endpoint IP remains published")
+		return microerror.Mask(err)
+	}
+
+	_ = c.logger.Log("info", "withdrew endpoint IP")
+
+	return nil
+}
+
+// run publishes the endpoint IP and returns the failure, if any. Being
+// cancelled is no failure.
+func (c *Command) run(ctx context.Context, reporter *telemetry.Reporter) error {
+	err := c.execute(ctx, reporter)
+	if IsCancelled(err) {
+		_ = c.logger.Log("info", "cancelled adding annotations to KVM pod")
+		return nil
+	} else if err != nil {
+		reporter.Inc("execution_failure")
+		c.report(reporter)
+		c.printer.Error(err)
+		c.printSummary(err)
+		return microerror.Mask(err)
+	}
+
+	reporter.Inc("execution_success")
+	c.report(reporter)
+
+	c.printer.Table()
+	c.printSummary(nil)
+
+	_ = c.logger.Log("info", "finished adding annotations to KVM pod")
+
+	return nil
+}
+
+// report sends the telemetry report, if enabled. Failing to report never
+// affects the outcome of the command.
+func (c *Command) report(reporter *telemetry.Reporter) {
+	err := reporter.Report()
+	if err != nil {
+		_ = c.logger.Log("warning", fmt.Sprintf("sending telemetry report failed: %#v", microerror.Mask(err)))
+	}
+}
+
+func (c *Command) execute(ctx context.Context, reporter *telemetry.Reporter) error {
+	var err error
+
+	reporter.SetLabel("provider_kind", f.Provider.Kind)
+	reporter.SetLabel("updater_kind", f.Updater.Kind)
+
+	var k8sClient kubernetes.Interface
 	{
-		action := func() error {
-			err := newUpdater.AddAnnotations(f.Kubernetes.Cluster.Namespace, f.Kubernetes.Cluster.Service, f.Kubernetes.Pod.Name, podIP)
-			if err != nil {
-				return microerror.Mask(err)
-			}
+		c := k8s.Config{
+			Logger: c.logger,
 
-			return nil
+			Flag: f.Kubernetes,
 		}
 
-		err := backoff.Retry(action, backoff.NewExponential(backoff.MediumMaxWait, backoff.LongMaxInterval))
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	newProvider, err := c.newProvider(f)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// We need to create the updater which is able to update Kubernetes endpoints.
+	newUpdater, err := c.newUpdater(k8sClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var prober *probe.Prober
+	if f.Readiness.Probe != "" {
+		probeConfig := probe.DefaultConfig()
+
+		probeConfig.Logger = c.logger
+
+		probeConfig.Target = f.Readiness.Probe
+		probeConfig.Timeout = f.Readiness.Timeout
+
+		prober, err = probe.New(probeConfig)
 		if err != nil {
 			return microerror.Mask(err)
 		}
+	}
+
+	newEndpointUpdater, err := c.newEndpointUpdater(k8sClient, newProvider, newUpdater, prober, reporter)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	c.endpointUpdater = newEndpointUpdater
+
+	// The endpoint updater looks up the endpoint IP, publishes it and keeps
+	// maintaining it in the background until the given context is cancelled.
+	c.printer.Start("lookup")
+
+	_, err = newEndpointUpdater.Start(ctx)
+	if endpointupdater.IsCancelled(err) {
+		c.printer.Fail(ctx.Err())
+		return microerror.Maskf(cancelledError, "publishing endpoint IP")
+	} else if endpointupdater.IsRetryBudgetExhausted(err) {
+		c.printer.Fail(err)
+		_ = c.logger.Log("error", "gave up publishing endpoint IP", "reason", err.Error(), "namespace", f.Kubernetes.Cluster.Namespace, "services", strings.Join(f.Kubernetes.Cluster.Services, ","))
+		c.emitWarning(k8sClient, eventReasonRetryBudgetExhausted, fmt.Sprintf("Gave up publishing the endpoint IP: %s", err.Error()))
+		return microerror.Maskf(retryBudgetExhaustedError, "%s", err.Error())
+	} else if err != nil {
+		c.printer.Fail(err)
+		return microerror.Mask(err)
+	}
+
+	if !f.DryRun {
+		go c.followHangups(ctx, newEndpointUpdater)
+	}
+
+	return nil
+}
+
+// newEndpointUpdater creates the endpoint updater publishing the endpoint IPs
+// found by the given provider according to the flags.
+func (c *Command) newEndpointUpdater(k8sClient kubernetes.Interface, newProvider provider.Provider, newUpdater *updater.Updater, prober *probe.Prober, reporter *telemetry.Reporter) (*endpointupdater.EndpointUpdater, error) {
+	var err error
+
+	endpointUpdaterConfig := endpointupdater.DefaultConfig()
+
+	// The conntrack table can optionally be used to confirm the IP found by any
+	// other provider, since the guest VM might drop all kinds of probes.
+	if f.Provider.Conntrack.Confirm && newProvider != nil {
+		endpointUpdaterConfig.Conntrack, err = c.newConntrackProvider()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+	// IPs which do not respond are not published, so that no traffic gets
+	// blackholed in case the provider guessed wrong.
+	if f.Reachability.Probe != "" && newProvider != nil {
+		probeConfig := probe.DefaultConfig()
+
+		probeConfig.Logger = c.logger
 
-		_ = c.logger.Log("debug", fmt.Sprintf("added annotations to the KVM pod '%s'", f.Kubernetes.Pod.Name))
+		probeConfig.Target = f.Reachability.Probe
+		probeConfig.Timeout = f.Reachability.Timeout
+
+		endpointUpdaterConfig.Verifier, err = probe.New(probeConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
 	}
-	_ = c.logger.Log("debug", "waiting forever")
-	// wait forever
-	select {}
+	endpointUpdaterConfig.Health = c.healthServer
+	endpointUpdaterConfig.Notifier = c.notifier
+	endpointUpdaterConfig.K8sClient = k8sClient
+	endpointUpdaterConfig.Logger = c.logger
+	endpointUpdaterConfig.Observer = &observer{command: c, reporter: reporter, updater: newUpdater}
+	endpointUpdaterConfig.Prober = prober
+	endpointUpdaterConfig.Provider = newProvider
+	endpointUpdaterConfig.Updater = newUpdater
+
+	endpointUpdaterConfig.DaemonInterval = f.Reconcile.Interval
+	endpointUpdaterConfig.DaemonJitter = f.Reconcile.Jitter
+	endpointUpdaterConfig.DryRun = f.DryRun
+	endpointUpdaterConfig.Finalizer = f.Updater.Finalizer
+	endpointUpdaterConfig.IPFamily = f.IPFamily
+	endpointUpdaterConfig.Kind = f.Updater.Kind
+	endpointUpdaterConfig.PodName = f.Kubernetes.Pod.Name
+	endpointUpdaterConfig.PodNamespace = podNamespace()
+	endpointUpdaterConfig.ReadinessDemote = f.Readiness.Demote
+	endpointUpdaterConfig.ReadinessFailureThreshold = f.Readiness.FailureThreshold
+	endpointUpdaterConfig.ReadinessInterval = f.Readiness.Interval
+	endpointUpdaterConfig.ReassertInterval = f.ReassertInterval
+	endpointUpdaterConfig.Repair = f.Updater.Repair
+	endpointUpdaterConfig.RetryInitialInterval = f.Retry.InitialInterval
+	endpointUpdaterConfig.RetryMaxElapsedTime = f.Retry.MaxElapsedTime
+	endpointUpdaterConfig.RetryMaxInterval = f.Retry.MaxInterval
+	endpointUpdaterConfig.RetryMaxRetries = f.Retry.MaxRetries
+	endpointUpdaterConfig.RetryMultiplier = f.Retry.Multiplier
+	endpointUpdaterConfig.RetryStrategy = f.Retry.Strategy
+	endpointUpdaterConfig.Targets = targets()
+	endpointUpdaterConfig.VRRPPollInterval = f.Provider.VRRP.PollInterval
+	endpointUpdaterConfig.Watch = cmdprovider.Watch(f.Provider)
+
+	// Annotations are put on the KVM pod for the first service only.
+	if f.Updater.Kind == updater.KindAnnotation {
+		endpointUpdaterConfig.Targets = []endpointupdater.Target{
+			{Namespace: f.Kubernetes.Cluster.Namespace, Service: f.Kubernetes.Cluster.Services[0]},
+		}
+	}
+
+	newEndpointUpdater, err := endpointupdater.New(endpointUpdaterConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newEndpointUpdater, nil
+}
+
+// newUpdater creates the updater publishing the endpoint IPs according to the
+// updater flags.
+func (c *Command) newUpdater(k8sClient kubernetes.Interface) (*updater.Updater, error) {
+	var err error
+
+	updaterConfig := updater.DefaultConfig()
+
+	updaterConfig.AuditLog = c.auditLog
+	updaterConfig.K8sClient = k8sClient
+	updaterConfig.Logger = c.logger
+
+	updaterConfig.DryRun = f.DryRun
+	// Dry-run output must not get mixed up with the JSON summary.
+	if c.jsonPrinter != nil {
+		updaterConfig.Writer = os.Stderr
+	}
+
+	for _, s := range f.Kubernetes.Cluster.Ports {
+		port, err := updater.ParsePort(s)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		updaterConfig.Ports = append(updaterConfig.Ports, port)
+	}
+
+	kind, managed := updater.ResourceKind(f.Updater.Kind)
+	if managed {
+		updaterConfig.CreateMissing = f.Updater.CreateMissing
+		updaterConfig.Kind = kind
+		updaterConfig.PatchStrategy = f.Updater.PatchStrategy
+		updaterConfig.ServerSideApply = f.Updater.ServerSideApply
+
+		updaterConfig.Pod, err = c.newPodInfo(k8sClient)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		updaterConfig.Owner, err = c.owner(updaterConfig.Pod)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		if f.Updater.OwnerReference.Enabled {
+			updaterConfig.OwnerReference = c.newOwnerReference(updaterConfig.Pod)
+		}
+	}
+
+	newUpdater, err := updater.New(updaterConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newUpdater, nil
 }
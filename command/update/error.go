@@ -22,3 +22,17 @@ var invalidConfigError = microerror.New("invalid config")
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var leaseLostError = microerror.New("lease lost")
+
+// IsLeaseLost asserts leaseLostError.
+func IsLeaseLost(err error) bool {
+	return microerror.Cause(err) == leaseLostError
+}
+
+var retryBudgetExhaustedError = microerror.New("retry budget exhausted")
+
+// IsRetryBudgetExhausted asserts retryBudgetExhaustedError.
+func IsRetryBudgetExhausted(err error) bool {
+	return microerror.Cause(err) == retryBudgetExhaustedError
+}
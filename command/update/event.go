@@ -0,0 +1,72 @@
+package update
+
+import (
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// eventComponent is the source component of the emitted Kubernetes events.
+	eventComponent = "k8s-endpoint-updater"
+	// eventReasonRetryBudgetExhausted is the reason of the event emitted once
+	// the updater gave up publishing the endpoint IP.
+	eventReasonRetryBudgetExhausted = "RetryBudgetExhausted"
+)
+
+// emitWarning emits a Kubernetes warning event with the given reason and
+// message. The event refers to the KVM pod when its name is known and to the
+// first service otherwise, so that it shows up where operators look for it.
+// Failing to emit the event is logged only, since it must not hide the
+// failure it reports.
+func (c *Command) emitWarning(k8sClient kubernetes.Interface, reason string, message string) {
+	var ref corev1.ObjectReference
+	if f.Kubernetes.Pod.Name != "" {
+		ref = corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       f.Kubernetes.Pod.Name,
+			Namespace:  podNamespace(),
+			UID:        types.UID(f.Kubernetes.Pod.UID),
+		}
+	} else {
+		t := targets()[0]
+		ref = corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Name:       t.Service,
+			Namespace:  t.Namespace,
+		}
+	}
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: ref.Name + ".",
+			Namespace:    ref.Namespace,
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        message,
+		Source: corev1.EventSource{
+			Component: eventComponent,
+			Host:      f.Kubernetes.Pod.NodeName,
+		},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           corev1.EventTypeWarning,
+	}
+
+	_, err := k8sClient.CoreV1().Events(ref.Namespace).Create(event)
+	if err != nil {
+		_ = c.logger.Log("warning", fmt.Sprintf("emitting event failed: %#v", microerror.Mask(err)), "reason", reason)
+		return
+	}
+
+	_ = c.logger.Log("debug", "emitted event", "reason", reason, "kind", ref.Kind, "name", ref.Name)
+}
@@ -0,0 +1,5 @@
+package daemon
+
+type Daemon struct {
+	Enabled bool
+}
@@ -0,0 +1,5 @@
+package debug
+
+type Debug struct {
+	Address string
+}
@@ -0,0 +1,204 @@
+package flag
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LoadFile applies the settings of the given YAML file to the given flag set.
+// Keys are the flag names, either dotted or nested, e.g.
+//
+//	provider:
+//	  kind: bridge
+//	  bridge.name: br-abc
+//	service.kubernetes.cluster.service: master
+//
+// Flags explicitly given on the command line take precedence over the file.
+func LoadFile(path string, flags *pflag.FlagSet) error {
+	values, keys, err := readFile(path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, k := range keys {
+		flag := flags.Lookup(k)
+		if flag == nil {
+			return microerror.Maskf(invalidFlagsError, "unknown key %#q in config file %#q", k, path)
+		}
+		if flag.Changed {
+			continue
+		}
+
+		err := setValue(flags, k, values[k])
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "invalid value for key %#q in config file %#q: %s", k, path, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ReloadFile applies the settings of the given YAML file like LoadFile, after
+// LoadFile applied an earlier version of it already. Only the given explicit
+// flags, which were given on the command line, take precedence over the file.
+// Keys removed from the file keep their last value.
+func ReloadFile(path string, flags *pflag.FlagSet, explicit map[string]bool) error {
+	values, keys, err := readFile(path)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	for _, k := range keys {
+		flag := flags.Lookup(k)
+		if flag == nil {
+			return microerror.Maskf(invalidFlagsError, "unknown key %#q in config file %#q", k, path)
+		}
+		if explicit[k] {
+			continue
+		}
+
+		err = setValue(flags, k, values[k])
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "invalid value for key %#q in config file %#q: %s", k, path, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ExplicitFlags returns the names of the flags given on the command line. It
+// has to be called before LoadFile, which marks the flags it sets as given.
+func ExplicitFlags(flags *pflag.FlagSet) map[string]bool {
+	explicit := map[string]bool{}
+	flags.Visit(func(flag *pflag.Flag) {
+		explicit[flag.Name] = true
+	})
+
+	return explicit
+}
+
+// readFile returns the flattened settings of the given YAML file along with
+// their sorted keys. Values are either strings or, for YAML lists, string
+// slices.
+func readFile(path string) (map[string]interface{}, []string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	var m map[interface{}]interface{}
+	err = yaml.Unmarshal(b, &m)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	values := map[string]interface{}{}
+	err = flatten("", m, values)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	// Sorting the keys keeps errors deterministic.
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return values, keys, nil
+}
+
+func flatten(prefix string, m map[interface{}]interface{}, values map[string]interface{}) error {
+	for k, v := range m {
+		key := fmt.Sprint(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := v.(type) {
+		case map[interface{}]interface{}:
+			err := flatten(key, v, values)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+		case []interface{}:
+			var s []string
+			for _, e := range v {
+				s = append(s, fmt.Sprint(e))
+			}
+			values[key] = s
+		case nil:
+			return microerror.Maskf(invalidFlagsError, "key %#q must not be empty", key)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+
+	return nil
+}
+
+// SetValues applies the given nested settings to the flags below the given
+// prefix, regardless of whether they were given on the command line. It is
+// used for settings given per entry of a batch file.
+func SetValues(prefix string, m map[interface{}]interface{}, flags *pflag.FlagSet) error {
+	values := map[string]interface{}{}
+	err := flatten(prefix, m, values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if flags.Lookup(k) == nil {
+			return microerror.Maskf(invalidFlagsError, "unknown key %#q", k)
+		}
+
+		err := setValue(flags, k, values[k])
+		if err != nil {
+			return microerror.Maskf(invalidFlagsError, "invalid value for key %#q: %s", k, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// setValue sets the given flag to the given string or string slice value.
+// Setting a slice flag again appends to its values, so they are replaced
+// instead. Each element of a YAML list becomes one element of the slice, while
+// strings are parsed like on the command line, e.g. comma separated.
+func setValue(flags *pflag.FlagSet, name string, value interface{}) error {
+	s, isSlice := flags.Lookup(name).Value.(pflag.SliceValue)
+
+	var err error
+	switch v := value.(type) {
+	case []string:
+		if isSlice {
+			err = s.Replace(v)
+		} else {
+			err = flags.Set(name, strings.Join(v, ","))
+		}
+	default:
+		if isSlice {
+			err = s.Replace(nil)
+		}
+		if err == nil {
+			err = flags.Set(name, fmt.Sprint(v))
+		}
+	}
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
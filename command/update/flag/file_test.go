@@ -0,0 +1,117 @@
+package flag
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func Test_LoadFile(t *testing.T) {
+	testCases := []struct {
+		name             string
+		args             []string
+		file             string
+		expectedPorts    []string
+		expectedServices []string
+	}{
+		{
+			name:             "case 0: list elements are kept intact",
+			file:             "service.kubernetes.cluster.ports: [\"https:443\", \"http:80\"]\nservice.kubernetes.cluster.service: [master, worker]\n",
+			expectedPorts:    []string{"https:443", "http:80"},
+			expectedServices: []string{"master", "worker"},
+		},
+		{
+			name:             "case 1: strings are parsed like on the command line",
+			file:             "service.kubernetes.cluster.ports: https:443\nservice.kubernetes.cluster.service: master,worker\n",
+			expectedPorts:    []string{"https:443"},
+			expectedServices: []string{"master", "worker"},
+		},
+		{
+			name:             "case 2: the command line takes precedence",
+			args:             []string{"--service.kubernetes.cluster.service", "api"},
+			file:             "service.kubernetes.cluster.service: [master, worker]\n",
+			expectedPorts:    nil,
+			expectedServices: []string{"api"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, flags := newTestFlags(t, tc.args)
+
+			err := LoadFile(writeTestFile(t, tc.file), flags)
+			if err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+
+			if !reflect.DeepEqual(f.Kubernetes.Cluster.Ports, tc.expectedPorts) {
+				t.Fatalf("expected ports %v, got %v", tc.expectedPorts, f.Kubernetes.Cluster.Ports)
+			}
+			if !reflect.DeepEqual(f.Kubernetes.Cluster.Services, tc.expectedServices) {
+				t.Fatalf("expected services %v, got %v", tc.expectedServices, f.Kubernetes.Cluster.Services)
+			}
+		})
+	}
+}
+
+func Test_SetValues(t *testing.T) {
+	f, flags := newTestFlags(t, []string{"--service.kubernetes.cluster.ports", "https:443"})
+
+	// Every batch entry replaces the values of the previous one.
+	for _, ports := range [][]interface{}{{"http:80", "metrics:9090"}, {"dns:53:UDP"}} {
+		m := map[interface{}]interface{}{
+			"ports": ports,
+		}
+
+		err := SetValues("service.kubernetes.cluster", m, flags)
+		if err != nil {
+			t.Fatalf("expected no error, got %#v", err)
+		}
+
+		var expected []string
+		for _, p := range ports {
+			expected = append(expected, p.(string))
+		}
+		if !reflect.DeepEqual(f.Kubernetes.Cluster.Ports, expected) {
+			t.Fatalf("expected ports %v, got %v", expected, f.Kubernetes.Cluster.Ports)
+		}
+	}
+}
+
+func newTestFlags(t *testing.T, args []string) (*Flag, *pflag.FlagSet) {
+	t.Helper()
+
+	f := &Flag{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringArrayVar(&f.Kubernetes.Cluster.Ports, "service.kubernetes.cluster.ports", nil, "")
+	flags.StringSliceVar(&f.Kubernetes.Cluster.Services, "service.kubernetes.cluster.service", nil, "")
+
+	err := flags.Parse(args)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	return f, flags
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "flag")
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	return path
+}
@@ -1,31 +1,277 @@
 package flag
 
 import (
+	"strings"
+	"time"
+
 	"github.com/giantswarm/microerror"
 
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/audit"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/daemon"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/debug"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/health"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/leaderelection"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/metrics"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/output"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/reachability"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/readiness"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/reconcile"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/retry"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/telemetry"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/tracing"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/updater"
 )
 
 type Flag struct {
-	Kubernetes kubernetes.Kubernetes
-	Provider   provider.Provider
+	Audit            audit.Audit
+	Batch            string
+	Config           string
+	Daemon           daemon.Daemon
+	Debug            debug.Debug
+	DryRun           bool
+	Filename         string
+	Health           health.Health
+	IPFamily         string
+	Kubernetes       kubernetes.Kubernetes
+	LeaderElection   leaderelection.LeaderElection
+	Metrics          metrics.Metrics
+	Output           output.Output
+	Provider         provider.Provider
+	Reachability     reachability.Reachability
+	Readiness        readiness.Readiness
+	ReassertInterval time.Duration
+	Reconcile        reconcile.Reconcile
+	Retry            retry.Retry
+	Shutdown         shutdown.Shutdown
+	Telemetry        telemetry.Telemetry
+	Tracing          tracing.Tracing
+	Updater          updater.Updater
 }
 
 func (f *Flag) Validate() error {
 	if f.Kubernetes.Cluster.Namespace == "" {
 		return microerror.Maskf(invalidFlagsError, "guest cluster namespace must not be empty")
 	}
-	if f.Kubernetes.Cluster.Service == "" {
+	for _, n := range f.Kubernetes.Cluster.Namespaces {
+		if n == "" {
+			return microerror.Maskf(invalidFlagsError, "guest cluster namespace must not be empty")
+		}
+	}
+	if len(f.Kubernetes.Cluster.Services) == 0 && f.Batch == "" && f.Filename == "" {
 		return microerror.Maskf(invalidFlagsError, "guest cluster service must not be empty")
 	}
-
-	if f.Provider.Kind == "env" && f.Provider.Env.Prefix == "" {
-		return microerror.Maskf(invalidFlagsError, "env prefix must not be empty")
+	if f.Batch != "" && (f.Daemon.Enabled || f.LeaderElection.Enabled) {
+		return microerror.Maskf(invalidFlagsError, "batch file cannot be combined with daemon mode or leader election")
+	}
+	if f.Filename != "" && (f.Batch != "" || f.Daemon.Enabled || f.LeaderElection.Enabled) {
+		return microerror.Maskf(invalidFlagsError, "update specs cannot be combined with a batch file, daemon mode or leader election")
 	}
+	for _, s := range f.Kubernetes.Cluster.Services {
+		if s == "" || strings.HasPrefix(s, "/") || strings.HasSuffix(s, "/") || strings.Count(s, "/") > 1 {
+			return microerror.Maskf(invalidFlagsError, "guest cluster service %#q must be formatted as service or namespace/service", s)
+		}
+	}
+
 	if f.Provider.Kind == "" {
 		return microerror.Maskf(invalidFlagsError, "provider kind must not be empty")
 	}
+	switch f.Provider.Mode {
+	case "composite", "fallback":
+	default:
+		return microerror.Maskf(invalidFlagsError, "provider mode must be one of composite or fallback")
+	}
+	for _, k := range strings.Split(f.Provider.Kind, ",") {
+		if strings.TrimSpace(k) == "" {
+			return microerror.Maskf(invalidFlagsError, "provider kind %#q must not contain empty kinds", f.Provider.Kind)
+		}
+		if strings.TrimSpace(k) == "vrrp" && f.Provider.VRRP.PollInterval <= 0 {
+			return microerror.Maskf(invalidFlagsError, "vrrp poll interval must be greater than zero")
+		}
+		if strings.TrimSpace(k) == "env" {
+			switch f.Provider.Env.Format {
+			case "indexed":
+				if f.Provider.Env.Prefix == "" {
+					return microerror.Maskf(invalidFlagsError, "env prefix must not be empty")
+				}
+			case "json":
+				if f.Provider.Env.Variable == "" {
+					return microerror.Maskf(invalidFlagsError, "env variable must not be empty")
+				}
+			default:
+				return microerror.Maskf(invalidFlagsError, "env format must be one of indexed or json")
+			}
+		}
+	}
+
+	if f.Daemon.Enabled && f.Reconcile.Interval <= 0 {
+		return microerror.Maskf(invalidFlagsError, "reconcile interval must be greater than zero when daemon mode is enabled")
+	}
+	if f.Reconcile.Interval < 0 {
+		return microerror.Maskf(invalidFlagsError, "reconcile interval must not be negative")
+	}
+	if f.Reconcile.Jitter < 0 {
+		return microerror.Maskf(invalidFlagsError, "reconcile jitter must not be negative")
+	}
+
+	if (f.Health.Address != "" || f.Health.ReadyFile != "") && f.Health.FailureThreshold <= 0 {
+		return microerror.Maskf(invalidFlagsError, "health failure threshold must be greater than zero")
+	}
+
+	if f.LeaderElection.Enabled {
+		if f.LeaderElection.LeaseDuration <= f.LeaderElection.RenewDeadline {
+			return microerror.Maskf(invalidFlagsError, "leader election lease duration must be greater than the renew deadline")
+		}
+		if f.LeaderElection.RetryPeriod <= 0 {
+			return microerror.Maskf(invalidFlagsError, "leader election retry period must be greater than zero")
+		}
+	}
+
+	switch f.IPFamily {
+	case "ipv4", "ipv6", "dual":
+	default:
+		return microerror.Maskf(invalidFlagsError, "ip family must be one of ipv4, ipv6 or dual")
+	}
+	if f.IPFamily == "dual" && f.Updater.Kind == "annotation" {
+		return microerror.Maskf(invalidFlagsError, "ip family dual requires an updater kind other than annotation")
+	}
+
+	if f.Readiness.Probe != "" {
+		if f.Updater.Kind == "annotation" {
+			return microerror.Maskf(invalidFlagsError, "readiness probe requires an updater kind other than annotation")
+		}
+		if f.Readiness.Interval <= 0 {
+			return microerror.Maskf(invalidFlagsError, "readiness interval must be greater than zero")
+		}
+	}
+	if f.Readiness.Demote {
+		if f.Readiness.Probe == "" {
+			return microerror.Maskf(invalidFlagsError, "readiness demotion requires a readiness probe")
+		}
+		if f.Readiness.FailureThreshold <= 0 {
+			return microerror.Maskf(invalidFlagsError, "readiness failure threshold must be greater than zero")
+		}
+	}
+
+	if f.ReassertInterval < 0 {
+		return microerror.Maskf(invalidFlagsError, "reassert interval must not be negative")
+	}
+
+	switch f.Retry.Strategy {
+	case "constant", "exponential", "immediate":
+	default:
+		return microerror.Maskf(invalidFlagsError, "retry strategy must be one of constant, exponential or immediate")
+	}
+	if f.Retry.InitialInterval <= 0 {
+		return microerror.Maskf(invalidFlagsError, "retry initial interval must be greater than zero")
+	}
+	if f.Retry.MaxElapsedTime < 0 {
+		return microerror.Maskf(invalidFlagsError, "retry max elapsed time must not be negative")
+	}
+	if f.Retry.MaxInterval < f.Retry.InitialInterval {
+		return microerror.Maskf(invalidFlagsError, "retry max interval must not be less than the initial interval")
+	}
+	if f.Retry.MaxRetries < 0 {
+		return microerror.Maskf(invalidFlagsError, "retry max retries must not be negative")
+	}
+	if f.Retry.Multiplier < 1 {
+		return microerror.Maskf(invalidFlagsError, "retry multiplier must be at least 1")
+	}
+
+	if f.Shutdown.Timeout <= 0 {
+		return microerror.Maskf(invalidFlagsError, "shutdown timeout must be greater than zero")
+	}
+
+	if f.Telemetry.Enabled && f.Telemetry.Endpoint == "" {
+		return microerror.Maskf(invalidFlagsError, "telemetry endpoint must not be empty when telemetry is enabled")
+	}
+
+	switch f.Updater.Kind {
+	case "annotation", "both", "endpoints", "endpointslice":
+	default:
+		return microerror.Maskf(invalidFlagsError, "updater kind must be one of annotation, endpoints, endpointslice or both")
+	}
+	if f.Updater.CreateMissing && f.Updater.Kind == "annotation" {
+		return microerror.Maskf(invalidFlagsError, "creating missing endpoints requires an updater kind other than annotation")
+	}
+	if f.Updater.ServerSideApply && f.Updater.Kind == "annotation" {
+		return microerror.Maskf(invalidFlagsError, "server-side apply requires an updater kind other than annotation")
+	}
+
+	switch f.Output.Format {
+	case "json", "text":
+	default:
+		return microerror.Maskf(invalidFlagsError, "output format must be one of json or text")
+	}
+
+	switch f.Updater.PatchStrategy {
+	case "json", "merge", "update":
+	default:
+		return microerror.Maskf(invalidFlagsError, "patch strategy must be one of json, merge or update")
+	}
+	if f.Updater.ServerSideApply && f.Updater.PatchStrategy != "update" {
+		return microerror.Maskf(invalidFlagsError, "patch strategy must be update with server-side apply")
+	}
+	if f.Updater.Finalizer {
+		if f.Updater.Kind == "annotation" {
+			return microerror.Maskf(invalidFlagsError, "cleanup finalizer requires an updater kind other than annotation")
+		}
+		if f.Kubernetes.Pod.Name == "" {
+			return microerror.Maskf(invalidFlagsError, "cleanup finalizer requires the pod name")
+		}
+	}
+	if f.Updater.OwnerReference.Enabled {
+		if f.Updater.Kind == "annotation" {
+			return microerror.Maskf(invalidFlagsError, "owner reference requires an updater kind other than annotation")
+		}
+
+		o := f.Updater.OwnerReference
+		if o.Name == "" && f.Kubernetes.Pod.Name == "" {
+			return microerror.Maskf(invalidFlagsError, "owner reference requires the pod name or an owner name")
+		}
+		if o.Name != "" && (o.APIVersion == "" || o.Kind == "" || o.UID == "") {
+			return microerror.Maskf(invalidFlagsError, "owner reference requires api version, kind and UID when an owner name is given")
+		}
+
+		// The garbage collector treats owners in other namespaces as absent
+		// and deletes the owned objects right away. The KVM pod lives in
+		// the pod namespace, other owners in the guest cluster namespace.
+		ownerNamespace := f.Kubernetes.Cluster.Namespace
+		if o.Name == "" && f.Kubernetes.Pod.Namespace != "" {
+			ownerNamespace = f.Kubernetes.Pod.Namespace
+		}
+		for _, n := range f.targetNamespaces() {
+			if n != ownerNamespace {
+				return microerror.Maskf(invalidFlagsError, "owner reference requires all services to be published in the namespace of the owner %#q, got %#q", ownerNamespace, n)
+			}
+		}
+	}
+	if f.Updater.Repair && f.Updater.Kind != "endpoints" && f.Updater.Kind != "both" {
+		return microerror.Maskf(invalidFlagsError, "repairing endpoints requires the updater kind endpoints or both")
+	}
 
 	return nil
 }
+
+// targetNamespaces returns the namespaces the services are published in.
+// Services given as namespace/service are only published in their namespace,
+// all others in each of the configured namespaces.
+func (f *Flag) targetNamespaces() []string {
+	namespaces := f.Kubernetes.Cluster.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{f.Kubernetes.Cluster.Namespace}
+	}
+
+	var result []string
+	for _, s := range f.Kubernetes.Cluster.Services {
+		if i := strings.Index(s, "/"); i >= 0 {
+			result = append(result, s[:i])
+			continue
+		}
+		result = append(result, namespaces...)
+	}
+
+	return result
+}
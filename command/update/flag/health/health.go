@@ -0,0 +1,11 @@
+package health
+
+import (
+	"time"
+)
+
+type Health struct {
+	Address          string
+	FailureThreshold time.Duration
+	ReadyFile        string
+}
@@ -1,6 +1,8 @@
 package cluster
 
 type Cluster struct {
-	Namespace string
-	Service   string
+	Namespace  string
+	Namespaces []string
+	Ports      []string
+	Services   []string
 }
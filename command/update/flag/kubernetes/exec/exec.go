@@ -0,0 +1,7 @@
+package exec
+
+type Exec struct {
+	APIVersion string
+	Args       []string
+	Command    string
+}
@@ -2,14 +2,24 @@ package kubernetes
 
 import (
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes/cluster"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes/exec"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes/pod"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes/proxy"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes/tls"
 )
 
 type Kubernetes struct {
-	Address   string
-	Cluster   cluster.Cluster
-	InCluster bool
-	Pod       pod.Pod
-	TLS       tls.TLS
+	Address    string
+	Burst      int
+	Cluster    cluster.Cluster
+	Context    string
+	Exec       exec.Exec
+	InCluster  bool
+	Kubeconfig string
+	Mock       bool
+	Pod        pod.Pod
+	Proxy      proxy.Proxy
+	QPS        float32
+	TLS        tls.TLS
+	Token      string
 }
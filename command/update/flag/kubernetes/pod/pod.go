@@ -1,5 +1,8 @@
 package pod
 
 type Pod struct {
-	Name string
+	Name      string
+	Namespace string
+	NodeName  string
+	UID       string
 }
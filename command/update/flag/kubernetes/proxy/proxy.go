@@ -0,0 +1,6 @@
+package proxy
+
+type Proxy struct {
+	NoProxy string
+	URL     string
+}
@@ -1,7 +1,8 @@
 package tls
 
 type TLS struct {
-	CaFile  string
-	CrtFile string
-	KeyFile string
+	CaFile    string
+	CrtFile   string
+	KeyFile   string
+	TokenFile string
 }
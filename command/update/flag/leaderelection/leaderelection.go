@@ -0,0 +1,14 @@
+package leaderelection
+
+import (
+	"time"
+)
+
+type LeaderElection struct {
+	Enabled       bool
+	LeaseDuration time.Duration
+	Name          string
+	Namespace     string
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
@@ -0,0 +1,5 @@
+package metrics
+
+type Metrics struct {
+	Address string
+}
@@ -0,0 +1,6 @@
+package output
+
+type Output struct {
+	Format      string
+	Interactive string
+}
@@ -0,0 +1,7 @@
+package arp
+
+type ARP struct {
+	Interface string
+	MACPrefix string
+	Path      string
+}
@@ -0,0 +1,9 @@
+package bpf
+
+import "time"
+
+type BPF struct {
+	Interface string
+	MAC       string
+	Timeout   time.Duration
+}
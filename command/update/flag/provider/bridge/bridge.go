@@ -1,5 +1,12 @@
 package bridge
 
+import "time"
+
 type Bridge struct {
-	Name string
+	Names         []string
+	Offset        int
+	Pattern       string
+	PreferredCIDR string
+	WaitTimeout   time.Duration
+	Watch         bool
 }
@@ -0,0 +1,7 @@
+package conntrack
+
+type Conntrack struct {
+	Confirm   bool
+	Interface string
+	Path      string
+}
@@ -0,0 +1,10 @@
+package dns
+
+import "time"
+
+type DNS struct {
+	Hostnames []string
+	Server    string
+	SRV       string
+	Timeout   time.Duration
+}
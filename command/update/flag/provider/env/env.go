@@ -1,5 +1,7 @@
 package env
 
 type Env struct {
-	Prefix string
+	Format   string
+	Prefix   string
+	Variable string
 }
@@ -1,7 +1,13 @@
 package etcd
 
+import (
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/etcd/tls"
+)
+
 type Etcd struct {
 	Address string
 	Kind    string
 	Prefix  string
+	TLS     tls.TLS
+	Watch   bool
 }
@@ -0,0 +1,7 @@
+package tls
+
+type TLS struct {
+	CaFile  string
+	CrtFile string
+	KeyFile string
+}
@@ -0,0 +1,9 @@
+package exec
+
+import "time"
+
+type Exec struct {
+	Args    []string
+	Command string
+	Timeout time.Duration
+}
@@ -0,0 +1,6 @@
+package fdb
+
+type FDB struct {
+	Bridge string
+	Device string
+}
@@ -0,0 +1,6 @@
+package file
+
+type File struct {
+	Path  string
+	Watch bool
+}
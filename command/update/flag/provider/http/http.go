@@ -0,0 +1,13 @@
+package http
+
+import (
+	"time"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/http/tls"
+)
+
+type HTTP struct {
+	Timeout time.Duration
+	TLS     tls.TLS
+	URL     string
+}
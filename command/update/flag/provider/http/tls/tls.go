@@ -0,0 +1,8 @@
+package tls
+
+type TLS struct {
+	CaFile    string
+	CrtFile   string
+	KeyFile   string
+	TokenFile string
+}
@@ -0,0 +1,10 @@
+package kvmconfig
+
+type KVMConfig struct {
+	APIVersion string
+	Kubeconfig string
+	Name       string
+	Namespace  string
+	Path       string
+	Resource   string
+}
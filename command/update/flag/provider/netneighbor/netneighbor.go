@@ -0,0 +1,7 @@
+package netneighbor
+
+type NetNeighbor struct {
+	InterfaceAlias string
+	MAC            string
+	Source         string
+}
@@ -1,14 +1,45 @@
 package provider
 
 import (
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/arp"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/bpf"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/bridge"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/conntrack"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/dns"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/env"
 	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/etcd"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/exec"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/fdb"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/file"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/http"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/kvmconfig"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/netneighbor"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/route"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/scan"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/static"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/vrrp"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider/whereabouts"
 )
 
 type Provider struct {
-	Bridge bridge.Bridge
-	Env    env.Env
-	Etcd   etcd.Etcd
-	Kind   string
+	ARP         arp.ARP
+	BPF         bpf.BPF
+	Bridge      bridge.Bridge
+	Conntrack   conntrack.Conntrack
+	DNS         dns.DNS
+	Env         env.Env
+	Etcd        etcd.Etcd
+	Exec        exec.Exec
+	FDB         fdb.FDB
+	File        file.File
+	HTTP        http.HTTP
+	Kind        string
+	KVMConfig   kvmconfig.KVMConfig
+	Mode        string
+	NetNeighbor netneighbor.NetNeighbor
+	Route       route.Route
+	Scan        scan.Scan
+	Static      static.Static
+	VRRP        vrrp.VRRP
+	Whereabouts whereabouts.Whereabouts
 }
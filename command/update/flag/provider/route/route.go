@@ -0,0 +1,6 @@
+package route
+
+type Route struct {
+	Device string
+	Path   string
+}
@@ -0,0 +1,10 @@
+package scan
+
+import "time"
+
+type Scan struct {
+	CIDR        string
+	Concurrency int
+	Port        int
+	Timeout     time.Duration
+}
@@ -0,0 +1,5 @@
+package static
+
+type Static struct {
+	IPs []string
+}
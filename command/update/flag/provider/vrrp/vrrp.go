@@ -0,0 +1,10 @@
+package vrrp
+
+import "time"
+
+type VRRP struct {
+	Interface    string
+	PollInterval time.Duration
+	StateFile    string
+	VIP          string
+}
@@ -0,0 +1,8 @@
+package whereabouts
+
+type Whereabouts struct {
+	Interface    string
+	Kubeconfig   string
+	Namespace    string
+	PodNamespace string
+}
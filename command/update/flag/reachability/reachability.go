@@ -0,0 +1,10 @@
+package reachability
+
+import (
+	"time"
+)
+
+type Reachability struct {
+	Probe   string
+	Timeout time.Duration
+}
@@ -0,0 +1,13 @@
+package readiness
+
+import (
+	"time"
+)
+
+type Readiness struct {
+	Demote           bool
+	FailureThreshold int
+	Interval         time.Duration
+	Probe            string
+	Timeout          time.Duration
+}
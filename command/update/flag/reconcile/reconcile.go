@@ -0,0 +1,10 @@
+package reconcile
+
+import (
+	"time"
+)
+
+type Reconcile struct {
+	Interval time.Duration
+	Jitter   float64
+}
@@ -0,0 +1,14 @@
+package retry
+
+import (
+	"time"
+)
+
+type Retry struct {
+	InitialInterval time.Duration
+	MaxElapsedTime  time.Duration
+	MaxInterval     time.Duration
+	MaxRetries      int
+	Multiplier      float64
+	Strategy        string
+}
@@ -0,0 +1,10 @@
+package shutdown
+
+import (
+	"time"
+)
+
+type Shutdown struct {
+	Timeout  time.Duration
+	Withdraw bool
+}
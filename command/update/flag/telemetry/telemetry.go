@@ -0,0 +1,6 @@
+package telemetry
+
+type Telemetry struct {
+	Enabled  bool
+	Endpoint string
+}
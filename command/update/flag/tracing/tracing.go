@@ -0,0 +1,6 @@
+package tracing
+
+type Tracing struct {
+	Endpoint string
+	Insecure bool
+}
@@ -0,0 +1,9 @@
+package ownerreference
+
+type OwnerReference struct {
+	APIVersion string
+	Enabled    bool
+	Kind       string
+	Name       string
+	UID        string
+}
@@ -0,0 +1,13 @@
+package updater
+
+import "github.com/giantswarm/k8s-endpoint-updater/command/update/flag/updater/ownerreference"
+
+type Updater struct {
+	CreateMissing   bool
+	Finalizer       bool
+	Kind            string
+	OwnerReference  ownerreference.OwnerReference
+	PatchStrategy   string
+	Repair          bool
+	ServerSideApply bool
+}
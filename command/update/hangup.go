@@ -0,0 +1,108 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"github.com/spf13/pflag"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/endpointupdater"
+)
+
+// followHangups reconciles the endpoint IP maintained by the given endpoint
+// updater whenever the process receives SIGHUP, until the given context is
+// cancelled. This lets operators force a refresh without restarting the pod.
+func (c *Command) followHangups(ctx context.Context, newEndpointUpdater *endpointupdater.EndpointUpdater) {
+	for {
+		var s os.Signal
+		select {
+		case <-ctx.Done():
+			return
+		case s = <-c.hangups:
+		}
+
+		_ = c.logger.Log("info", "received signal, reconciling endpoint IP", "signal", s.String())
+
+		if f.Config != "" {
+			err := c.reload(newEndpointUpdater)
+			if err != nil {
+				_ = c.logger.Log("warning", fmt.Sprintf("reloading config file failed, keeping the previous configuration: %#v", microerror.Mask(err)), "file", f.Config)
+			} else {
+				_ = c.logger.Log("info", "reloaded config file", "file", f.Config)
+			}
+		}
+
+		err := newEndpointUpdater.Reconcile(ctx)
+		if err != nil {
+			c.healthServer.ReportFailure()
+			_ = c.logger.Log("warning", fmt.Sprintf("reconciling endpoint IP failed: %#v", microerror.Mask(err)))
+			continue
+		}
+		c.healthServer.ReportSuccess()
+	}
+}
+
+// reload applies the config file again and hands a provider created from the
+// reloaded flags to the given endpoint updater. The flags are reloaded into a
+// copy, so that the flags read by other goroutines are never written. Settings
+// other than the provider ones only take effect once the process got
+// restarted.
+func (c *Command) reload(newEndpointUpdater *endpointupdater.EndpointUpdater) error {
+	reloaded, err := c.reloadFlags()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	newProvider, err := c.newProvider(reloaded)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	newEndpointUpdater.SetProvider(newProvider)
+
+	return nil
+}
+
+// reloadFlags returns new flags parsed from the flags given on the command
+// line and the config file.
+func (c *Command) reloadFlags() (*flag.Flag, error) {
+	reloaded := &flag.Flag{}
+
+	flags := pflag.NewFlagSet(c.cobraCommand.Name(), pflag.ContinueOnError)
+	addFlags(flags, reloaded)
+
+	var err error
+	c.cobraCommand.Flags().VisitAll(func(given *pflag.Flag) {
+		if err != nil || !c.explicitFlags[given.Name] {
+			return
+		}
+
+		if s, ok := given.Value.(pflag.SliceValue); ok {
+			err = flags.Lookup(given.Name).Value.(pflag.SliceValue).Replace(s.GetSlice())
+		} else {
+			err = flags.Set(given.Name, given.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = flag.ReloadFile(reloaded.Config, flags, c.explicitFlags)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(reloaded.Kubernetes.Cluster.Namespaces) != 0 {
+		reloaded.Kubernetes.Cluster.Namespace = reloaded.Kubernetes.Cluster.Namespaces[0]
+	}
+
+	err = reloaded.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return reloaded, nil
+}
@@ -0,0 +1,90 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// runLeaderElection blocks and calls run once the lease of the configured
+// coordination.k8s.io Lease is acquired, so that only a single replica writes
+// endpoints. The context given to run is cancelled once the lease is lost.
+// Losing the lease results in a leaseLostError, so that the process terminates
+// and is restarted as standby, unless the given context got cancelled.
+// Waiting for the lease stops once the given context is cancelled.
+func (c *Command) runLeaderElection(ctx context.Context, run func(ctx context.Context)) error {
+	k8sClient, err := k8s.NewClient(k8s.Config{Logger: c.logger, Flag: f.Kubernetes})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	name := f.LeaderElection.Name
+	if name == "" {
+		name = f.Kubernetes.Cluster.Services[0] + "-" + updater.ManagedBy
+	}
+	namespace := f.LeaderElection.Namespace
+	if namespace == "" {
+		namespace = f.Kubernetes.Cluster.Namespace
+	}
+	identity := f.Kubernetes.Pod.Name
+	if identity == "" {
+		identity, err = os.Hostname()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Client: k8sClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: f.LeaderElection.LeaseDuration,
+		RenewDeadline: f.LeaderElection.RenewDeadline,
+		RetryPeriod:   f.LeaderElection.RetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				_ = c.logger.Log("info", "acquired leader lease", "lease", fmt.Sprintf("%s/%s", namespace, name), "identity", identity)
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				_ = c.logger.Log("info", "stopped leading", "lease", fmt.Sprintf("%s/%s", namespace, name), "identity", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					_ = c.logger.Log("info", "waiting as standby", "leader", leader)
+				}
+			},
+		},
+		Name: name,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = c.logger.Log("debug", "waiting for leader lease", "lease", fmt.Sprintf("%s/%s", namespace, name), "identity", identity)
+
+	elector.Run(ctx)
+
+	if ctx.Err() == nil {
+		return microerror.Maskf(leaseLostError, "lease %s/%s", namespace, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package update
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/endpointupdater"
+	"github.com/giantswarm/k8s-endpoint-updater/service/telemetry"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// observer reports the progress of the endpoint updater as interactive
+// output, telemetry counters and JSON summary results.
+type observer struct {
+	command  *Command
+	reporter *telemetry.Reporter
+	updater  *updater.Updater
+}
+
+func (o *observer) LookupDone(ips []net.IP, err error) {
+	if err != nil {
+		o.reporter.Inc("lookup_failure")
+		return
+	}
+	o.reporter.Inc("lookup_success")
+
+	p := o.command.printer
+
+	p.Done(updater.JoinIPs(ips))
+
+	if f.Provider.Conntrack.Confirm {
+		p.Start("verify")
+		p.Done("confirmed via conntrack table")
+	} else {
+		p.Skip("verify", "no verification configured")
+	}
+
+	p.Start("register")
+}
+
+func (o *observer) PublishDone(ips []net.IP, err error) {
+	if err != nil {
+		o.reporter.Inc("update_failure")
+		return
+	}
+	o.reporter.Inc("update_success")
+
+	p := o.command.printer
+
+	if f.Updater.Kind == updater.KindAnnotation {
+		p.Done(fmt.Sprintf("annotated pod %s", f.Kubernetes.Pod.Name))
+	} else {
+		p.Done(fmt.Sprintf("updated %s of services %s", f.Updater.Kind, serviceNames(targets())))
+	}
+	for _, t := range targets() {
+		p.AddRow(t.Namespace, t.Service, f.Kubernetes.Pod.Name, updater.JoinIPs(ips))
+	}
+}
+
+func (o *observer) ResultDone(result endpointupdater.Result) {
+	o.command.addResult(o.updater, result)
+}
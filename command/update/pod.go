@@ -0,0 +1,99 @@
+package update
+
+import (
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+// newPodInfo describes the KVM pod backing the published endpoint IPs, so
+// that published addresses can reference it. It is nil in case no pod name
+// is configured. In case the updater is not allowed to read the pod, the pod
+// identity exposed via the downward API is used instead, as long as it
+// includes the UID.
+func (c *Command) newPodInfo(k8sClient kubernetes.Interface) (*provider.PodInfo, error) {
+	if f.Kubernetes.Pod.Name == "" {
+		return nil, nil
+	}
+
+	pod, err := k8sClient.CoreV1().Pods(podNamespace()).Get(f.Kubernetes.Pod.Name, metav1.GetOptions{})
+	if errors.IsForbidden(err) && f.Kubernetes.Pod.UID != "" {
+		_ = c.logger.Log("debug", "not allowed to read KVM pod, using downward API pod identity", "pod", f.Kubernetes.Pod.Name)
+
+		podInfo := &provider.PodInfo{
+			Name:      f.Kubernetes.Pod.Name,
+			Namespace: podNamespace(),
+			NodeName:  f.Kubernetes.Pod.NodeName,
+			UID:       f.Kubernetes.Pod.UID,
+		}
+
+		return podInfo, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	podInfo := &provider.PodInfo{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		NodeName:  pod.Spec.NodeName,
+		UID:       string(pod.UID),
+	}
+
+	return podInfo, nil
+}
+
+// podNamespace returns the namespace of the KVM pod. It defaults to the guest
+// cluster namespace, where the KVM pod traditionally lives.
+func podNamespace() string {
+	if f.Kubernetes.Pod.Namespace != "" {
+		return f.Kubernetes.Pod.Namespace
+	}
+
+	return f.Kubernetes.Cluster.Namespace
+}
+
+// owner identifies this updater instance for ownership tracking of published
+// IPs. The pod UID is preferred since it is unique even across pod restarts.
+func (c *Command) owner(podInfo *provider.PodInfo) (string, error) {
+	if podInfo != nil && podInfo.UID != "" {
+		return podInfo.UID, nil
+	}
+	if f.Kubernetes.Pod.Name != "" {
+		return f.Kubernetes.Pod.Name, nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return hostname, nil
+}
+
+// newOwnerReference returns the owner reference of created Endpoints and
+// EndpointSlices. The KVM pod is the owner unless another owner is configured.
+// The flag validation ensures the pod is known in this case.
+func (c *Command) newOwnerReference(podInfo *provider.PodInfo) *metav1.OwnerReference {
+	o := f.Updater.OwnerReference
+	if o.Name != "" {
+		return &metav1.OwnerReference{
+			APIVersion: o.APIVersion,
+			Kind:       o.Kind,
+			Name:       o.Name,
+			UID:        types.UID(o.UID),
+		}
+	}
+
+	return &metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       podInfo.Name,
+		UID:        types.UID(podInfo.UID),
+	}
+}
@@ -0,0 +1,60 @@
+package update
+
+import (
+	"context"
+
+	"github.com/giantswarm/microerror"
+
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/conntrack"
+)
+
+// newProvider creates the provider configured by the given flags. It is closed on
+// shutdown, since watches started by the endpoint updater keep using it even
+// after it got replaced on reload.
+func (c *Command) newProvider(f *flag.Flag) (provider.Provider, error) {
+	newProvider, err := cmdprovider.New(c.providerConfig(f))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c.providersMutex.Lock()
+	c.providers = append(c.providers, newProvider)
+	c.providersMutex.Unlock()
+
+	return newProvider, nil
+}
+
+// closeProviders closes all providers created so far.
+func (c *Command) closeProviders(ctx context.Context) error {
+	c.providersMutex.Lock()
+	defer c.providersMutex.Unlock()
+
+	err := provider.Close(c.providers...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	c.providers = nil
+
+	return nil
+}
+
+func (c *Command) newConntrackProvider() (*conntrack.Provider, error) {
+	conntrackProvider, err := cmdprovider.NewConntrack(c.providerConfig(f))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return conntrackProvider, nil
+}
+
+func (c *Command) providerConfig(f *flag.Flag) cmdprovider.Config {
+	return cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.Kubernetes.Pod.Name,
+	}
+}
@@ -0,0 +1,54 @@
+package update
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/endpointupdater"
+	"github.com/giantswarm/k8s-endpoint-updater/service/output"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// addResult records the outcome of updating the given service for the JSON
+// summary. Nothing is recorded unless the JSON output format is used.
+func (c *Command) addResult(newUpdater *updater.Updater, r endpointupdater.Result) {
+	if c.jsonPrinter == nil {
+		return
+	}
+
+	t := r.Target
+	result := output.Result{
+		Namespace:        t.Namespace,
+		Service:          t.Service,
+		Added:            ipStrings(r.Added),
+		Removed:          ipStrings(r.Removed),
+		ResourceVersions: newUpdater.ResourceVersions(t.Namespace, t.Service),
+		Duration:         time.Since(r.Start).Round(time.Millisecond).String(),
+	}
+	if r.Err != nil {
+		result.Error = r.Err.Error()
+	}
+
+	c.jsonPrinter.AddResult(result)
+}
+
+// printSummary prints the JSON summary including the given error terminating
+// the run, if any.
+func (c *Command) printSummary(runErr error) {
+	err := c.jsonPrinter.Print(runErr)
+	if err != nil {
+		_ = c.logger.Log("warning", fmt.Sprintf("printing JSON summary failed: %#v", microerror.Mask(err)))
+	}
+}
+
+func ipStrings(ips []net.IP) []string {
+	var s []string
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+
+	return s
+}
@@ -0,0 +1,153 @@
+package update
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	"github.com/giantswarm/k8s-endpoint-updater/service/telemetry"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// updateSpec is a single update read from the spec input, given as one JSON
+// object per line, e.g.
+//
+//	{"namespace": "abc12", "service": "master", "ips": ["10.0.0.1"]}
+//
+// The namespace defaults to the namespace flag.
+type updateSpec struct {
+	IPs       []string `json:"ips"`
+	Namespace string   `json:"namespace"`
+	Service   string   `json:"service"`
+}
+
+// runSpecs publishes the endpoint IPs given by the update specs read from the
+// configured file, or stdin in case it is "-". No provider is involved. All
+// specs are processed, even if some of them fail, unless the given context is
+// cancelled.
+func (c *Command) runSpecs(ctx context.Context, reporter *telemetry.Reporter) error {
+	var r io.Reader
+	if f.Filename == "-" {
+		r = os.Stdin
+	} else {
+		file, err := os.Open(f.Filename)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	k8sClient, err := k8s.NewClient(k8s.Config{Logger: c.logger, Flag: f.Kubernetes})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	newUpdater, err := c.newUpdater(k8sClient)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	baseCluster := f.Kubernetes.Cluster
+	baseLogger := c.logger
+	defer func() {
+		f.Kubernetes.Cluster = baseCluster
+		c.logger = baseLogger
+	}()
+
+	var line, total, failed int
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return microerror.Maskf(cancelledError, "processing update specs")
+		}
+
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		total++
+
+		f.Kubernetes.Cluster = baseCluster
+		c.logger = baseLogger
+
+		ips, err := c.runSpec(ctx, scanner.Bytes(), k8sClient, newUpdater, reporter)
+		if err != nil {
+			failed++
+			_ = c.logger.Log("warning", fmt.Sprintf("publishing endpoint IP for update spec on line %d failed: %#v", line, microerror.Mask(err)))
+			continue
+		}
+
+		for _, t := range targets() {
+			c.printer.AddRow(t.Namespace, t.Service, f.Kubernetes.Pod.Name, updater.JoinIPs(ips))
+		}
+	}
+	if scanner.Err() != nil {
+		return microerror.Mask(scanner.Err())
+	}
+
+	if failed != 0 {
+		return microerror.Maskf(executionFailedError, "publishing endpoint IP failed for %d of %d update specs", failed, total)
+	}
+
+	return nil
+}
+
+func (c *Command) runSpec(ctx context.Context, b []byte, k8sClient kubernetes.Interface, newUpdater *updater.Updater, reporter *telemetry.Reporter) ([]net.IP, error) {
+	var spec updateSpec
+	err := json.Unmarshal(b, &spec)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "invalid update spec: %s", err.Error())
+	}
+
+	if spec.Service == "" {
+		return nil, microerror.Maskf(invalidConfigError, "service must not be empty")
+	}
+	if len(spec.IPs) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "ips must not be empty")
+	}
+
+	var ips []net.IP
+	for _, s := range spec.IPs {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, microerror.Maskf(invalidConfigError, "invalid IP %#q", s)
+		}
+		ips = append(ips, ip)
+	}
+
+	if spec.Namespace != "" {
+		f.Kubernetes.Cluster.Namespaces = []string{spec.Namespace}
+	}
+	f.Kubernetes.Cluster.Services = []string{spec.Service}
+
+	err = f.Validate()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	c.logger = c.logger.With(logFields()...)
+
+	// No provider is involved, since the endpoint IPs are given by the spec.
+	newEndpointUpdater, err := c.newEndpointUpdater(k8sClient, nil, newUpdater, nil, reporter)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	err = newEndpointUpdater.Publish(ctx, ips)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,32 @@
+package update
+
+import (
+	"os"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/systemd"
+)
+
+// newNotifier creates the systemd notifier from the environment systemd passes
+// to Type=notify units.
+func (c *Command) newNotifier() (*systemd.Notifier, error) {
+	watchdogTimeout, err := systemd.WatchdogTimeout()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	notifierConfig := systemd.DefaultConfig()
+
+	notifierConfig.Logger = c.logger
+
+	notifierConfig.Socket = os.Getenv(systemd.SocketEnv)
+	notifierConfig.WatchdogTimeout = watchdogTimeout
+
+	newNotifier, err := systemd.New(notifierConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newNotifier, nil
+}
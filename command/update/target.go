@@ -0,0 +1,81 @@
+package update
+
+import (
+	"strings"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/endpointupdater"
+)
+
+// targets returns the services the endpoint IPs are published for. Services
+// given as namespace/service are only published in their namespace, all
+// others in each of the configured namespaces.
+func targets() []endpointupdater.Target {
+	namespaces := f.Kubernetes.Cluster.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{f.Kubernetes.Cluster.Namespace}
+	}
+
+	var ts []endpointupdater.Target
+	add := func(t endpointupdater.Target) {
+		for _, e := range ts {
+			if e == t {
+				return
+			}
+		}
+		ts = append(ts, t)
+	}
+
+	for _, s := range f.Kubernetes.Cluster.Services {
+		if i := strings.Index(s, "/"); i >= 0 {
+			add(endpointupdater.Target{Namespace: s[:i], Service: s[i+1:]})
+			continue
+		}
+
+		for _, n := range namespaces {
+			add(endpointupdater.Target{Namespace: n, Service: s})
+		}
+	}
+
+	return ts
+}
+
+// logFields returns the structured fields identifying the guest cluster, which
+// are attached to all log lines. Update specs are published without provider,
+// so the provider kind is omitted in this case.
+func logFields() []interface{} {
+	var namespaces, services []string
+	for _, t := range targets() {
+		namespaces = appendUnique(namespaces, t.Namespace)
+		services = appendUnique(services, t.Service)
+	}
+
+	fields := []interface{}{
+		"namespace", strings.Join(namespaces, ","),
+		"service", strings.Join(services, ","),
+	}
+	if f.Filename == "" {
+		fields = append(fields, "provider", f.Provider.Kind)
+	}
+
+	return fields
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
+
+// serviceNames returns the names of the given targets, separated by commas.
+func serviceNames(ts []endpointupdater.Target) string {
+	var names []string
+	for _, t := range ts {
+		names = append(names, t.String())
+	}
+
+	return strings.Join(names, ", ")
+}
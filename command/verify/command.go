@@ -0,0 +1,295 @@
+// Package verify implements the verify command for the command line tool.
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	"github.com/giantswarm/k8s-endpoint-updater/command/k8s"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/command/verify/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+const (
+	// ExitCodeDrift is the exit code of the process in case the published
+	// endpoint IPs do not match the desired ones.
+	ExitCodeDrift = 1
+	// ExitCodeFailure is the exit code of the process in case the
+	// verification itself failed.
+	ExitCodeFailure = 2
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new verify command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new verify
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured verify command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the published endpoint IPs match the provider lookup.",
+		Long:  "Verify the published endpoint IPs match the provider lookup. Meant to run as CronJob or CI check. Looked up IPs missing in the Endpoints or EndpointSlices and IPs claimed by us which are not looked up anymore are printed as diff. Exits with 1 on drift and with 2 in case the verification failed.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kind, "updater.kind", updater.KindEndpoints, "Resources the endpoint IPs are verified against. One of endpoints, endpointslice or both.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Namespace, "namespace", "default", "Namespace of the services and the KVM pod.")
+	newCommand.cobraCommand.PersistentFlags().StringSliceVar(&f.Services, "service", nil, "Name of the service to verify. Can be repeated or comma separated.")
+
+	k8s.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Kubernetes)
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.Kubernetes.Pod.Name, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod. Only IPs claimed by this pod are considered stale when given. Defaults to the value of POD_NAME environment variable.")
+
+	cmdprovider.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Provider)
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Maskf(verificationFailedError, "%s", err.Error())
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	drift, err := c.execute(ctx, os.Stdout)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Maskf(verificationFailedError, "%s", err.Error())
+	}
+
+	if drift {
+		return microerror.Maskf(driftDetectedError, "published endpoint IPs do not match the desired ones")
+	}
+
+	return nil
+}
+
+// execute prints the differences between the desired and the published
+// endpoint IPs of all services and returns whether there are any.
+func (c *Command) execute(ctx context.Context, w io.Writer) (bool, error) {
+	var err error
+
+	var k8sClient kubernetes.Interface
+	{
+		c := k8s.Config{
+			Logger: c.logger,
+
+			Flag: f.Kubernetes,
+		}
+
+		k8sClient, err = k8s.NewClient(c)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	var newUpdater *updater.Updater
+	{
+		updaterConfig := updater.DefaultConfig()
+
+		updaterConfig.K8sClient = k8sClient
+		updaterConfig.Logger = c.logger
+
+		updaterConfig.Kind = f.Kind
+
+		newUpdater, err = updater.New(updaterConfig)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+	}
+
+	// The pod is identified the same way the update command does, so that
+	// we know which of the published IPs it claims.
+	var owner string
+	if f.Kubernetes.Pod.Name != "" {
+		pod, err := k8sClient.CoreV1().Pods(f.Namespace).Get(f.Kubernetes.Pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		owner = string(pod.UID)
+	}
+
+	desired, err := c.lookup(ctx)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	var drift bool
+	for _, service := range f.Services {
+		statuses, err := newUpdater.Status(f.Namespace, service)
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+
+		for _, resource := range resources(f.Kind) {
+			missing, stale := diff(desired, filterResource(statuses, resource), owner)
+			if len(missing) == 0 && len(stale) == 0 {
+				continue
+			}
+			drift = true
+
+			fmt.Fprintf(w, "--- desired %s/%s\n", f.Namespace, service)
+			fmt.Fprintf(w, "+++ %s %s/%s\n", resource, f.Namespace, service)
+			for _, ip := range missing {
+				fmt.Fprintf(w, "-%s\n", ip)
+			}
+			for _, ip := range stale {
+				fmt.Fprintf(w, "+%s\n", ip)
+			}
+		}
+	}
+
+	if !drift {
+		_ = c.logger.Log("info", fmt.Sprintf("published endpoint IPs of services '%s' match", strings.Join(f.Services, ", ")), "ips", updater.JoinIPs(desired))
+	}
+
+	return drift, nil
+}
+
+// lookup resolves the desired endpoint IPs using the configured provider.
+func (c *Command) lookup(ctx context.Context) ([]net.IP, error) {
+	providerConfig := cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.Kubernetes.Pod.Name,
+	}
+
+	newProvider, err := cmdprovider.New(providerConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ips, err := cmdprovider.LookupAll(ctx, newProvider)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ips, nil
+}
+
+// diff returns the desired IPs which are not published, and the published IPs
+// which are claimed by the given owner, or any owner when empty, but are not
+// desired anymore.
+func diff(desired []net.IP, statuses []updater.AddressStatus, owner string) ([]string, []string) {
+	published := map[string]bool{}
+	for _, s := range statuses {
+		published[s.IP] = true
+	}
+
+	var missing []string
+	wanted := map[string]bool{}
+	for _, ip := range desired {
+		wanted[ip.String()] = true
+		if !published[ip.String()] {
+			missing = append(missing, ip.String())
+		}
+	}
+
+	var stale []string
+	for _, s := range statuses {
+		if wanted[s.IP] || !claimedBy(s, owner) {
+			continue
+		}
+		stale = append(stale, s.IP)
+	}
+
+	sort.Strings(missing)
+	sort.Strings(stale)
+
+	return missing, stale
+}
+
+func claimedBy(s updater.AddressStatus, owner string) bool {
+	if owner == "" {
+		return len(s.Owners) != 0
+	}
+
+	for _, o := range s.Owners {
+		if o == owner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterResource returns the statuses of the given resource. The
+// EndpointSlices of both families are verified together.
+func filterResource(statuses []updater.AddressStatus, resource string) []updater.AddressStatus {
+	var filtered []updater.AddressStatus
+	for _, s := range statuses {
+		if strings.HasPrefix(s.Resource, resource+"/") {
+			filtered = append(filtered, s)
+		}
+	}
+
+	return filtered
+}
+
+func resources(kind string) []string {
+	switch kind {
+	case updater.KindBoth:
+		return []string{"endpoints", "endpointslice"}
+	default:
+		return []string{kind}
+	}
+}
@@ -0,0 +1,24 @@
+package verify
+
+import "github.com/giantswarm/microerror"
+
+var driftDetectedError = microerror.New("drift detected")
+
+// IsDriftDetected asserts driftDetectedError.
+func IsDriftDetected(err error) bool {
+	return microerror.Cause(err) == driftDetectedError
+}
+
+var invalidConfigError = microerror.New("invalid config")
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var verificationFailedError = microerror.New("verification failed")
+
+// IsVerificationFailed asserts verificationFailedError.
+func IsVerificationFailed(err error) bool {
+	return microerror.Cause(err) == verificationFailedError
+}
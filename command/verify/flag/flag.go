@@ -0,0 +1,38 @@
+package flag
+
+import (
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/kubernetes"
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+)
+
+type Flag struct {
+	Kind       string
+	Kubernetes kubernetes.Kubernetes
+	Namespace  string
+	Provider   provider.Provider
+	Services   []string
+}
+
+func (f *Flag) Validate() error {
+	if f.Namespace == "" {
+		return microerror.Maskf(invalidFlagsError, "namespace must not be empty")
+	}
+	if len(f.Services) == 0 {
+		return microerror.Maskf(invalidFlagsError, "service must not be empty")
+	}
+	for _, s := range f.Services {
+		if s == "" {
+			return microerror.Maskf(invalidFlagsError, "service must not be empty")
+		}
+	}
+
+	switch f.Kind {
+	case "both", "endpoints", "endpointslice":
+	default:
+		return microerror.Maskf(invalidFlagsError, "updater kind must be one of both, endpoints or endpointslice")
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package watch implements the watch command for the command line tool.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/downward"
+	cmdprovider "github.com/giantswarm/k8s-endpoint-updater/command/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/command/shutdown"
+	"github.com/giantswarm/k8s-endpoint-updater/command/watch/flag"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+var (
+	f = &flag.Flag{}
+)
+
+// Config represents the configuration used to create a new watch command.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+}
+
+// DefaultConfig provides a default configuration to create a new watch
+// command by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+	}
+}
+
+// New creates a new configured watch command.
+func New(config Config) (*Command, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "logger must not be empty")
+	}
+
+	newCommand := &Command{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		cobraCommand: nil,
+	}
+
+	newCommand.cobraCommand = &cobra.Command{
+		Use:   "watch",
+		Short: "Continuously run the provider lookup and print changed endpoint IPs.",
+		Long:  "Continuously run the provider lookup and print changed endpoint IPs as they happen, without touching Kubernetes. Meant to debug the provider configuration before enabling actual updates.",
+		RunE:  newCommand.Execute,
+	}
+
+	newCommand.cobraCommand.PersistentFlags().DurationVar(&f.Interval, "watch.interval", 5*time.Second, "Interval in which the provider lookup is re-run.")
+	newCommand.cobraCommand.PersistentFlags().StringVar(&f.PodName, "service.kubernetes.pod.name", os.Getenv(downward.PodNameEnv), "Name of the guest cluster kvm Kubernetes pod, which the etcd provider looks up. Defaults to the value of POD_NAME environment variable.")
+
+	cmdprovider.AddFlags(newCommand.cobraCommand.PersistentFlags(), &f.Provider)
+
+	return newCommand, nil
+}
+
+type Command struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	cobraCommand *cobra.Command
+}
+
+func (c *Command) CobraCommand() *cobra.Command {
+	return c.cobraCommand
+}
+
+func (c *Command) Execute(cmd *cobra.Command, args []string) error {
+	err := f.Validate()
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	ctx, cancel := shutdown.Context(c.logger)
+	defer cancel()
+
+	err = c.execute(ctx, os.Stdout)
+	if err != nil {
+		_ = c.logger.Log("error", fmt.Sprintf("%#v", microerror.Mask(err)))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// execute runs the provider lookup until the given context is cancelled and
+// prints a line whenever its result changes, including transitions between
+// failing and succeeding.
+func (c *Command) execute(ctx context.Context, w io.Writer) error {
+	providerConfig := cmdprovider.Config{
+		Logger: c.logger,
+
+		Flag:    f.Provider,
+		PodName: f.PodName,
+	}
+
+	newProvider, err := cmdprovider.New(providerConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// Providers able to watch their source notify about changed IPs, which
+	// are then looked up immediately instead of waiting for the next tick.
+	var changes <-chan []provider.PodInfo
+	if watchingProvider, ok := newProvider.(provider.WatchingProvider); ok && cmdprovider.Watch(f.Provider) {
+		changes = watchingProvider.Watch(ctx)
+	}
+
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		var current string
+		ips, err := cmdprovider.LookupAll(ctx, newProvider)
+		if ctx.Err() != nil {
+			return nil
+		} else if err != nil {
+			current = fmt.Sprintf("lookup failed: %s", err.Error())
+		} else {
+			current = updater.JoinIPs(ips)
+		}
+
+		if current != last {
+			fmt.Fprintf(w, "%s %s\n", time.Now().UTC().Format(time.RFC3339), current)
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+			}
+		}
+	}
+}
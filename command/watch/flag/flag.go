@@ -0,0 +1,26 @@
+package flag
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/command/update/flag/provider"
+)
+
+type Flag struct {
+	Interval time.Duration
+	PodName  string
+	Provider provider.Provider
+}
+
+func (f *Flag) Validate() error {
+	if f.Interval <= 0 {
+		return microerror.Maskf(invalidFlagsError, "interval must be greater than zero")
+	}
+	if f.Provider.Kind == "" {
+		return microerror.Maskf(invalidFlagsError, "provider kind must not be empty")
+	}
+
+	return nil
+}
@@ -3,9 +3,8 @@ package main
 import (
 	"os"
 
-	"github.com/giantswarm/micrologger"
-
 	"github.com/giantswarm/k8s-endpoint-updater/command"
+	"github.com/giantswarm/k8s-endpoint-updater/service/logging"
 )
 
 var (
@@ -18,13 +17,15 @@ var (
 func main() {
 	var err error
 
-	// Create a new logger which is used by all packages.
-	var newLogger micrologger.Logger
+	// Create a new logger which is used by all packages. Its level is applied
+	// by the root command once the flags got parsed.
+	var newLogger *logging.Logger
 	{
-		loggerConfig := micrologger.Config{
-			IOWriter: os.Stdout,
-		}
-		newLogger, err = micrologger.New(loggerConfig)
+		loggerConfig := logging.DefaultConfig()
+
+		loggerConfig.IOWriter = os.Stdout
+
+		newLogger, err = logging.New(loggerConfig)
 		if err != nil {
 			panic(err)
 		}
@@ -47,8 +48,10 @@ func main() {
 		}
 	}
 
+	// The commands return their errors instead of terminating the process
+	// themselves, so that deferred cleanup always runs.
 	err = newCommand.CobraCommand().Execute()
 	if err != nil {
-		panic(err)
+		os.Exit(command.ExitCode(err))
 	}
 }
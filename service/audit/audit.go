@@ -0,0 +1,151 @@
+// Package audit implements an append-only audit log of the mutations of
+// Endpoints and EndpointSlices. Every mutation is written as a single JSON
+// line, giving operators a forensic trail when guest cluster connectivity
+// incidents are investigated.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	OperationApply  = "apply"
+	OperationCreate = "create"
+	OperationDelete = "delete"
+	OperationPatch  = "patch"
+	OperationUpdate = "update"
+)
+
+// Entry describes a single mutation of an Endpoints object or EndpointSlice.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Resource  string    `json:"resource"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	// Previous are the addresses before the mutation. They are empty when the
+	// object got created.
+	Previous Addresses `json:"previous"`
+	// Current are the addresses written. They are empty when the object got
+	// deleted.
+	Current  Addresses `json:"current"`
+	Response Response  `json:"response"`
+}
+
+// Addresses is the address set of an Endpoints object or EndpointSlice.
+type Addresses struct {
+	Ready    []string `json:"ready"`
+	NotReady []string `json:"notReady"`
+}
+
+// Response describes how the API server responded to a mutation.
+type Response struct {
+	// Code is the HTTP status code of failed requests.
+	Code            int32  `json:"code,omitempty"`
+	Error           string `json:"error,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+// Config represents the configuration used to create a new audit log.
+type Config struct {
+	// Settings.
+
+	// Path is the file the entries are appended to. It is created in case it
+	// does not exist.
+	Path string
+}
+
+// DefaultConfig provides a default configuration to create a new audit log by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Path: "",
+	}
+}
+
+// New creates a new audit log and opens its file.
+func New(config Config) (*Log, error) {
+	// Settings.
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Path must not be empty")
+	}
+
+	file, err := os.OpenFile(config.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newLog := &Log{
+		// Internals.
+		file:  file,
+		mutex: sync.Mutex{},
+	}
+
+	return newLog, nil
+}
+
+// Log appends entries to the audit file. All methods are no-ops on a nil log,
+// so that auditing is optional for its users.
+type Log struct {
+	// Internals.
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// Close closes the audit file. Entries recorded afterwards fail.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.file.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Record appends the given entry to the audit file. Each entry is written with
+// a single write call, so that the lines of concurrent writers do not
+// interleave.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	// Empty address sets are written as empty arrays instead of null.
+	for _, a := range []*Addresses{&entry.Previous, &entry.Current} {
+		if a.Ready == nil {
+			a.Ready = []string{}
+		}
+		if a.NotReady == nil {
+			a.NotReady = []string{}
+		}
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, err = l.file.Write(append(b, '\n'))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+// Package cleaner implements the cluster-wide cleanup of orphaned endpoint
+// IPs. Endpoints and EndpointSlices record which updater instance added which
+// IPs in their ownership annotation. Updater instances are identified by the
+// UID of their pod. IPs claimed by pods which do not exist anymore, e.g.
+// because the updater crashed before withdrawing them, are orphaned and get
+// removed.
+package cleaner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// uidRegexp matches pod UIDs. Owners not being pod UIDs, e.g. the hostname of
+// updaters running outside of Kubernetes, are never considered orphaned since
+// we cannot tell whether they are still alive.
+var uidRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Config represents the configuration used to create a new cleaner.
+type Config struct {
+	// Dependencies.
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+	Updater   *updater.Updater
+
+	// Settings.
+
+	// DryRun only reports orphaned endpoint IPs without removing them.
+	DryRun bool
+}
+
+// DefaultConfig provides a default configuration to create a new cleaner by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		K8sClient: nil,
+		Logger:    nil,
+		Updater:   nil,
+
+		// Settings.
+		DryRun: false,
+	}
+}
+
+// New creates a new cleaner.
+func New(config Config) (*Cleaner, error) {
+	// Dependencies.
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if config.Updater == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Updater must not be empty")
+	}
+
+	newCleaner := &Cleaner{
+		// Dependencies.
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		updater:   config.Updater,
+
+		// Settings.
+		dryRun: config.DryRun,
+	}
+
+	return newCleaner, nil
+}
+
+type Cleaner struct {
+	// Dependencies.
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+	updater   *updater.Updater
+
+	// Settings.
+	dryRun bool
+}
+
+// Orphan describes the endpoint IPs a deleted pod still claims for a service.
+type Orphan struct {
+	Namespace string
+	Service   string
+	Owner     string
+	IPs       []string
+}
+
+// Cleanup scans all Endpoints and EndpointSlices carrying the ownership
+// annotation in the given namespace, or all namespaces when empty, and
+// removes the IPs claimed by pods which do not exist anymore. The orphaned
+// IPs are returned, including those which would have been removed in dry-run
+// mode. Cleaning up stops once the given context is cancelled.
+func (c *Cleaner) Cleanup(ctx context.Context, namespace string) ([]Orphan, error) {
+	// The claims are read before the pods are listed. An updater pod starting
+	// in between then either claimed its IPs after they were read or shows
+	// up in the pod list, so that its IPs are never taken for orphans.
+	claims, err := c.claims(namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The updater pods might live in other namespaces than the services
+	// they publish for, so all pods are considered.
+	pods, err := c.k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	alive := map[string]bool{}
+	for _, pod := range pods.Items {
+		alive[string(pod.UID)] = true
+	}
+
+	var keys []string
+	for k := range claims {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var orphans []Orphan
+	for _, k := range keys {
+		if ctx.Err() != nil {
+			return nil, microerror.Mask(ctx.Err())
+		}
+
+		i := strings.Index(k, "/")
+		ns, service := k[:i], k[i+1:]
+
+		var owners []string
+		for owner := range claims[k] {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+
+		for _, owner := range owners {
+			if alive[owner] || !uidRegexp.MatchString(owner) {
+				continue
+			}
+
+			ips := claims[k][owner]
+			_ = c.logger.Log("info", fmt.Sprintf("found orphaned endpoint IPs of service '%s/%s'", ns, service), "owner", owner, "ips", strings.Join(ips, ","), "dryRun", c.dryRun)
+
+			if !c.dryRun {
+				_, err := c.updater.DeleteOwned(ctx, ns, service, owner)
+				if err != nil {
+					_ = c.logger.Log("warning", fmt.Sprintf("removing orphaned endpoint IPs of service '%s/%s' failed: %#v", ns, service, microerror.Mask(err)))
+					continue
+				}
+			}
+
+			orphans = append(orphans, Orphan{Namespace: ns, Service: service, Owner: owner, IPs: ips})
+		}
+	}
+
+	return orphans, nil
+}
+
+// claims returns the IPs claimed per owner for all services in the given
+// namespace, keyed by namespace/service. The claims on the Endpoints and the
+// EndpointSlices of a service are merged.
+func (c *Cleaner) claims(namespace string) (map[string]map[string][]string, error) {
+	claims := map[string]map[string][]string{}
+
+	add := func(meta metav1.Object, service string) error {
+		o, err := updater.Owners(meta)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		k := meta.GetNamespace() + "/" + service
+		for owner, ips := range o {
+			if claims[k] == nil {
+				claims[k] = map[string][]string{}
+			}
+			for _, ip := range ips {
+				if !containsString(claims[k][owner], ip) {
+					claims[k][owner] = append(claims[k][owner], ip)
+				}
+			}
+			sort.Strings(claims[k][owner])
+		}
+
+		return nil
+	}
+
+	endpoints, err := c.k8sClient.CoreV1().Endpoints(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for i := range endpoints.Items {
+		err := add(&endpoints.Items[i], endpoints.Items[i].Name)
+		if err != nil {
+			_ = c.logger.Log("warning", fmt.Sprintf("reading owners of endpoints '%s/%s' failed: %#v", endpoints.Items[i].Namespace, endpoints.Items[i].Name, microerror.Mask(err)))
+		}
+	}
+
+	slices, err := c.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).List(metav1.ListOptions{
+		LabelSelector: updater.LabelManagedBy + "=" + updater.ManagedBy,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for i := range slices.Items {
+		service := slices.Items[i].Labels[discoveryv1alpha1.LabelServiceName]
+		if service == "" {
+			continue
+		}
+
+		err := add(&slices.Items[i], service)
+		if err != nil {
+			_ = c.logger.Log("warning", fmt.Sprintf("reading owners of endpointslice '%s/%s' failed: %#v", slices.Items[i].Namespace, slices.Items[i].Name, microerror.Mask(err)))
+		}
+	}
+
+	return claims, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
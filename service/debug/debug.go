@@ -0,0 +1,147 @@
+// Package debug implements the HTTP server exposing the net/http/pprof
+// profiles, e.g. to profile CPU and heap usage of the daemon mode on busy KVM
+// hosts. The profiles reveal internals of the process, which is why the server
+// only listens on loopback addresses.
+package debug
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// shutdownTimeout is the time in-flight requests are given to complete
+	// when the server is stopped. Profiles taking longer, e.g. a CPU profile
+	// of 30 seconds, are cut off.
+	shutdownTimeout = 5 * time.Second
+)
+
+// Config represents the configuration used to create a new debug server.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Address is the address the debug server listens on, e.g.
+	// "127.0.0.1:6060". The host must be a loopback address or localhost.
+	Address string
+}
+
+// DefaultConfig provides a default configuration to create a new debug server
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Address: "",
+	}
+}
+
+// New creates a new debug server.
+func New(config Config) (*Server, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Address == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Address must not be empty")
+	}
+	err := validateAddress(config.Address)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newServer := &Server{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		server: nil,
+
+		// Settings.
+		address: config.Address,
+	}
+
+	return newServer, nil
+}
+
+type Server struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	server *http.Server
+
+	// Settings.
+	address string
+}
+
+// Boot starts the debug server in the background.
+func (s *Server) Boot() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	s.server = &http.Server{Addr: s.address, Handler: mux}
+
+	go func() {
+		_ = s.logger.Log("debug", "starting debug server", "address", s.address)
+
+		err := s.server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			_ = s.logger.Log("error", fmt.Sprintf("debug server failed: %#v", microerror.Mask(err)))
+		}
+	}()
+}
+
+// Stop shuts the debug server down gracefully, giving in-flight requests
+// shutdownTimeout to complete.
+func (s *Server) Stop() {
+	if s == nil || s.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		_ = s.logger.Log("warning", fmt.Sprintf("stopping debug server failed: %#v", microerror.Mask(err)))
+	}
+}
+
+// validateAddress returns an error unless the host of the given address is a
+// loopback address or localhost. An empty host would listen on all
+// interfaces.
+func validateAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "config.Address must be host:port, got '%s'", address)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsLoopback() {
+		return microerror.Maskf(invalidConfigError, "config.Address must listen on a loopback address, got '%s'", address)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package endpointupdater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cenkalti "github.com/cenkalti/backoff"
+	"github.com/giantswarm/backoff"
+)
+
+const (
+	// RetryStrategyConstant waits the initial retry interval between all
+	// retries.
+	RetryStrategyConstant = "constant"
+	// RetryStrategyExponential grows the wait between retries exponentially.
+	RetryStrategyExponential = "exponential"
+	// RetryStrategyImmediate does not retry at all, so that short-lived
+	// invocations like Jobs and hooks fail fast.
+	RetryStrategyImmediate = "immediate"
+)
+
+// newBackOff returns the backoff used to retry the initial lookup and
+// registration according to the configured retry strategy and settings.
+// Retrying stops once the given context is cancelled, including the wait
+// between attempts.
+func (e *EndpointUpdater) newBackOff(ctx context.Context) backoff.BackOff {
+	return cenkalti.WithContext(e.newStrategyBackOff(), ctx)
+}
+
+func (e *EndpointUpdater) newStrategyBackOff() backoff.BackOff {
+	var b backoff.BackOff
+	switch e.retryStrategy {
+	case RetryStrategyConstant:
+		if e.retryMaxElapsedTime > 0 {
+			b = backoff.NewConstant(e.retryMaxElapsedTime, e.retryInitialInterval)
+		} else {
+			b = cenkalti.NewConstantBackOff(e.retryInitialInterval)
+		}
+	case RetryStrategyImmediate:
+		b = &cenkalti.StopBackOff{}
+	default:
+		exponential := &cenkalti.ExponentialBackOff{
+			InitialInterval:     e.retryInitialInterval,
+			RandomizationFactor: cenkalti.DefaultRandomizationFactor,
+			Multiplier:          e.retryMultiplier,
+			MaxInterval:         e.retryMaxInterval,
+			MaxElapsedTime:      e.retryMaxElapsedTime,
+			Clock:               cenkalti.SystemClock,
+		}
+		exponential.Reset()
+
+		b = exponential
+	}
+
+	if e.retryMaxRetries > 0 {
+		b = cenkalti.WithMaxRetries(b, uint64(e.retryMaxRetries))
+	}
+
+	return b
+}
+
+// newRetryNotifier returns the notifier logging each failed attempt of the
+// given operation along with the number of attempts made so far and the wait
+// until the next one.
+func (e *EndpointUpdater) newRetryNotifier(operation string, attempts *int) backoff.Notify {
+	return func(err error, d time.Duration) {
+		_ = e.logger.Log("warning", fmt.Sprintf("%s failed, retrying", operation), "attempt", *attempts, "retryIn", d.String(), "reason", err.Error())
+	}
+}
+
+// deadlineBackOff stops retrying once the next attempt would not fit into the
+// deadline of the given context anymore and records that it did so, so that
+// running out of time can be told apart from the strategy giving up.
+type deadlineBackOff struct {
+	backoff.BackOff
+
+	ctx     context.Context
+	expired bool
+}
+
+func (b *deadlineBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next == cenkalti.Stop {
+		return cenkalti.Stop
+	}
+
+	if deadline, ok := b.ctx.Deadline(); ok && time.Until(deadline) < next {
+		b.expired = true
+		return cenkalti.Stop
+	}
+
+	return next
+}
@@ -0,0 +1,119 @@
+package endpointupdater
+
+import (
+	"testing"
+	"time"
+
+	cenkalti "github.com/cenkalti/backoff"
+)
+
+func Test_EndpointUpdater_newStrategyBackOff(t *testing.T) {
+	testCases := []struct {
+		name                string
+		retryStrategy       string
+		retryMaxElapsedTime time.Duration
+		retryMaxRetries     int
+		attempts            int
+		expectedMin         time.Duration
+		expectedMax         time.Duration
+		expectedStopAfter   int
+	}{
+		{
+			name:              "case 0: immediate stops right away",
+			retryStrategy:     RetryStrategyImmediate,
+			attempts:          3,
+			expectedStopAfter: 0,
+		},
+		{
+			name:              "case 1: immediate ignores max retries",
+			retryStrategy:     RetryStrategyImmediate,
+			retryMaxRetries:   5,
+			attempts:          3,
+			expectedStopAfter: 0,
+		},
+		{
+			name:              "case 2: constant waits the initial interval",
+			retryStrategy:     RetryStrategyConstant,
+			attempts:          5,
+			expectedMin:       time.Second,
+			expectedMax:       time.Second,
+			expectedStopAfter: -1,
+		},
+		{
+			name:              "case 3: constant stops after max retries",
+			retryStrategy:     RetryStrategyConstant,
+			retryMaxRetries:   3,
+			attempts:          5,
+			expectedMin:       time.Second,
+			expectedMax:       time.Second,
+			expectedStopAfter: 3,
+		},
+		{
+			name:                "case 4: constant with max elapsed time",
+			retryStrategy:       RetryStrategyConstant,
+			retryMaxElapsedTime: time.Minute,
+			attempts:            5,
+			expectedMin:         time.Second,
+			expectedMax:         time.Second,
+			expectedStopAfter:   -1,
+		},
+		{
+			name:              "case 5: exponential grows up to the max interval",
+			retryStrategy:     RetryStrategyExponential,
+			attempts:          10,
+			expectedMin:       time.Second / 2,
+			expectedMax:       4 * time.Second * 3 / 2,
+			expectedStopAfter: -1,
+		},
+		{
+			name:              "case 6: exponential stops after max retries",
+			retryStrategy:     RetryStrategyExponential,
+			retryMaxRetries:   2,
+			attempts:          5,
+			expectedMin:       time.Second / 2,
+			expectedMax:       4 * time.Second * 3 / 2,
+			expectedStopAfter: 2,
+		},
+		{
+			name:              "case 7: unknown strategies fall back to exponential",
+			retryStrategy:     "",
+			retryMaxRetries:   2,
+			attempts:          5,
+			expectedMin:       time.Second / 2,
+			expectedMax:       4 * time.Second * 3 / 2,
+			expectedStopAfter: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &EndpointUpdater{
+				retryInitialInterval: time.Second,
+				retryMaxElapsedTime:  tc.retryMaxElapsedTime,
+				retryMaxInterval:     4 * time.Second,
+				retryMaxRetries:      tc.retryMaxRetries,
+				retryMultiplier:      2,
+				retryStrategy:        tc.retryStrategy,
+			}
+
+			b := e.newStrategyBackOff()
+
+			for i := 0; i < tc.attempts; i++ {
+				next := b.NextBackOff()
+
+				if i == tc.expectedStopAfter {
+					if next != cenkalti.Stop {
+						t.Fatalf("expected stop after %d retries, got %s", i, next)
+					}
+					return
+				}
+				if next == cenkalti.Stop {
+					t.Fatalf("expected no stop after %d retries", i)
+				}
+				if next < tc.expectedMin || next > tc.expectedMax {
+					t.Fatalf("expected wait between %s and %s, got %s", tc.expectedMin, tc.expectedMax, next)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,921 @@
+// Package endpointupdater implements the lifecycle of publishing endpoint IPs,
+// so that operators are able to embed it instead of running the command line
+// tool. The endpoint IPs are looked up using the configured provider,
+// published for all targets and then maintained in the background until the
+// context given to Run is cancelled. Maintaining covers reconciling changed
+// IPs, reasserting them, promoting them once ready, repairing external
+// removals and following VRRP transitions, depending on the configuration.
+package endpointupdater
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	cenkalti "github.com/cenkalti/backoff"
+	"github.com/giantswarm/backoff"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/health"
+	"github.com/giantswarm/k8s-endpoint-updater/service/logging"
+	"github.com/giantswarm/k8s-endpoint-updater/service/probe"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/conntrack"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/vrrp"
+	"github.com/giantswarm/k8s-endpoint-updater/service/systemd"
+	"github.com/giantswarm/k8s-endpoint-updater/service/tracing"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+const (
+	// IPFamilyDual selects one endpoint IP of each family.
+	IPFamilyDual = "dual"
+)
+
+// Config represents the configuration used to create a new endpoint updater.
+type Config struct {
+	// Dependencies.
+
+	// Conntrack optionally confirms the looked up endpoint IPs against the
+	// host conntrack table.
+	Conntrack *conntrack.Provider
+	// Health optionally gets the outcome of maintaining the endpoint IPs
+	// reported.
+	Health *health.Server
+	// K8sClient is used to watch the Endpoints of the targets. It is only
+	// required when Repair is enabled.
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+	// Notifier optionally notifies systemd once the endpoint IPs are published
+	// and gets watchdog pings from the reconcile loop.
+	Notifier *systemd.Notifier
+	// Observer is optionally notified about the progress.
+	Observer Observer
+	// Prober optionally probes endpoint IPs, which are published as not ready
+	// until their probe succeeds.
+	Prober *probe.Prober
+	// Provider looks up the endpoint IPs. It is only required for Lookup,
+	// Start and Run.
+	Provider provider.Provider
+	Updater  *updater.Updater
+	// Verifier optionally probes the looked up endpoint IPs. IPs which do not
+	// respond are not published at all, e.g. when the provider guessed wrong.
+	Verifier *probe.Prober
+
+	// Settings.
+
+	// DaemonInterval is the interval in which the lookup is re-run in the
+	// background. Disabled when zero.
+	DaemonInterval time.Duration
+	// DaemonJitter is the maximum factor of DaemonInterval randomly added to
+	// each wait, so that many instances do not reconcile in lockstep.
+	DaemonJitter float64
+	// DryRun only publishes the endpoint IPs once, without maintaining them.
+	DryRun bool
+	// Finalizer puts the cleanup finalizer on the pod given by PodName and
+	// PodNamespace once the endpoint IPs are published.
+	Finalizer bool
+	// IPFamily is the IP family policy of the published endpoint IPs. One of
+	// ipv4, ipv6 or dual.
+	IPFamily string
+	// Kind is the updater kind. The annotation kind annotates the pod given by
+	// PodName and PodNamespace and supports a single target only. The pod is
+	// looked up in the namespace of the target when PodNamespace is empty.
+	Kind         string
+	PodName      string
+	PodNamespace string
+	// ReadinessDemote keeps probing the endpoint IPs after they became ready
+	// and demotes them to not ready addresses again once their probe fails
+	// ReadinessFailureThreshold times in a row. Only used with a Prober.
+	ReadinessDemote bool
+	// ReadinessFailureThreshold is the number of consecutive probe failures
+	// after which a ready endpoint IP is demoted. Only used with
+	// ReadinessDemote.
+	ReadinessFailureThreshold int
+	// ReadinessInterval is the interval in which not ready endpoint IPs are
+	// probed. Only used with a Prober.
+	ReadinessInterval time.Duration
+	// ReassertInterval is the interval in which the endpoint IPs are published
+	// again even when no change is detected. Disabled when zero.
+	ReassertInterval time.Duration
+	// Repair watches the Endpoints of the targets and publishes the endpoint
+	// IPs again as soon as another actor removed them.
+	Repair bool
+	// RetryInitialInterval is the wait before the first retry of the initial
+	// lookup, the initial publication and the withdrawal on shutdown.
+	RetryInitialInterval time.Duration
+	// RetryMaxElapsedTime is the duration after which retrying gives up.
+	// Retrying never gives up when zero.
+	RetryMaxElapsedTime time.Duration
+	// RetryMaxInterval caps the wait between retries.
+	RetryMaxInterval time.Duration
+	// RetryMaxRetries is the number of retries after which retrying gives up.
+	// Unlimited when zero.
+	RetryMaxRetries int
+	// RetryMultiplier is the factor the wait between retries grows with.
+	RetryMultiplier float64
+	// RetryStrategy is how retries are spaced. One of constant, exponential
+	// or immediate.
+	RetryStrategy string
+	// Targets are the services the endpoint IPs are published for.
+	Targets []Target
+	// VRRPPollInterval is the interval in which the keepalived state is
+	// checked for transitions when using the VRRP provider.
+	VRRPPollInterval time.Duration
+	// Watch follows the changes notified by providers able to watch their
+	// source.
+	Watch bool
+}
+
+// DefaultConfig provides a default configuration to create a new endpoint
+// updater by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Conntrack: nil,
+		Health:    nil,
+		K8sClient: nil,
+		Logger:    nil,
+		Notifier:  nil,
+		Observer:  nil,
+		Prober:    nil,
+		Provider:  nil,
+		Updater:   nil,
+		Verifier:  nil,
+
+		// Settings.
+		DaemonInterval:            0,
+		DaemonJitter:              0,
+		DryRun:                    false,
+		Finalizer:                 false,
+		IPFamily:                  updater.FamilyIPv4,
+		Kind:                      updater.KindEndpoints,
+		PodName:                   "",
+		PodNamespace:              "",
+		ReadinessDemote:           false,
+		ReadinessFailureThreshold: 3,
+		ReadinessInterval:         5 * time.Second,
+		ReassertInterval:          0,
+		Repair:                    false,
+		RetryInitialInterval:      500 * time.Millisecond,
+		RetryMaxElapsedTime:       10 * time.Minute,
+		RetryMaxInterval:          time.Minute,
+		RetryMaxRetries:           0,
+		RetryMultiplier:           1.5,
+		RetryStrategy:             RetryStrategyExponential,
+		Targets:                   nil,
+		VRRPPollInterval:          5 * time.Second,
+		Watch:                     false,
+	}
+}
+
+// New creates a new configured endpoint updater.
+func New(config Config) (*EndpointUpdater, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if config.Updater == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Updater must not be empty")
+	}
+	if config.Repair && config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty when repairing")
+	}
+
+	// Settings.
+	if len(config.Targets) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Targets must not be empty")
+	}
+	if config.Kind == updater.KindAnnotation {
+		if config.PodName == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.PodName must not be empty with the annotation kind")
+		}
+		if len(config.Targets) != 1 {
+			return nil, microerror.Maskf(invalidConfigError, "config.Targets must contain a single target with the annotation kind")
+		}
+	}
+	if config.Finalizer && (config.PodName == "" || config.PodNamespace == "") {
+		return nil, microerror.Maskf(invalidConfigError, "config.PodName and config.PodNamespace must not be empty with the finalizer")
+	}
+	if config.DaemonJitter < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.DaemonJitter must not be negative")
+	}
+	if config.ReadinessDemote && config.ReadinessFailureThreshold <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.ReadinessFailureThreshold must be greater than zero when demoting")
+	}
+	if _, ok := config.Provider.(*vrrp.Provider); ok && config.VRRPPollInterval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.VRRPPollInterval must be greater than zero with the VRRP provider")
+	}
+	if config.RetryInitialInterval <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryInitialInterval must be greater than zero")
+	}
+	if config.RetryMaxElapsedTime < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryMaxElapsedTime must not be negative")
+	}
+	if config.RetryMaxInterval < config.RetryInitialInterval {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryMaxInterval must not be less than config.RetryInitialInterval")
+	}
+	if config.RetryMaxRetries < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryMaxRetries must not be negative")
+	}
+	if config.RetryMultiplier < 1 {
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryMultiplier must be at least 1")
+	}
+	switch config.RetryStrategy {
+	case RetryStrategyConstant, RetryStrategyExponential, RetryStrategyImmediate:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.RetryStrategy must be one of constant, exponential or immediate")
+	}
+
+	observer := config.Observer
+	if observer == nil {
+		observer = nopObserver{}
+	}
+
+	newEndpointUpdater := &EndpointUpdater{
+		// Dependencies.
+		conntrack: config.Conntrack,
+		health:    config.Health,
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		notifier:  config.Notifier,
+		observer:  observer,
+		prober:    config.Prober,
+		provider:  config.Provider,
+		updater:   config.Updater,
+		verifier:  config.Verifier,
+
+		// Internals.
+		desired:        nil,
+		desiredMutex:   sync.Mutex{},
+		pending:        nil,
+		providerMutex:  sync.Mutex{},
+		readiness:      map[string]bool{},
+		readinessMutex: sync.Mutex{},
+		reconcileMutex: sync.Mutex{},
+
+		// Settings.
+		daemonInterval:            config.DaemonInterval,
+		daemonJitter:              config.DaemonJitter,
+		dryRun:                    config.DryRun,
+		finalizer:                 config.Finalizer,
+		ipFamily:                  config.IPFamily,
+		kind:                      config.Kind,
+		podName:                   config.PodName,
+		podNamespace:              config.PodNamespace,
+		readinessDemote:           config.ReadinessDemote,
+		readinessFailureThreshold: config.ReadinessFailureThreshold,
+		readinessInterval:         config.ReadinessInterval,
+		reassertInterval:          config.ReassertInterval,
+		repair:                    config.Repair,
+		retryInitialInterval:      config.RetryInitialInterval,
+		retryMaxElapsedTime:       config.RetryMaxElapsedTime,
+		retryMaxInterval:          config.RetryMaxInterval,
+		retryMaxRetries:           config.RetryMaxRetries,
+		retryMultiplier:           config.RetryMultiplier,
+		retryStrategy:             config.RetryStrategy,
+		targets:                   config.Targets,
+		vrrpPollInterval:          config.VRRPPollInterval,
+		watch:                     config.Watch,
+	}
+
+	// The endpoint IPs currently published are attached to all log lines, so
+	// that they can be queried without parsing the messages. Before they are
+	// published, the IPs of the lookup or write in progress are attached.
+	newEndpointUpdater.logger = config.Logger.With("endpointIP", logging.Valuer(func() interface{} {
+		return updater.JoinIPs(newEndpointUpdater.getLogged())
+	}))
+
+	return newEndpointUpdater, nil
+}
+
+type EndpointUpdater struct {
+	// Dependencies.
+	conntrack *conntrack.Provider
+	health    *health.Server
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+	notifier  *systemd.Notifier
+	observer  Observer
+	prober    *probe.Prober
+	provider  provider.Provider
+	updater   *updater.Updater
+	verifier  *probe.Prober
+
+	// Internals.
+
+	// desired are the endpoint IPs which should currently be published. It is
+	// nil while the IPs are withdrawn.
+	desired      []net.IP
+	desiredMutex sync.Mutex
+	// pending are the endpoint IPs found by the lookup or published by the
+	// write in progress, until they became the desired ones. It is guarded by
+	// desiredMutex.
+	pending []net.IP
+	// providerMutex guards the provider, which might be replaced while the
+	// endpoint IPs are maintained.
+	providerMutex sync.Mutex
+	// readiness is the readiness of the endpoint IPs last published while
+	// demoting, so that publishing them again does not contradict it.
+	readiness      map[string]bool
+	readinessMutex sync.Mutex
+	// reconcileMutex serializes reconciliations, so that a reconciliation
+	// triggered from outside does not interleave with the periodic one.
+	reconcileMutex sync.Mutex
+
+	// Settings.
+	daemonInterval            time.Duration
+	daemonJitter              float64
+	dryRun                    bool
+	finalizer                 bool
+	ipFamily                  string
+	kind                      string
+	podName                   string
+	podNamespace              string
+	readinessDemote           bool
+	readinessFailureThreshold int
+	readinessInterval         time.Duration
+	reassertInterval          time.Duration
+	repair                    bool
+	retryInitialInterval      time.Duration
+	retryMaxElapsedTime       time.Duration
+	retryMaxInterval          time.Duration
+	retryMaxRetries           int
+	retryMultiplier           float64
+	retryStrategy             string
+	targets                   []Target
+	vrrpPollInterval          time.Duration
+	watch                     bool
+}
+
+// Run publishes the endpoint IPs like Start and maintains them until the given
+// context is cancelled. A cancelled context is no failure. In dry-run mode Run
+// returns once the endpoint IPs would have been published.
+func (e *EndpointUpdater) Run(ctx context.Context) error {
+	_, err := e.Start(ctx)
+	if IsCancelled(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if e.dryRun {
+		return nil
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// Start looks up the endpoint IPs and publishes them, retrying both with the
+// configured backoff. Afterwards the endpoint IPs are maintained in the
+// background until the given context is cancelled. The published endpoint IPs
+// are returned. A cancelledError is returned in case the given context is
+// cancelled before the endpoint IPs are published. A retryBudgetExhaustedError
+// is returned in case retrying gave up. A VRRP BACKUP node has nothing to
+// publish, so it starts idle and only follows the VRRP state.
+func (e *EndpointUpdater) Start(ctx context.Context) ([]net.IP, error) {
+	var ips []net.IP
+	var standby bool
+	{
+		var attempts int
+		action := func() error {
+			attempts++
+
+			var err error
+			ips, err = e.Lookup(ctx)
+			if vrrp.IsNotMaster(err) {
+				standby = true
+				err = nil
+			}
+			e.observer.LookupDone(ips, err)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		}
+
+		err := backoff.RetryNotify(action, e.newBackOff(ctx), e.newRetryNotifier("looking up endpoint IP", &attempts))
+		if ctx.Err() != nil {
+			return nil, microerror.Maskf(cancelledError, "looking up endpoint IP")
+		} else if err != nil {
+			return nil, microerror.Maskf(retryBudgetExhaustedError, "looking up endpoint IP failed %d times: %s", attempts, err.Error())
+		}
+
+		if standby {
+			_ = e.logger.Log("info", "VRRP state is not MASTER, waiting to take over the VIP")
+		} else {
+			_ = e.logger.Log("debug", fmt.Sprintf("found pod info for services '%s'", targetNames(e.targets)), "ips", updater.JoinIPs(ips))
+		}
+	}
+
+	if !standby {
+		var attempts int
+		action := func() error {
+			attempts++
+
+			err := e.Publish(ctx, ips)
+			e.observer.PublishDone(ips, err)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		}
+
+		err := backoff.RetryNotify(action, e.newBackOff(ctx), e.newRetryNotifier("publishing endpoint IP", &attempts))
+		if ctx.Err() != nil {
+			return nil, microerror.Maskf(cancelledError, "publishing endpoint IP")
+		} else if err != nil {
+			return nil, microerror.Maskf(retryBudgetExhaustedError, "publishing endpoint IP failed %d times: %s", attempts, err.Error())
+		}
+
+		if e.kind == updater.KindAnnotation {
+			_ = e.logger.Log("debug", fmt.Sprintf("added annotations to the KVM pod '%s'", e.podName))
+		} else {
+			_ = e.logger.Log("debug", fmt.Sprintf("added endpoint IP to services '%s'", targetNames(e.targets)), "kind", e.kind)
+		}
+	}
+
+	// Nothing has been published in dry-run mode, so there is nothing to
+	// maintain in the background either.
+	if e.dryRun {
+		return ips, nil
+	}
+
+	// The finalizer guarantees the cleanup of the published endpoint IPs by
+	// the reap command, even when we get killed without being able to
+	// withdraw them ourselves.
+	if e.finalizer {
+		var services []string
+		for _, t := range e.targets {
+			services = append(services, t.String())
+		}
+
+		err := e.updater.AddFinalizer(ctx, e.podNamespace, e.podName, services)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	e.setDesired(ips)
+	e.health.SetReady()
+	e.notifier.Ready()
+
+	e.maintain(ctx, ips)
+
+	return ips, nil
+}
+
+// Lookup resolves the endpoint IPs using the configured provider according to
+// the configured IP family policy and optionally confirms them against the
+// conntrack table. With a verifier only the IPs which respond to its probe are
+// returned.
+func (e *EndpointUpdater) Lookup(ctx context.Context) ([]net.IP, error) {
+	p := e.getProvider()
+	if p == nil {
+		return nil, microerror.Maskf(invalidConfigError, "provider must not be empty for lookups")
+	}
+
+	ctx, span := tracing.Start(ctx, "lookup")
+	defer span.End()
+
+	pods, err := p.Lookup(ctx)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, microerror.Mask(err)
+	}
+
+	ips, err := selectIPFamily(provider.IPs(pods), e.ipFamily)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, microerror.Mask(err)
+	}
+
+	if e.conntrack != nil {
+		for _, ip := range ips {
+			err = e.conntrack.Confirm(ip)
+			if err != nil {
+				tracing.Fail(span, err)
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	if e.verifier != nil {
+		ips, err = e.verify(ips)
+		if err != nil {
+			tracing.Fail(span, err)
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	span.SetAttributes(attribute.String("ips", updater.JoinIPs(ips)))
+
+	e.setPending(ips)
+
+	return ips, nil
+}
+
+// SetProvider replaces the provider used by subsequent lookups, e.g. once the
+// configuration got reloaded. Watches and VRRP state tracking started already
+// keep following the previous provider.
+func (e *EndpointUpdater) SetProvider(p provider.Provider) {
+	e.providerMutex.Lock()
+	defer e.providerMutex.Unlock()
+
+	e.provider = p
+}
+
+// Reconcile looks up the endpoint IPs and publishes them right away,
+// withdrawing previously published IPs which changed.
+func (e *EndpointUpdater) Reconcile(ctx context.Context) error {
+	e.reconcileMutex.Lock()
+	defer e.reconcileMutex.Unlock()
+
+	ctx, span := tracing.Start(ctx, "reconcile", attribute.String("services", targetNames(e.targets)))
+	defer span.End()
+
+	ips, err := e.Lookup(ctx)
+	if err != nil {
+		tracing.Fail(span, err)
+		return microerror.Mask(err)
+	}
+
+	// Previously published IPs which changed are withdrawn within the same
+	// write publishing the new ones, so that the targets never lack endpoints
+	// in between, e.g. when the guest VM got rebooted onto another IP.
+	var stale []net.IP
+	desired := e.getDesired()
+	if desired != nil && updater.JoinIPs(desired) != updater.JoinIPs(ips) {
+		_ = e.logger.Log("info", "endpoint IP changed", "old", updater.JoinIPs(desired), "new", updater.JoinIPs(ips))
+
+		stale = subtractIPs(desired, ips)
+	}
+
+	err = e.Replace(ctx, stale, ips)
+	if err != nil {
+		tracing.Fail(span, err)
+		return microerror.Mask(err)
+	}
+
+	e.setDesired(ips)
+
+	_ = e.logger.Log("debug", "reconciled endpoint IP", "ips", updater.JoinIPs(ips))
+
+	return nil
+}
+
+// verify returns the given IPs which respond to the probe of the configured
+// verifier. It fails in case none of them responds.
+func (e *EndpointUpdater) verify(ips []net.IP) ([]net.IP, error) {
+	var reachable []net.IP
+	var probeErr error
+	for _, ip := range ips {
+		err := e.verifier.Probe(ip)
+		if err != nil {
+			_ = e.logger.Log("warning", "endpoint IP not reachable", "ip", ip.String(), "reason", err.Error())
+			if probeErr == nil {
+				probeErr = err
+			}
+			continue
+		}
+
+		reachable = append(reachable, ip)
+	}
+
+	if len(reachable) == 0 {
+		return nil, microerror.Maskf(unreachableError, "endpoint IPs %s do not respond: %s", updater.JoinIPs(ips), probeErr.Error())
+	}
+
+	return reachable, nil
+}
+
+// Publish registers the given endpoint IPs for all targets using the
+// configured updater kind. Annotations carry a single IP only.
+func (e *EndpointUpdater) Publish(ctx context.Context, ips []net.IP) error {
+	e.setPending(ips)
+
+	err := e.Replace(ctx, nil, ips)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Replace withdraws the given stale endpoint IPs and registers the given
+// endpoint IPs for all targets within a single write per object, so that the
+// targets never lack endpoints while the IPs change. Annotations carry a
+// single IP only and are simply overwritten.
+func (e *EndpointUpdater) Replace(ctx context.Context, stale, ips []net.IP) error {
+	ctx, span := tracing.Start(ctx, "publish", attribute.String("ips", updater.JoinIPs(ips)), attribute.String("stale", updater.JoinIPs(stale)))
+	defer span.End()
+
+	if e.kind == updater.KindAnnotation {
+		t := e.targets[0]
+		start := time.Now()
+
+		err := e.updater.AddAnnotations(e.annotatedNamespace(t), t.Service, e.podName, ips[0])
+		e.observer.ResultDone(Result{Target: t, Start: start, Added: ips[:1], Err: err})
+		if err != nil {
+			tracing.Fail(span, err)
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	// With readiness probing IPs not serving yet are published as not ready,
+	// so that no traffic is routed to booting VMs.
+	ready, notReady := e.probe(ips)
+
+	// All services are updated, even if some of them fail, so that a single
+	// broken service does not affect the others.
+	var failed int
+	for _, t := range e.targets {
+		start := time.Now()
+
+		err := e.updater.Replace(ctx, t.Namespace, t.Service, stale, ready, notReady)
+		e.observer.ResultDone(Result{Target: t, Start: start, Added: ips, Removed: stale, Err: err})
+		if err != nil {
+			failed++
+			_ = e.logger.Log("warning", fmt.Sprintf("publishing endpoint IP for service '%s' failed: %#v", t, microerror.Mask(err)))
+			continue
+		}
+
+		_ = e.logger.Log("debug", fmt.Sprintf("published endpoint IP for service '%s'", t), "ips", updater.JoinIPs(ips))
+	}
+
+	if failed != 0 {
+		err := microerror.Maskf(executionFailedError, "publishing endpoint IP failed for %d of %d services", failed, len(e.targets))
+		tracing.Fail(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Withdraw removes the given endpoint IPs from all targets using the
+// configured updater kind.
+func (e *EndpointUpdater) Withdraw(ctx context.Context, ips []net.IP) error {
+	ctx, span := tracing.Start(ctx, "withdraw", attribute.String("ips", updater.JoinIPs(ips)))
+	defer span.End()
+
+	if e.kind == updater.KindAnnotation {
+		t := e.targets[0]
+		start := time.Now()
+
+		err := e.updater.RemoveAnnotations(e.annotatedNamespace(t), e.podName)
+		e.observer.ResultDone(Result{Target: t, Start: start, Removed: ips, Err: err})
+		if err != nil {
+			tracing.Fail(span, err)
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	var failed int
+	for _, t := range e.targets {
+		start := time.Now()
+
+		err := e.updater.Delete(ctx, t.Namespace, t.Service, ips)
+		e.observer.ResultDone(Result{Target: t, Start: start, Removed: ips, Err: err})
+		if err != nil {
+			failed++
+			_ = e.logger.Log("warning", fmt.Sprintf("withdrawing endpoint IP for service '%s' failed: %#v", t, microerror.Mask(err)))
+			continue
+		}
+	}
+
+	if failed != 0 {
+		err := microerror.Maskf(executionFailedError, "withdrawing endpoint IP failed for %d of %d services", failed, len(e.targets))
+		tracing.Fail(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown withdraws the endpoint IPs currently published from all targets.
+// Failed attempts are retried until the given context expires. Shutdown
+// returns once the context expired even when a request is still in flight, so
+// that the caller can bound the cleanup to the termination grace period.
+func (e *EndpointUpdater) Shutdown(ctx context.Context) error {
+	ips := e.getDesired()
+	if ips == nil {
+		return nil
+	}
+
+	b := &deadlineBackOff{BackOff: e.newStrategyBackOff(), ctx: ctx}
+
+	done := make(chan error, 1)
+	go func() {
+		var attempts int
+		action := func() error {
+			attempts++
+
+			err := e.Withdraw(ctx, ips)
+			if err != nil {
+				return microerror.Mask(err)
+			}
+
+			return nil
+		}
+
+		done <- backoff.RetryNotify(action, cenkalti.WithContext(b, ctx), e.newRetryNotifier("withdrawing endpoint IP", &attempts))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && (b.expired || ctx.Err() != nil) {
+			return microerror.Maskf(timeoutError, "withdrawing endpoint IPs %s: %s", updater.JoinIPs(ips), err.Error())
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+	case <-ctx.Done():
+		return microerror.Maskf(timeoutError, "withdrawing endpoint IPs %s", updater.JoinIPs(ips))
+	}
+
+	e.setDesired(nil)
+
+	return nil
+}
+
+// probe splits the given IPs into ready and not ready ones using the
+// configured readiness probe. All IPs are ready when probing is disabled.
+// The readiness of IPs known already is kept and IPs passing their probe are
+// remembered as ready, so that a single failing probe during a later publish
+// does not demote them. Only demoting marks them not ready again, once the
+// failure threshold is reached. The given IPs have to be all desired ones,
+// since the readiness of all others is forgotten.
+func (e *EndpointUpdater) probe(ips []net.IP) ([]net.IP, []net.IP) {
+	if e.prober == nil {
+		return ips, nil
+	}
+
+	e.pruneReadiness(ips)
+
+	var ready []net.IP
+	var notReady []net.IP
+	for _, ip := range ips {
+		isReady, known := e.getReadiness(ip)
+		if known && isReady {
+			ready = append(ready, ip)
+			continue
+		} else if known {
+			notReady = append(notReady, ip)
+			continue
+		}
+
+		err := e.prober.Probe(ip)
+		if err != nil {
+			_ = e.logger.Log("debug", "endpoint IP not ready", "ip", ip.String(), "reason", err.Error())
+			notReady = append(notReady, ip)
+			continue
+		}
+
+		e.setReadiness(ip, true)
+		ready = append(ready, ip)
+	}
+
+	return ready, notReady
+}
+
+// annotatedNamespace returns the namespace of the pod annotated for the given
+// target with the annotation kind.
+func (e *EndpointUpdater) annotatedNamespace(t Target) string {
+	if e.podNamespace != "" {
+		return e.podNamespace
+	}
+
+	return t.Namespace
+}
+
+func (e *EndpointUpdater) getDesired() []net.IP {
+	e.desiredMutex.Lock()
+	defer e.desiredMutex.Unlock()
+
+	return e.desired
+}
+
+func (e *EndpointUpdater) setDesired(ips []net.IP) {
+	e.desiredMutex.Lock()
+	defer e.desiredMutex.Unlock()
+
+	e.desired = ips
+	e.pending = nil
+}
+
+func (e *EndpointUpdater) setPending(ips []net.IP) {
+	e.desiredMutex.Lock()
+	defer e.desiredMutex.Unlock()
+
+	e.pending = ips
+}
+
+// getLogged returns the endpoint IPs attached to log lines.
+func (e *EndpointUpdater) getLogged() []net.IP {
+	e.desiredMutex.Lock()
+	defer e.desiredMutex.Unlock()
+
+	if e.desired != nil {
+		return e.desired
+	}
+
+	return e.pending
+}
+
+func (e *EndpointUpdater) getProvider() provider.Provider {
+	e.providerMutex.Lock()
+	defer e.providerMutex.Unlock()
+
+	return e.provider
+}
+
+func (e *EndpointUpdater) getReadiness(ip net.IP) (bool, bool) {
+	e.readinessMutex.Lock()
+	defer e.readinessMutex.Unlock()
+
+	ready, ok := e.readiness[ip.String()]
+	return ready, ok
+}
+
+func (e *EndpointUpdater) setReadiness(ip net.IP, ready bool) {
+	e.readinessMutex.Lock()
+	defer e.readinessMutex.Unlock()
+
+	e.readiness[ip.String()] = ready
+}
+
+// pruneReadiness forgets the readiness of IPs not contained in the given
+// ones, e.g. because they got withdrawn.
+func (e *EndpointUpdater) pruneReadiness(ips []net.IP) {
+	e.readinessMutex.Lock()
+	defer e.readinessMutex.Unlock()
+
+	for key := range e.readiness {
+		if !containsIP(ips, key) {
+			delete(e.readiness, key)
+		}
+	}
+}
+
+// maintain starts the background loops keeping the published endpoint IPs up
+// to date according to the configuration. They stop once the given context is
+// cancelled.
+func (e *EndpointUpdater) maintain(ctx context.Context, ips []net.IP) {
+	// Not ready IPs are promoted once their probe succeeds. With demoting,
+	// ready IPs keep being probed and are demoted again once they fail.
+	if e.prober != nil && e.readinessDemote {
+		go e.superviseReadiness(ctx)
+	} else if e.prober != nil {
+		go e.awaitReady(ctx)
+	}
+
+	// External actors or etcd restores might silently drop what we published,
+	// so we optionally re-apply the desired state periodically.
+	if e.reassertInterval > 0 {
+		go e.reassert(ctx)
+	}
+
+	// Other actors might remove or overwrite our addresses, which is repaired
+	// as soon as the change is observed.
+	if e.repair {
+		for _, t := range e.targets {
+			go e.repairTarget(ctx, t)
+		}
+	}
+
+	// The lookup is optionally re-run periodically, so that changed IPs get
+	// published and drift caused by restarts or manual edits gets repaired.
+	// The reconcile loop feeds the systemd watchdog, so that a stuck
+	// reconciliation gets the updater restarted. Without it there is no loop
+	// to supervise and the watchdog is fed unconditionally.
+	if e.daemonInterval > 0 {
+		go e.reconcile(ctx)
+	} else if e.notifier.WatchdogInterval() > 0 {
+		go e.feedWatchdog(ctx)
+	}
+
+	// Providers able to watch their source notify about changed IPs, which are
+	// then reconciled immediately.
+	if watchingProvider, ok := e.getProvider().(provider.WatchingProvider); ok && e.watch {
+		go e.followWatch(ctx, watchingProvider)
+	}
+
+	// The VIP has to be withdrawn as soon as the local node transitions to
+	// BACKUP and to be registered again once it becomes MASTER. Nothing has
+	// been registered in case we started as BACKUP.
+	if vrrpProvider, ok := e.getProvider().(*vrrp.Provider); ok {
+		go e.followVRRP(ctx, vrrpProvider, len(ips) != 0)
+	}
+}
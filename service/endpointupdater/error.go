@@ -0,0 +1,45 @@
+package endpointupdater
+
+import "github.com/giantswarm/microerror"
+
+var cancelledError = microerror.New("cancelled")
+
+// IsCancelled asserts cancelledError.
+func IsCancelled(err error) bool {
+	return microerror.Cause(err) == cancelledError
+}
+
+var executionFailedError = microerror.New("execution failed")
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
+var invalidConfigError = microerror.New("invalid config")
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var retryBudgetExhaustedError = microerror.New("retry budget exhausted")
+
+// IsRetryBudgetExhausted asserts retryBudgetExhaustedError.
+func IsRetryBudgetExhausted(err error) bool {
+	return microerror.Cause(err) == retryBudgetExhaustedError
+}
+
+var timeoutError = microerror.New("timeout")
+
+// IsTimeout asserts timeoutError.
+func IsTimeout(err error) bool {
+	return microerror.Cause(err) == timeoutError
+}
+
+var unreachableError = microerror.New("unreachable")
+
+// IsUnreachable asserts unreachableError.
+func IsUnreachable(err error) bool {
+	return microerror.Cause(err) == unreachableError
+}
@@ -0,0 +1,72 @@
+package endpointupdater
+
+import (
+	"net"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// selectIPFamily picks the IPs matching the given IP family policy. The
+// policies ipv4 and ipv6 select the IPs of the respective family, dual selects
+// the IPs of both. Multiple IPs of a family are only found by composite
+// providers. A policy which cannot be satisfied results in an error.
+func selectIPFamily(ips []net.IP, policy string) ([]net.IP, error) {
+	var families []string
+	switch policy {
+	case IPFamilyDual:
+		families = []string{updater.FamilyIPv4, updater.FamilyIPv6}
+	default:
+		families = []string{policy}
+	}
+
+	var selected []net.IP
+	for _, family := range families {
+		var found bool
+		for _, ip := range ips {
+			if updater.Family(ip) == family {
+				selected = append(selected, ip)
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, microerror.Maskf(executionFailedError, "no %s endpoint IP found", family)
+		}
+	}
+
+	return selected, nil
+}
+
+// subtractIPs returns the IPs of a which are not contained in b.
+func subtractIPs(a, b []net.IP) []net.IP {
+	var result []net.IP
+	for _, x := range a {
+		var found bool
+		for _, y := range b {
+			if x.Equal(y) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			result = append(result, x)
+		}
+	}
+
+	return result
+}
+
+// containsIP returns whether the given IPs contain the given IP in its string
+// representation.
+func containsIP(ips []net.IP, s string) bool {
+	for _, ip := range ips {
+		if ip.String() == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,286 @@
+package endpointupdater
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/vrrp"
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// awaitReady promotes the published endpoint IPs to ready addresses once
+// their readiness probe succeeds. The probe remembers them as ready, so that
+// later publishes keep them ready after awaitReady returned.
+func (e *EndpointUpdater) awaitReady(ctx context.Context) {
+	ticker := time.NewTicker(e.readinessInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ips := e.getDesired()
+		if ips == nil {
+			continue
+		}
+
+		ready, notReady := e.probe(ips)
+		if len(ready) != 0 {
+			var failed bool
+			for _, t := range e.targets {
+				err := e.updater.Create(ctx, t.Namespace, t.Service, ready)
+				if err != nil {
+					failed = true
+					_ = e.logger.Log("warning", fmt.Sprintf("promoting ready endpoint IP for service '%s' failed: %#v", t, microerror.Mask(err)))
+				}
+			}
+			if failed {
+				continue
+			}
+		}
+
+		if len(notReady) == 0 {
+			_ = e.logger.Log("info", "endpoint IP became ready", "ips", updater.JoinIPs(ready))
+			return
+		}
+	}
+}
+
+// superviseReadiness keeps probing the published endpoint IPs, promoting them
+// once their probe succeeds and demoting them to not ready addresses again
+// once their probe failed the configured number of times in a row, e.g. when
+// the guest cluster API server becomes unhealthy.
+func (e *EndpointUpdater) superviseReadiness(ctx context.Context) {
+	ticker := time.NewTicker(e.readinessInterval)
+	defer ticker.Stop()
+
+	failures := map[string]int{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ips := e.getDesired()
+		e.pruneReadiness(ips)
+
+		for _, ip := range ips {
+			key := ip.String()
+			isReady, known := e.getReadiness(ip)
+
+			err := e.prober.Probe(ip)
+			if err == nil {
+				failures[key] = 0
+				if known && isReady {
+					continue
+				}
+
+				if e.publishReadiness(ctx, ip, true) {
+					if known {
+						_ = e.logger.Log("info", "endpoint IP became ready again", "ip", key)
+					} else {
+						_ = e.logger.Log("info", "endpoint IP became ready", "ip", key)
+					}
+					e.setReadiness(ip, true)
+				}
+				continue
+			}
+
+			failures[key]++
+			_ = e.logger.Log("debug", "endpoint IP probe failed", "ip", key, "failures", failures[key], "reason", err.Error())
+
+			if failures[key] < e.readinessFailureThreshold || (known && !isReady) {
+				continue
+			}
+
+			if e.publishReadiness(ctx, ip, false) {
+				_ = e.logger.Log("warning", "endpoint IP became not ready", "ip", key, "failures", failures[key], "reason", err.Error())
+				e.setReadiness(ip, false)
+			}
+		}
+
+		for key := range failures {
+			if !containsIP(ips, key) {
+				delete(failures, key)
+			}
+		}
+	}
+}
+
+// publishReadiness publishes the given IP as ready or not ready address for
+// all targets. It returns whether all targets got updated.
+func (e *EndpointUpdater) publishReadiness(ctx context.Context, ip net.IP, ready bool) bool {
+	ok := true
+	for _, t := range e.targets {
+		var err error
+		if ready {
+			err = e.updater.Create(ctx, t.Namespace, t.Service, []net.IP{ip})
+		} else {
+			err = e.updater.CreateNotReady(ctx, t.Namespace, t.Service, []net.IP{ip})
+		}
+		if err != nil {
+			ok = false
+			_ = e.logger.Log("warning", fmt.Sprintf("changing readiness of endpoint IP for service '%s' failed: %#v", t, microerror.Mask(err)), "ready", ready)
+		}
+	}
+
+	return ok
+}
+
+func (e *EndpointUpdater) reassert(ctx context.Context) {
+	ticker := time.NewTicker(e.reassertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ips := e.getDesired()
+		if ips == nil {
+			continue
+		}
+
+		err := e.Publish(ctx, ips)
+		if err != nil {
+			e.health.ReportFailure()
+			_ = e.logger.Log("warning", fmt.Sprintf("reasserting endpoint IP failed: %#v", microerror.Mask(err)))
+			continue
+		}
+		e.health.ReportSuccess()
+
+		_ = e.logger.Log("debug", "reasserted endpoint IP", "ips", updater.JoinIPs(ips))
+	}
+}
+
+func (e *EndpointUpdater) reconcile(ctx context.Context) {
+	var watchdog <-chan time.Time
+	if interval := e.notifier.WatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		watchdog = ticker.C
+	}
+
+	for {
+		// The jitter is drawn for every wait, so that instances started at
+		// the same time drift apart instead of hitting the API server in
+		// lockstep.
+		interval := e.daemonInterval
+		if e.daemonJitter > 0 {
+			interval = wait.Jitter(e.daemonInterval, e.daemonJitter)
+		}
+
+		next := time.After(interval)
+
+	waiting:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-watchdog:
+				e.notifier.Watchdog()
+			case <-next:
+				break waiting
+			}
+		}
+
+		e.reconcileOnce(ctx)
+	}
+}
+
+// feedWatchdog sends systemd watchdog pings until the given context is
+// cancelled.
+func (e *EndpointUpdater) feedWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(e.notifier.WatchdogInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		e.notifier.Watchdog()
+	}
+}
+
+func (e *EndpointUpdater) followWatch(ctx context.Context, watchingProvider provider.WatchingProvider) {
+	for pods := range watchingProvider.Watch(ctx) {
+		_ = e.logger.Log("debug", "provider reported changed endpoint IP", "ips", updater.JoinIPs(provider.IPs(pods)))
+
+		e.reconcileOnce(ctx)
+	}
+}
+
+// reconcileOnce reconciles the endpoint IPs and reports the outcome to the
+// health server.
+func (e *EndpointUpdater) reconcileOnce(ctx context.Context) {
+	err := e.Reconcile(ctx)
+	if err != nil {
+		e.health.ReportFailure()
+		_ = e.logger.Log("warning", fmt.Sprintf("reconciling endpoint IP failed: %#v", microerror.Mask(err)))
+		return
+	}
+	e.health.ReportSuccess()
+}
+
+func (e *EndpointUpdater) followVRRP(ctx context.Context, vrrpProvider *vrrp.Provider, registered bool) {
+	vips := []net.IP{vrrpProvider.VIP()}
+
+	ticker := time.NewTicker(e.vrrpPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		master, err := vrrpProvider.IsMaster()
+		if err != nil {
+			_ = e.logger.Log("warning", fmt.Sprintf("checking VRRP state failed: %#v", microerror.Mask(err)))
+			continue
+		}
+
+		if master == registered {
+			continue
+		}
+
+		if master {
+			_ = e.logger.Log("info", "transitioned to MASTER, registering VIP", "ips", updater.JoinIPs(vips))
+			err = e.Publish(ctx, vips)
+		} else {
+			_ = e.logger.Log("info", "transitioned to BACKUP, withdrawing VIP", "ips", updater.JoinIPs(vips))
+			err = e.Withdraw(ctx, vips)
+		}
+		if err != nil {
+			e.health.ReportFailure()
+			_ = e.logger.Log("warning", fmt.Sprintf("following VRRP transition failed: %#v", microerror.Mask(err)))
+			continue
+		}
+		e.health.ReportSuccess()
+
+		registered = master
+
+		if master {
+			e.setDesired(vips)
+		} else {
+			e.setDesired(nil)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package endpointupdater
+
+import (
+	"net"
+	"time"
+)
+
+// Observer is notified about the progress of the endpoint updater, e.g. to
+// print progress or to collect metrics. Implementations must not block.
+type Observer interface {
+	// LookupDone is called after every attempt of the initial lookup with the
+	// endpoint IPs found, if any, and the error of the attempt, if any.
+	LookupDone(ips []net.IP, err error)
+	// PublishDone is called after every attempt of the initial publishing
+	// with the endpoint IPs being published and the error of the attempt, if
+	// any.
+	PublishDone(ips []net.IP, err error)
+	// ResultDone is called after every write of the endpoint IPs of a single
+	// target, including the ones done in the background.
+	ResultDone(result Result)
+}
+
+// Result is the outcome of writing the endpoint IPs of a single target.
+type Result struct {
+	Target Target
+	// Start is the time the write started.
+	Start time.Time
+	// Added are the endpoint IPs published.
+	Added []net.IP
+	// Removed are the endpoint IPs withdrawn.
+	Removed []net.IP
+	// Err is the error of the write, if any.
+	Err error
+}
+
+// nopObserver is used when no observer is configured.
+type nopObserver struct{}
+
+func (nopObserver) LookupDone(ips []net.IP, err error)  {}
+func (nopObserver) PublishDone(ips []net.IP, err error) {}
+func (nopObserver) ResultDone(result Result)            {}
@@ -0,0 +1,127 @@
+package endpointupdater
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+const (
+	// repairRewatchInterval is the time waited before the Endpoints are
+	// watched again after the watch ended or failed.
+	repairRewatchInterval = 5 * time.Second
+)
+
+// repairTarget watches the Endpoints of the given service and publishes the desired
+// endpoint IPs again as soon as another actor removed or overwrote them,
+// instead of waiting for the next reassertion or reconciliation.
+func (e *EndpointUpdater) repairTarget(ctx context.Context, t Target) {
+	for {
+		err := e.watchEndpoints(ctx, t)
+		if err != nil {
+			_ = e.logger.Log("warning", fmt.Sprintf("watching endpoints failed: %#v", microerror.Mask(err)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(repairRewatchInterval):
+		}
+	}
+}
+
+// watchEndpoints repairs the Endpoints of the given service until the watch
+// ends or the given context is cancelled.
+func (e *EndpointUpdater) watchEndpoints(ctx context.Context, t Target) error {
+	options := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", t.Service).String(),
+	}
+
+	w, err := e.k8sClient.CoreV1().Endpoints(t.Namespace).Watch(options)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer w.Stop()
+
+	for {
+		var event watch.Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+			event = ev
+		}
+
+		var missing []net.IP
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			endpoints, ok := event.Object.(*corev1.Endpoints)
+			if !ok {
+				continue
+			}
+			missing = missingIPs(endpoints, e.getDesired())
+		case watch.Deleted:
+			missing = e.getDesired()
+		case watch.Error:
+			return microerror.Mask(errors.FromObject(event.Object))
+		}
+
+		if len(missing) == 0 {
+			continue
+		}
+
+		_ = e.logger.Log("info", "endpoint IP removed externally, repairing", "namespace", t.Namespace, "service", t.Service, "ips", updater.JoinIPs(missing))
+
+		ready, notReady := e.probe(e.getDesired())
+		err := e.updater.Replace(ctx, t.Namespace, t.Service, nil, ready, notReady)
+		if err != nil {
+			e.health.ReportFailure()
+			_ = e.logger.Log("warning", fmt.Sprintf("repairing endpoint IP failed: %#v", microerror.Mask(err)))
+			continue
+		}
+		e.health.ReportSuccess()
+	}
+}
+
+// missingIPs returns the given IPs which are neither ready nor not ready
+// addresses of the given Endpoints.
+func missingIPs(endpoints *corev1.Endpoints, ips []net.IP) []net.IP {
+	var missing []net.IP
+	for _, ip := range ips {
+		var found bool
+		for _, subset := range endpoints.Subsets {
+			if containsAddress(subset.Addresses, ip) || containsAddress(subset.NotReadyAddresses, ip) {
+				found = true
+			}
+		}
+
+		if !found {
+			missing = append(missing, ip)
+		}
+	}
+
+	return missing
+}
+
+func containsAddress(addresses []corev1.EndpointAddress, ip net.IP) bool {
+	for _, a := range addresses {
+		if a.IP == ip.String() {
+			return true
+		}
+	}
+
+	return false
+}
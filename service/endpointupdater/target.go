@@ -0,0 +1,25 @@
+package endpointupdater
+
+import (
+	"strings"
+)
+
+// Target is a service the endpoint IPs are published for.
+type Target struct {
+	Namespace string
+	Service   string
+}
+
+func (t Target) String() string {
+	return t.Namespace + "/" + t.Service
+}
+
+// targetNames returns the names of the given targets, separated by commas.
+func targetNames(ts []Target) string {
+	var names []string
+	for _, t := range ts {
+		names = append(names, t.String())
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,268 @@
+// Package health implements the HTTP server exposing liveness and readiness
+// endpoints for long running updaters. /readyz succeeds once the endpoint IP
+// has been published initially. /healthz fails once reconciling the endpoint
+// IP kept failing for longer than the configured threshold. For exec probes
+// the same state is optionally reflected by a ready file, which exists while
+// the updater is ready and healthy.
+package health
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// shutdownTimeout is the time in-flight requests are given to complete
+	// when the server is stopped.
+	shutdownTimeout = 5 * time.Second
+)
+
+// Config represents the configuration used to create a new health server.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Address is the address the health server listens on, e.g. ":8080". No
+	// HTTP server is started when empty.
+	Address string
+	// FailureThreshold is the duration reconciliation may keep failing before
+	// the updater is reported unhealthy.
+	FailureThreshold time.Duration
+	// ReadyFile is the path of the file created once the endpoint IP has been
+	// published initially and removed while reconciliation keeps failing for
+	// longer than FailureThreshold. Disabled when empty.
+	ReadyFile string
+}
+
+// DefaultConfig provides a default configuration to create a new health
+// server by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Address:          "",
+		FailureThreshold: 5 * time.Minute,
+		ReadyFile:        "",
+	}
+}
+
+// New creates a new health server.
+func New(config Config) (*Server, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Address == "" && config.ReadyFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Address or config.ReadyFile must not be empty")
+	}
+	if config.FailureThreshold <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.FailureThreshold must be greater than zero")
+	}
+
+	newServer := &Server{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		failingSince:     time.Time{},
+		mutex:            sync.Mutex{},
+		ready:            false,
+		readyFileWritten: false,
+		server:           nil,
+
+		// Settings.
+		address:          config.Address,
+		failureThreshold: config.FailureThreshold,
+		readyFile:        config.ReadyFile,
+	}
+
+	return newServer, nil
+}
+
+// Server serves the health endpoints. All methods are no-ops on a nil server,
+// so callers do not have to distinguish whether health checks are enabled.
+type Server struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	failingSince time.Time
+	mutex        sync.Mutex
+	ready        bool
+	// readyFileWritten is whether the ready file currently exists, so that it
+	// is only touched when the state changes.
+	readyFileWritten bool
+	server           *http.Server
+
+	// Settings.
+	address          string
+	failureThreshold time.Duration
+	readyFile        string
+}
+
+// Boot starts the health server in the background.
+func (s *Server) Boot() {
+	if s == nil || s.address == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+
+	s.server = &http.Server{Addr: s.address, Handler: mux}
+
+	go func() {
+		_ = s.logger.Log("debug", "starting health server", "address", s.address)
+
+		err := s.server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			_ = s.logger.Log("error", fmt.Sprintf("health server failed: %#v", microerror.Mask(err)))
+		}
+	}()
+}
+
+// Stop shuts the health server down gracefully, giving in-flight requests
+// shutdownTimeout to complete. The ready file is removed, since the updater
+// does not maintain the endpoint IP anymore.
+func (s *Server) Stop() {
+	if s == nil {
+		return
+	}
+
+	if s.readyFile != "" {
+		s.mutex.Lock()
+		s.ready = false
+		s.syncReadyFile()
+		s.mutex.Unlock()
+	}
+
+	if s.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		_ = s.logger.Log("warning", fmt.Sprintf("stopping health server failed: %#v", microerror.Mask(err)))
+	}
+}
+
+// SetReady marks the updater ready, which is the case once the endpoint IP
+// has been published initially.
+func (s *Server) SetReady() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ready = true
+	s.syncReadyFile()
+}
+
+// ReportFailure records a failed reconciliation.
+func (s *Server) ReportFailure() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failingSince.IsZero() {
+		s.failingSince = time.Now()
+	}
+	s.syncReadyFile()
+}
+
+// ReportSuccess records a successful reconciliation.
+func (s *Server) ReportSuccess() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failingSince = time.Time{}
+	s.syncReadyFile()
+}
+
+// syncReadyFile creates or removes the ready file according to the current
+// state. It has to be called with the mutex held.
+func (s *Server) syncReadyFile() {
+	if s.readyFile == "" {
+		return
+	}
+
+	healthy := s.failingSince.IsZero() || time.Since(s.failingSince) <= s.failureThreshold
+	want := s.ready && healthy
+	if want == s.readyFileWritten {
+		return
+	}
+
+	var err error
+	if want {
+		err = ioutil.WriteFile(s.readyFile, nil, 0644)
+	} else {
+		err = os.Remove(s.readyFile)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		_ = s.logger.Log("warning", fmt.Sprintf("updating ready file failed: %#v", microerror.Mask(err)), "file", s.readyFile)
+		return
+	}
+	s.readyFileWritten = want
+
+	if want {
+		_ = s.logger.Log("debug", "created ready file", "file", s.readyFile)
+	} else {
+		_ = s.logger.Log("info", "removed ready file", "file", s.readyFile)
+	}
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	failingSince := s.failingSince
+	s.mutex.Unlock()
+
+	if !failingSince.IsZero() && time.Since(failingSince) > s.failureThreshold {
+		http.Error(w, fmt.Sprintf("reconciliation failing since %s", failingSince.UTC().Format(time.RFC3339)), http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	ready := s.ready
+	s.mutex.Unlock()
+
+	if !ready {
+		http.Error(w, "endpoint IP not published yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
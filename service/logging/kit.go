@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/giantswarm/micrologger"
+	"github.com/giantswarm/micrologger/loggermeta"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// kitLogger implements micrologger.Logger on top of any go-kit logger, e.g.
+// one writing logfmt. Keys given to Log replace the ones given to With, like
+// they do in JSON objects, so that no key is written twice.
+type kitLogger struct {
+	logger  kitlog.Logger
+	keyVals []interface{}
+}
+
+func (l *kitLogger) Log(keyVals ...interface{}) error {
+	return kitlog.With(l.logger, merge(l.keyVals, keyVals)...).Log()
+}
+
+func (l *kitLogger) LogCtx(ctx context.Context, keyVals ...interface{}) error {
+	meta, ok := loggermeta.FromContext(ctx)
+	if !ok {
+		return kitlog.With(l.logger, merge(l.keyVals, keyVals)...).Log()
+	}
+
+	var newKeyVals []interface{}
+	{
+		newKeyVals = append(newKeyVals, keyVals...)
+
+		for k, v := range meta.KeyVals {
+			newKeyVals = append(newKeyVals, k)
+			newKeyVals = append(newKeyVals, v)
+		}
+	}
+
+	return kitlog.With(l.logger, merge(l.keyVals, newKeyVals)...).Log()
+}
+
+func (l *kitLogger) With(keyVals ...interface{}) micrologger.Logger {
+	return &kitLogger{
+		logger:  l.logger,
+		keyVals: merge(l.keyVals, keyVals),
+	}
+}
+
+// merge appends the given key value pairs to the given context, dropping the
+// pairs of the context whose keys are given again.
+func merge(context []interface{}, keyVals []interface{}) []interface{} {
+	given := map[interface{}]bool{}
+	for i := 0; i < len(keyVals); i += 2 {
+		given[keyVals[i]] = true
+	}
+
+	var merged []interface{}
+	for i := 0; i+1 < len(context); i += 2 {
+		if given[context[i]] {
+			continue
+		}
+		merged = append(merged, context[i], context[i+1])
+	}
+
+	return append(merged, keyVals...)
+}
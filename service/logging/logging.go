@@ -0,0 +1,301 @@
+// Package logging implements the logger used by all packages. It wraps a
+// micrologger, drops log lines below the configured level and optionally
+// writes logfmt instead of JSON.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-stack/stack"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+)
+
+// The levels are the keys the packages log their messages with, e.g.
+// logger.Log("info", "message").
+const (
+	LevelDebug   = "debug"
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// callerDepth is the depth of the frame logging a line, as seen from the
+// caller valuer. It is one more than micrologger's default, since the line
+// passes through Logger.Log first.
+const callerDepth = 5
+
+var levels = map[string]int{
+	LevelDebug:   0,
+	LevelInfo:    1,
+	LevelWarning: 2,
+	LevelError:   3,
+}
+
+// Config represents the configuration used to create a new logger.
+type Config struct {
+	// Settings.
+	Format   string
+	IOWriter io.Writer
+	// Level is the lowest level being logged. "warn" is accepted as alias
+	// for "warning".
+	Level string
+}
+
+// DefaultConfig provides a default configuration to create a new logger by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Format:   FormatJSON,
+		IOWriter: os.Stdout,
+		Level:    LevelDebug,
+	}
+}
+
+// New creates a new configured logger.
+func New(config Config) (*Logger, error) {
+	// Settings.
+	if config.IOWriter == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.IOWriter must not be empty")
+	}
+
+	err := ValidateFormat(config.Format)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	s := &settings{
+		format: config.Format,
+		level:  level,
+	}
+
+	newLogger, err := newLogger(config.IOWriter, s)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newLogger, nil
+}
+
+// Redirect returns a logger writing to the given writer instead. Loggers
+// created by this package keep sharing their level and format with the given
+// logger.
+func Redirect(logger micrologger.Logger, w io.Writer) (micrologger.Logger, error) {
+	l, ok := logger.(*Logger)
+	if !ok {
+		newLogger, err := micrologger.New(micrologger.Config{IOWriter: w})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return newLogger, nil
+	}
+
+	newLogger, err := newLogger(w, l.settings)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newLogger, nil
+}
+
+func newLogger(w io.Writer, s *settings) (*Logger, error) {
+	caller := func() interface{} {
+		return fmt.Sprintf("%+v", stack.Caller(callerDepth))
+	}
+
+	microLogger, err := micrologger.New(micrologger.Config{
+		Caller:   caller,
+		IOWriter: w,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// The logfmt logger mirrors micrologger, so that both formats carry the
+	// same keys.
+	logfmtLogger := kitlog.With(
+		kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(w)),
+		"caller", kitlog.Valuer(caller),
+		"time", kitlog.Valuer(micrologger.DefaultTimestampFormatter),
+	)
+
+	newLogger := &Logger{
+		// Dependencies.
+		json:   microLogger,
+		logfmt: &kitLogger{logger: logfmtLogger},
+
+		// Internals.
+		settings: s,
+	}
+
+	return newLogger, nil
+}
+
+// Logger implements micrologger.Logger. It writes log lines in its format and
+// drops the ones below its level. Lines without a known level are always
+// logged.
+type Logger struct {
+	// Dependencies.
+	json   micrologger.Logger
+	logfmt micrologger.Logger
+
+	// Internals.
+	settings *settings
+}
+
+func (l *Logger) Log(keyVals ...interface{}) error {
+	logger, ok := l.settings.logger(l, keyVals)
+	if !ok {
+		return nil
+	}
+
+	return logger.Log(keyVals...)
+}
+
+func (l *Logger) LogCtx(ctx context.Context, keyVals ...interface{}) error {
+	logger, ok := l.settings.logger(l, keyVals)
+	if !ok {
+		return nil
+	}
+
+	return logger.LogCtx(ctx, keyVals...)
+}
+
+// SetFormat changes the format of this logger and all loggers derived from
+// it.
+func (l *Logger) SetFormat(format string) error {
+	err := ValidateFormat(format)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	l.settings.setFormat(format)
+
+	return nil
+}
+
+// SetLevel changes the lowest level being logged by this logger and all
+// loggers derived from it.
+func (l *Logger) SetLevel(level string) error {
+	n, err := parseLevel(level)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	l.settings.setLevel(n)
+
+	return nil
+}
+
+func (l *Logger) With(keyVals ...interface{}) micrologger.Logger {
+	return &Logger{
+		json:     l.json.With(keyVals...),
+		logfmt:   l.logfmt.With(keyVals...),
+		settings: l.settings,
+	}
+}
+
+// Valuer returns a value for With which is evaluated whenever a line gets
+// logged, e.g. to attach state changing over time to all log lines.
+func Valuer(f func() interface{}) interface{} {
+	return kitlog.Valuer(f)
+}
+
+// ValidateFormat returns an error when the given format is unknown.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatJSON, FormatLogfmt:
+		return nil
+	default:
+		return microerror.Maskf(invalidConfigError, "format must be one of json or logfmt, got '%s'", format)
+	}
+}
+
+// ValidateLevel returns an error when the given level is unknown.
+func ValidateLevel(level string) error {
+	_, err := parseLevel(level)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func parseLevel(level string) (int, error) {
+	if level == "warn" {
+		level = LevelWarning
+	}
+
+	n, ok := levels[level]
+	if !ok {
+		return 0, microerror.Maskf(invalidConfigError, "level must be one of debug, info, warn or error, got '%s'", level)
+	}
+
+	return n, nil
+}
+
+// settings are shared by a logger and all loggers derived from it, so that
+// changing the level or format applies to all of them.
+type settings struct {
+	mutex  sync.RWMutex
+	format string
+	level  int
+}
+
+// logger returns the logger of the given Logger matching the format and
+// whether the given log line is at or above the level. The level of a line
+// is its first key naming a level.
+func (s *settings) logger(l *Logger, keyVals []interface{}) (micrologger.Logger, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	logger := l.json
+	if s.format == FormatLogfmt {
+		logger = l.logfmt
+	}
+
+	for i := 0; i < len(keyVals); i += 2 {
+		k, ok := keyVals[i].(string)
+		if !ok {
+			continue
+		}
+
+		n, ok := levels[k]
+		if ok {
+			return logger, n >= s.level
+		}
+	}
+
+	return logger, true
+}
+
+func (s *settings) setFormat(format string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.format = format
+}
+
+func (s *settings) setLevel(level int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.level = level
+}
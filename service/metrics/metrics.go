@@ -0,0 +1,141 @@
+// Package metrics implements the HTTP server exposing Prometheus metrics. Next
+// to the metrics registered by the other services it exposes a build_info
+// metric as well as the standard Go runtime and process metrics like
+// goroutines, GC and memory statistics.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// Namespace is the metric namespace shared by all metrics of the command
+	// line tool.
+	Namespace = "k8s_endpoint_updater"
+)
+
+const (
+	// shutdownTimeout is the time in-flight requests are given to complete
+	// when the server is stopped.
+	shutdownTimeout = 5 * time.Second
+)
+
+// Config represents the configuration used to create a new metrics server.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Address is the address the metrics server listens on, e.g. ":8000".
+	Address string
+	// GitCommit is exposed as label of the build_info metric.
+	GitCommit string
+}
+
+// DefaultConfig provides a default configuration to create a new metrics
+// server by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Address:   "",
+		GitCommit: "",
+	}
+}
+
+// New creates a new metrics server and registers the build_info metric.
+func New(config Config) (*Server, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Address == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Address must not be empty")
+	}
+
+	buildInfo := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "build_info",
+			Help:      "A metric with a constant '1' value labeled by the git commit and Go version the command line tool was built from.",
+		},
+		[]string{"git_commit", "go_version"},
+	)
+	buildInfo.WithLabelValues(config.GitCommit, runtime.Version()).Set(1)
+
+	err := prometheus.Register(buildInfo)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newServer := &Server{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		server: nil,
+
+		// Settings.
+		address: config.Address,
+	}
+
+	return newServer, nil
+}
+
+type Server struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	server *http.Server
+
+	// Settings.
+	address string
+}
+
+// Boot starts the metrics server in the background.
+func (s *Server) Boot() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	s.server = &http.Server{Addr: s.address, Handler: mux}
+
+	go func() {
+		_ = s.logger.Log("debug", "starting metrics server", "address", s.address)
+
+		err := s.server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			_ = s.logger.Log("error", fmt.Sprintf("metrics server failed: %#v", microerror.Mask(err)))
+		}
+	}()
+}
+
+// Stop shuts the metrics server down gracefully, giving in-flight requests
+// shutdownTimeout to complete.
+func (s *Server) Stop() {
+	if s == nil || s.server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		_ = s.logger.Log("warning", fmt.Sprintf("stopping metrics server failed: %#v", microerror.Mask(err)))
+	}
+}
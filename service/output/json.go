@@ -0,0 +1,133 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Summary is the machine-readable result of a run.
+type Summary struct {
+	Duration string   `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+	Results  []Result `json:"results"`
+}
+
+// Result describes what was done for a single service.
+type Result struct {
+	Namespace string   `json:"namespace"`
+	Service   string   `json:"service"`
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	// ResourceVersions maps the written objects, formatted as
+	// resource/name, to their resource version.
+	ResourceVersions map[string]string `json:"resourceVersions,omitempty"`
+	Duration         string            `json:"duration"`
+	Error            string            `json:"error,omitempty"`
+}
+
+// NewJSON creates a new JSON printer.
+func NewJSON(config Config) (*JSONPrinter, error) {
+	// Settings.
+	if config.Writer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Writer must not be empty")
+	}
+
+	newPrinter := &JSONPrinter{
+		// Internals.
+		mutex:   sync.Mutex{},
+		printed: false,
+		results: nil,
+		start:   time.Now(),
+
+		// Settings.
+		writer: config.Writer,
+	}
+
+	return newPrinter, nil
+}
+
+// JSONPrinter collects the results of a run and prints them as a single JSON
+// summary once the run is done. Results added afterwards, e.g. by background
+// reconciliation, are ignored. All methods are no-ops on a nil printer.
+type JSONPrinter struct {
+	// Internals.
+	mutex   sync.Mutex
+	printed bool
+	results []Result
+	start   time.Time
+
+	// Settings.
+	writer io.Writer
+}
+
+// AddResult adds the given result to the summary. A previous result of the
+// same service, e.g. of a failed attempt which got retried, is replaced.
+func (p *JSONPrinter) AddResult(result Result) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.printed {
+		return
+	}
+
+	for i, r := range p.results {
+		if r.Namespace == result.Namespace && r.Service == result.Service {
+			p.results[i] = result
+			return
+		}
+	}
+
+	p.results = append(p.results, result)
+}
+
+// Print prints the summary including the given error terminating the run, if
+// any.
+func (p *JSONPrinter) Print(runErr error) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.printed {
+		return nil
+	}
+	p.printed = true
+
+	summary := Summary{
+		Duration: time.Since(p.start).Round(time.Millisecond).String(),
+		Results:  p.results,
+	}
+	if summary.Results == nil {
+		summary.Results = []Result{}
+	}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_, err = p.writer.Write(append(b, '\n'))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// Package output implements human-friendly output for interactive runs of the
+// command line tool. It prints colored per-phase status lines and a final
+// table of the registered addresses. Non-interactive runs keep using the
+// structured logger instead, scripts can request a single JSON summary.
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	InteractiveAlways = "always"
+	InteractiveAuto   = "auto"
+	InteractiveNever  = "never"
+)
+
+const (
+	colorGreen  = "\033[32m"
+	colorRed    = "\033[31m"
+	colorReset  = "\033[0m"
+	colorYellow = "\033[33m"
+)
+
+// IsInteractive decides based on the given mode whether the interactive
+// output should be used. In auto mode this is the case when stdout is
+// attached to a terminal.
+func IsInteractive(mode string) (bool, error) {
+	switch mode {
+	case InteractiveAlways:
+		return true, nil
+	case InteractiveNever:
+		return false, nil
+	case InteractiveAuto:
+		info, err := os.Stdout.Stat()
+		if err != nil {
+			return false, microerror.Mask(err)
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, microerror.Maskf(invalidConfigError, "interactive mode must be one of %#q, %#q or %#q", InteractiveAlways, InteractiveAuto, InteractiveNever)
+	}
+}
+
+// Config represents the configuration used to create a new printer.
+type Config struct {
+	// Settings.
+	Writer io.Writer
+}
+
+// DefaultConfig provides a default configuration to create a new printer by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Settings.
+		Writer: os.Stdout,
+	}
+}
+
+// New creates a new printer.
+func New(config Config) (*Printer, error) {
+	// Settings.
+	if config.Writer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Writer must not be empty")
+	}
+
+	newPrinter := &Printer{
+		// Internals.
+		phase: "",
+		rows:  nil,
+		start: time.Time{},
+
+		// Settings.
+		writer: config.Writer,
+	}
+
+	return newPrinter, nil
+}
+
+// Printer prints the progress of interactive runs. All methods are no-ops on a
+// nil printer, so callers do not have to distinguish interactive and
+// non-interactive runs.
+type Printer struct {
+	// Internals.
+	phase string
+	rows  [][]string
+	start time.Time
+
+	// Settings.
+	writer io.Writer
+}
+
+// Start announces the beginning of the given phase, e.g. lookup.
+func (p *Printer) Start(phase string) {
+	if p == nil {
+		return
+	}
+
+	p.phase = phase
+	p.start = time.Now()
+
+	fmt.Fprintf(p.writer, "%s→%s %-10s ...\n", colorYellow, colorReset, phase)
+}
+
+// Done marks the current phase as succeeded with an optional detail.
+func (p *Printer) Done(detail string) {
+	if p == nil {
+		return
+	}
+
+	fmt.Fprintf(p.writer, "%s✔%s %-10s %s (%s)\n", colorGreen, colorReset, p.phase, detail, p.elapsed())
+}
+
+// Skip marks the given phase as skipped.
+func (p *Printer) Skip(phase string, reason string) {
+	if p == nil {
+		return
+	}
+
+	fmt.Fprintf(p.writer, "%s-%s %-10s skipped: %s\n", colorYellow, colorReset, phase, reason)
+}
+
+// Fail marks the current phase as failed.
+func (p *Printer) Fail(err error) {
+	if p == nil {
+		return
+	}
+
+	fmt.Fprintf(p.writer, "%s✘%s %-10s %s (%s)\n", colorRed, colorReset, p.phase, err.Error(), p.elapsed())
+}
+
+// Error prints the given error which terminates the run.
+func (p *Printer) Error(err error) {
+	if p == nil {
+		return
+	}
+
+	fmt.Fprintf(p.writer, "%serror:%s %s\n", colorRed, colorReset, err.Error())
+}
+
+// AddRow adds a row to the final table of registered addresses.
+func (p *Printer) AddRow(namespace, service, pod, ip string) {
+	if p == nil {
+		return
+	}
+
+	p.rows = append(p.rows, []string{namespace, service, pod, ip})
+}
+
+// Table prints the final table of registered addresses.
+func (p *Printer) Table() {
+	if p == nil || len(p.rows) == 0 {
+		return
+	}
+
+	fmt.Fprintln(p.writer)
+
+	w := tabwriter.NewWriter(p.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tSERVICE\tPOD\tIP")
+	for _, row := range p.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	_ = w.Flush()
+}
+
+func (p *Printer) elapsed() time.Duration {
+	return time.Since(p.start).Round(time.Millisecond)
+}
@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+const (
+	icmpv4EchoReply   = 0
+	icmpv4EchoRequest = 8
+	icmpv6EchoReply   = 129
+	icmpv6EchoRequest = 128
+)
+
+// icmpPayload identifies our echo requests in addition to the identifier,
+// which other processes on the host might use as well.
+var icmpPayload = []byte("k8s-endpoint-updater")
+
+// ping sends an ICMP echo request with the given sequence number to the given
+// IP and waits for the matching echo reply until the given timeout expires.
+func ping(ip net.IP, seq uint16, timeout time.Duration) error {
+	network := "ip4:icmp"
+	requestType := byte(icmpv4EchoRequest)
+	replyType := byte(icmpv4EchoReply)
+	if ip.To4() == nil {
+		network = "ip6:ipv6-icmp"
+		requestType = icmpv6EchoRequest
+		replyType = icmpv6EchoReply
+	}
+
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	id := uint16(os.Getpid())
+	request := echo(requestType, id, seq)
+	// The kernel computes the checksum of ICMPv6 messages itself.
+	if requestType == icmpv4EchoRequest {
+		c := checksum(request)
+		request[2] = byte(c >> 8)
+		request[3] = byte(c)
+	}
+
+	_, err = conn.WriteTo(request, &net.IPAddr{IP: ip})
+	if err != nil {
+		return microerror.Maskf(probeFailedError, "%s", err.Error())
+	}
+
+	// Raw sockets receive all ICMP messages of the host, so replies of other
+	// peers or to other requests are skipped.
+	reply := echo(replyType, id, seq)
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return microerror.Maskf(probeFailedError, "%s", err.Error())
+		}
+
+		ipAddr, ok := addr.(*net.IPAddr)
+		if !ok || !ipAddr.IP.Equal(ip) || n != len(reply) {
+			continue
+		}
+		if buf[0] != reply[0] || !bytes.Equal(buf[4:n], reply[4:]) {
+			continue
+		}
+
+		return nil
+	}
+}
+
+// echo returns an ICMP echo message of the given type without checksum.
+func echo(t byte, id, seq uint16) []byte {
+	b := []byte{t, 0, 0, 0, byte(id >> 8), byte(id), byte(seq >> 8), byte(seq)}
+
+	return append(b, icmpPayload...)
+}
+
+// checksum computes the internet checksum of the given message, see RFC 1071.
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return ^uint16(sum)
+}
@@ -0,0 +1,159 @@
+// Package probe implements readiness probes of endpoint IPs, e.g. checking
+// whether the guest cluster API server of a booting VM accepts connections.
+// Targets are given as URL without host, since the host is the probed IP:
+//
+//	icmp://
+//	tcp://:6443
+//	https://:6443/healthz
+package probe
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+	schemeICMP  = "icmp"
+	schemeTCP   = "tcp"
+)
+
+// Config represents the configuration used to create a new prober.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Target is the URL without host probed for each IP. The scheme is one of
+	// icmp, tcp, http or https. ICMP targets require the CAP_NET_RAW
+	// capability.
+	Target string
+	// Timeout is the maximum duration of a single probe.
+	Timeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new prober by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Target:  "",
+		Timeout: 5 * time.Second,
+	}
+}
+
+// New creates a new prober.
+func New(config Config) (*Prober, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	target, err := url.Parse(config.Target)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Target must be a valid URL: %s", err.Error())
+	}
+	switch target.Scheme {
+	case schemeHTTP, schemeHTTPS, schemeICMP, schemeTCP:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Target must have scheme %#q, %#q, %#q or %#q", schemeICMP, schemeTCP, schemeHTTP, schemeHTTPS)
+	}
+	if target.Scheme != schemeICMP && target.Port() == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Target must have a port")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+
+	newProber := &Prober{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		httpClient: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				// The certificates of booting guest clusters cannot be known
+				// upfront. The probe only checks whether the endpoint serves.
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // nolint:gosec
+			},
+		},
+
+		// Settings.
+		target:  target,
+		timeout: config.Timeout,
+	}
+
+	return newProber, nil
+}
+
+type Prober struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	httpClient *http.Client
+	// sequence is the sequence number of the last ICMP echo request sent.
+	sequence uint32
+
+	// Settings.
+	target  *url.URL
+	timeout time.Duration
+}
+
+// Probe checks whether the given IP serves the configured target. ICMP targets
+// succeed once an echo reply is received, TCP targets once a connection is
+// established, HTTP targets once any response other than a server error is
+// received.
+func (p *Prober) Probe(ip net.IP) error {
+	if p.target.Scheme == schemeICMP {
+		seq := uint16(atomic.AddUint32(&p.sequence, 1))
+
+		err := ping(ip, seq, p.timeout)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	}
+
+	host := net.JoinHostPort(ip.String(), p.target.Port())
+
+	if p.target.Scheme == schemeTCP {
+		conn, err := net.DialTimeout("tcp", host, p.timeout)
+		if err != nil {
+			return microerror.Maskf(probeFailedError, "%s", err.Error())
+		}
+		_ = conn.Close()
+
+		return nil
+	}
+
+	u := *p.target
+	u.Host = host
+
+	res, err := p.httpClient.Get(u.String())
+	if err != nil {
+		return microerror.Maskf(probeFailedError, "%s", err.Error())
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return microerror.Maskf(probeFailedError, "%s returned status %d", u.String(), res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package arp implements a provider which resolves the guest VM IP from the
+// host neighbor table as exposed in /proc/net/arp. Unlike the bridge provider
+// it does not assume the guest VM IP to follow the bridge IP, but reports the
+// addresses the host actually resolved on the bridge.
+package arp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "arp"
+)
+
+const (
+	// flagIncomplete marks entries whose resolution has not completed yet.
+	flagIncomplete = "0x0"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Interface is the bridge the neighbor entries have to be learned on.
+	Interface string
+	// MACPrefix optionally restricts the neighbor entries to MACs starting with
+	// the given prefix, e.g. 52:54:00 for QEMU guests.
+	MACPrefix string
+	// Path is the IPv4 neighbor table exposed by the kernel.
+	Path string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Interface: "",
+		MACPrefix: "",
+		Path:      "/proc/net/arp",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Interface == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Interface must not be empty")
+	}
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Path must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		iface:     config.Interface,
+		macPrefix: normalizeMAC(config.MACPrefix),
+		path:      config.Path,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	iface     string
+	macPrefix string
+	path      string
+}
+
+// Lookup returns the complete neighbor entries of the configured interface,
+// optionally matching the configured MAC prefix, along with their MACs.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	neighbors, err := Neighbors(p.path, p.iface)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var pods []provider.PodInfo
+	for _, n := range neighbors {
+		if !strings.HasPrefix(n.MAC.String(), p.macPrefix) {
+			continue
+		}
+
+		pods = append(pods, n)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no neighbor found on interface %#q", p.iface)
+	}
+
+	_ = p.logger.Log("debug", "found neighbor entries", "interface", p.iface, "ips", len(pods))
+
+	return pods, nil
+}
+
+// Neighbors returns the complete IPv4 neighbor entries learned on the given
+// interface as found in the neighbor table at the given path, usually
+// /proc/net/arp.
+func Neighbors(path string, iface string) ([]provider.PodInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	// The first line holds the column headers.
+	scanner.Scan()
+
+	var neighbors []provider.PodInfo
+	for scanner.Scan() {
+		// IP address HW type Flags HW address Mask Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 {
+			continue
+		}
+		if fields[5] != iface || fields[2] == flagIncomplete {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0]).To4()
+		if ip == nil {
+			continue
+		}
+
+		mac, err := net.ParseMAC(fields[3])
+		if err != nil {
+			continue
+		}
+
+		neighbors = append(neighbors, provider.PodInfo{IP: ip, MAC: mac})
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return neighbors, nil
+}
+
+func normalizeMAC(mac string) string {
+	return strings.ToLower(strings.Replace(mac, "-", ":", -1))
+}
@@ -0,0 +1,202 @@
+// Package bpf implements a provider which passively observes the traffic on
+// the host bridge to learn the IP the guest VM actually uses. A packet socket
+// is bound to the bridge and a BPF socket filter is attached to it, so that
+// only IPv4 frames, optionally sent from the guest MAC, reach user space. The
+// source IP of the first matching frame inside the bridge subnet is the guest
+// address. No probes are sent.
+//
+// The provider requires CAP_NET_RAW and is only supported on Linux.
+package bpf
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"golang.org/x/net/bpf"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "bpf"
+)
+
+const (
+	// etherTypeIPv4 is the EtherType of IPv4 frames.
+	etherTypeIPv4 = 0x0800
+	// snapLength is the number of bytes of each frame passed to user space. It
+	// covers the Ethernet and IPv4 headers.
+	snapLength = 64
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Interface is the name of the host bridge the guest VM is attached to.
+	Interface string
+	// MAC optionally restricts observed frames to those sent by the guest VM.
+	MAC string
+	// Timeout is the maximum time a single lookup waits for guest traffic.
+	Timeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Interface: "",
+		MAC:       "",
+		Timeout:   30 * time.Second,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Interface == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Interface must not be empty")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+
+	var mac net.HardwareAddr
+	if config.MAC != "" {
+		var err error
+		mac, err = net.ParseMAC(config.MAC)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.MAC must be a valid MAC: %s", err.Error())
+		}
+		if len(mac) != 6 {
+			return nil, microerror.Maskf(invalidConfigError, "config.MAC must be a 48 bit MAC")
+		}
+	}
+
+	filter, err := bpf.Assemble(filterProgram(mac))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		filter: filter,
+
+		// Settings.
+		iface:   config.Interface,
+		timeout: config.Timeout,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	filter []bpf.RawInstruction
+
+	// Settings.
+	iface   string
+	timeout time.Duration
+}
+
+// Lookup waits for the first IPv4 frame on the bridge which originates from
+// an address inside the bridge subnet other than the bridge itself. The
+// source MAC of the frame is returned along with the IP.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	netInterface, err := net.InterfaceByName(p.iface)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	subnet, own, err := bridgeSubnet(netInterface)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "observing guest traffic", "interface", p.iface, "subnet", subnet.String())
+
+	ip, mac, err := p.observe(ctx, netInterface, func(ip net.IP) bool {
+		return subnet.Contains(ip) && !ip.Equal(own)
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return []provider.PodInfo{{IP: ip, MAC: mac}}, nil
+}
+
+// filterProgram returns the socket filter accepting IPv4 frames, optionally
+// only those sent from the given MAC.
+func filterProgram(mac net.HardwareAddr) []bpf.Instruction {
+	var program []bpf.Instruction
+
+	// Every failing check jumps to the final reject instruction. Jump offsets
+	// are relative, so they are computed once all checks are known.
+	checks := []bpf.Instruction{
+		bpf.LoadAbsolute{Off: 12, Size: 2},
+		bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: etherTypeIPv4},
+	}
+	if mac != nil {
+		checks = append(checks,
+			bpf.LoadAbsolute{Off: 6, Size: 4},
+			bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: uint32(mac[0])<<24 | uint32(mac[1])<<16 | uint32(mac[2])<<8 | uint32(mac[3])},
+			bpf.LoadAbsolute{Off: 10, Size: 2},
+			bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: uint32(mac[4])<<8 | uint32(mac[5])},
+		)
+	}
+
+	for i, c := range checks {
+		if j, ok := c.(bpf.JumpIf); ok {
+			// Skip the remaining checks and the accept instruction.
+			j.SkipTrue = uint8(len(checks) - i)
+			c = j
+		}
+		program = append(program, c)
+	}
+
+	program = append(program,
+		bpf.RetConstant{Val: snapLength},
+		bpf.RetConstant{Val: 0},
+	)
+
+	return program
+}
+
+func bridgeSubnet(netInterface *net.Interface) (*net.IPNet, net.IP, error) {
+	addrs, err := netInterface.Addrs()
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+
+		return ipNet, ipNet.IP.To4(), nil
+	}
+
+	return nil, nil, microerror.Maskf(notFoundError, "no IPv4 subnet found on interface %#q", netInterface.Name)
+}
@@ -0,0 +1,78 @@
+package bpf
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+func (p *Provider) observe(ctx context.Context, netInterface *net.Interface, accept func(ip net.IP) bool) (net.IP, net.HardwareAddr, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_IP)))
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	defer syscall.Close(fd)
+
+	var filter []syscall.SockFilter
+	for _, i := range p.filter {
+		filter = append(filter, syscall.SockFilter{Code: i.Op, Jt: i.Jt, Jf: i.Jf, K: i.K})
+	}
+	err = syscall.AttachLsf(fd, filter) // nolint:staticcheck
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_IP), Ifindex: netInterface.Index})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	// The receive timeout makes sure we periodically check the deadline and
+	// the context even when there is no traffic at all.
+	tv := syscall.NsecToTimeval(time.Second.Nanoseconds())
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	buf := make([]byte, snapLength)
+	deadline := time.Now().Add(p.timeout)
+
+	for time.Now().Before(deadline) {
+		if ctx.Err() != nil {
+			return nil, nil, microerror.Mask(ctx.Err())
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return nil, nil, microerror.Mask(err)
+		}
+
+		// The IPv4 source address follows the 14 byte Ethernet header at
+		// offset 12 of the IPv4 header.
+		if n < 14+20 {
+			continue
+		}
+		ip := net.IPv4(buf[26], buf[27], buf[28], buf[29]).To4()
+
+		if accept(ip) {
+			// The source MAC follows the destination MAC at offset 6 of the
+			// Ethernet header.
+			mac := make(net.HardwareAddr, 6)
+			copy(mac, buf[6:12])
+
+			return ip, mac, nil
+		}
+	}
+
+	return nil, nil, microerror.Maskf(notFoundError, "no guest traffic observed on interface %#q within %s", netInterface.Name, p.timeout)
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package bpf
+
+import (
+	"context"
+	"net"
+	"runtime"
+
+	"github.com/giantswarm/microerror"
+)
+
+func (p *Provider) observe(ctx context.Context, netInterface *net.Interface, accept func(ip net.IP) bool) (net.IP, net.HardwareAddr, error) {
+	return nil, nil, microerror.Maskf(unsupportedPlatformError, "provider %#q is not supported on %s", Kind, runtime.GOOS)
+}
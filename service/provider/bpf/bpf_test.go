@@ -0,0 +1,97 @@
+package bpf
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/bpf"
+)
+
+func Test_filterProgram(t *testing.T) {
+	guest := mustParseMAC(t, "52:54:00:12:34:56")
+	other := mustParseMAC(t, "52:54:00:12:34:57")
+
+	testCases := []struct {
+		name      string
+		mac       net.HardwareAddr
+		src       net.HardwareAddr
+		etherType uint16
+		expected  int
+	}{
+		{
+			name:      "case 0: IPv4 frame without MAC filter",
+			mac:       nil,
+			src:       other,
+			etherType: etherTypeIPv4,
+			expected:  snapLength,
+		},
+		{
+			name:      "case 1: ARP frame without MAC filter",
+			mac:       nil,
+			src:       other,
+			etherType: 0x0806,
+			expected:  0,
+		},
+		{
+			name:      "case 2: IPv4 frame from the guest",
+			mac:       guest,
+			src:       guest,
+			etherType: etherTypeIPv4,
+			expected:  snapLength,
+		},
+		{
+			name:      "case 3: IPv4 frame from another MAC",
+			mac:       guest,
+			src:       other,
+			etherType: etherTypeIPv4,
+			expected:  0,
+		},
+		{
+			name:      "case 4: IPv4 frame differing in the first MAC bytes",
+			mac:       guest,
+			src:       mustParseMAC(t, "02:54:00:12:34:56"),
+			etherType: etherTypeIPv4,
+			expected:  0,
+		},
+		{
+			name:      "case 5: ARP frame from the guest",
+			mac:       guest,
+			src:       guest,
+			etherType: 0x0806,
+			expected:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vm, err := bpf.NewVM(filterProgram(tc.mac))
+			if err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+
+			frame := make([]byte, 128)
+			copy(frame[6:12], tc.src)
+			frame[12] = byte(tc.etherType >> 8)
+			frame[13] = byte(tc.etherType)
+
+			n, err := vm.Run(frame)
+			if err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+			if n != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, n)
+			}
+		})
+	}
+}
+
+func mustParseMAC(t *testing.T, s string) net.HardwareAddr {
+	t.Helper()
+
+	mac, err := net.ParseMAC(s)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	return mac
+}
@@ -0,0 +1,24 @@
+package bpf
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = microerror.New("invalid config")
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var notFoundError = microerror.New("not found")
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var unsupportedPlatformError = microerror.New("unsupported platform")
+
+// IsUnsupportedPlatform asserts unsupportedPlatformError.
+func IsUnsupportedPlatform(err error) bool {
+	return microerror.Cause(err) == unsupportedPlatformError
+}
@@ -1,15 +1,27 @@
 package bridge
 
 import (
+	"context"
 	"errors"
+	"math/big"
 	"net"
+	"path"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
 )
 
 const (
 	Kind = "bridge"
+
+	// waitPollInterval is the time waited between lookups while waiting for
+	// the bridges to appear.
+	waitPollInterval = time.Second
 )
 
 // Config represents the configuration used to create a new provider.
@@ -19,9 +31,27 @@ type Config struct {
 
 	// Settings.
 
-	// BridgeName is the bridge name of the underlying host used to lookup the endpoint
-	// IP.
-	BridgeName string
+	// BridgeNames are the bridge names of the underlying host used to lookup
+	// the endpoint IPs. Names may be glob patterns like br-*, matching all
+	// bridges of the guest VMs running on the host.
+	BridgeNames []string
+	// BridgePattern is a regular expression bridge names have to match
+	// entirely, e.g. br-.*, as an alternative to templating the cluster IDs
+	// contained in bridge names into BridgeNames.
+	BridgePattern string
+	// Offset is added to the bridge IP to derive the guest VM IP. It may be
+	// negative for layouts where the guest VM IP precedes the bridge IP.
+	Offset int
+	// PreferredCIDR is the network, e.g. 10.0.0.0/8, the bridge IP is
+	// preferably chosen from in case the bridge has multiple addresses
+	// assigned. The first suitable address is used when none is inside the
+	// network or when empty.
+	PreferredCIDR string
+	// WaitTimeout is the maximum time a lookup waits for the bridges to appear
+	// and have an address assigned, e.g. when the updater starts before
+	// Flannel or libvirt created the bridge. The lookup fails immediately when
+	// zero.
+	WaitTimeout time.Duration
 }
 
 // DefaultConfig provides a default configuration to create a new provider
@@ -32,7 +62,11 @@ func DefaultConfig() Config {
 		Logger: nil,
 
 		// Settings.
-		BridgeName: "",
+		BridgeNames:   nil,
+		BridgePattern: "",
+		Offset:        1,
+		PreferredCIDR: "",
+		WaitTimeout:   0,
 	}
 }
 
@@ -44,8 +78,40 @@ func New(config Config) (*Provider, error) {
 	}
 
 	// Settings.
-	if config.BridgeName == "" {
-		return nil, microerror.Maskf(invalidConfigError, "config.BridgeName must not be empty")
+	if len(config.BridgeNames) == 0 && config.BridgePattern == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.BridgeNames or config.BridgePattern must not be empty")
+	}
+	for _, n := range config.BridgeNames {
+		if n == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.BridgeNames must not contain empty names")
+		}
+		_, err := path.Match(n, "")
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.BridgeNames must contain valid glob patterns, got %#q", n)
+		}
+	}
+	if config.Offset == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Offset must not be zero")
+	}
+	var preferredNet *net.IPNet
+	if config.PreferredCIDR != "" {
+		var err error
+		_, preferredNet, err = net.ParseCIDR(config.PreferredCIDR)
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.PreferredCIDR must be a CIDR, got %#q", config.PreferredCIDR)
+		}
+	}
+	if config.WaitTimeout < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.WaitTimeout must not be negative")
+	}
+
+	var bridgePattern *regexp.Regexp
+	if config.BridgePattern != "" {
+		var err error
+		bridgePattern, err = regexp.Compile("^(?:" + config.BridgePattern + ")$")
+		if err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.BridgePattern must be a valid regular expression: %s", err.Error())
+		}
 	}
 
 	newProvider := &Provider{
@@ -53,7 +119,12 @@ func New(config Config) (*Provider, error) {
 		logger: config.Logger,
 
 		// Settings.
-		bridgeName: config.BridgeName,
+		bridgeNames:         config.BridgeNames,
+		bridgePattern:       bridgePattern,
+		bridgePatternString: config.BridgePattern,
+		offset:              config.Offset,
+		preferredNet:        preferredNet,
+		waitTimeout:         config.WaitTimeout,
 	}
 
 	return newProvider, nil
@@ -64,20 +135,162 @@ type Provider struct {
 	logger micrologger.Logger
 
 	// Settings.
-	bridgeName string
+	bridgeNames         []string
+	bridgePattern       *regexp.Regexp
+	bridgePatternString string
+	offset              int
+	preferredNet        *net.IPNet
+	waitTimeout         time.Duration
 }
 
-func (p *Provider) Lookup() (net.IP, error) {
+// Lookup returns the guest VM IPs of all configured bridges. Bridges failing
+// the lookup are skipped as long as at least one bridge succeeds, so that a
+// single broken guest VM does not prevent publishing the others. In case a
+// wait timeout is configured, the lookup is retried until it succeeds or the
+// timeout expires.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	if p.waitTimeout == 0 {
+		pods, err := p.lookup()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return pods, nil
+	}
+
+	start := time.Now()
+	for {
+		pods, err := p.lookup()
+		if err == nil {
+			if time.Since(start) >= waitPollInterval {
+				_ = p.logger.Log("info", "bridge appeared", "bridge", p.selector(), "waited", time.Since(start).Round(time.Second).String())
+			}
+
+			return pods, nil
+		}
+
+		waited := time.Since(start)
+		if waited >= p.waitTimeout {
+			_ = p.logger.Log("warning", "giving up waiting for bridge", "bridge", p.selector(), "waited", waited.Round(time.Second).String(), "error", err.Error())
+			return nil, microerror.Mask(err)
+		}
+
+		_ = p.logger.Log("info", "waiting for bridge", "bridge", p.selector(), "waited", waited.Round(time.Second).String(), "timeout", p.waitTimeout.String(), "reason", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return nil, microerror.Mask(ctx.Err())
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// lookup looks up the configured bridges once.
+func (p *Provider) lookup() ([]provider.PodInfo, error) {
+	names, err := p.bridges()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(names) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no bridge matches %#q", p.selector())
+	}
+
+	var pods []provider.PodInfo
+	var lookupErr error
+	for _, name := range names {
+		bridgePods, err := p.lookupBridge(name)
+		if err != nil {
+			if len(names) > 1 {
+				_ = p.logger.Log("warning", "looking up bridge failed", "bridge", name, "error", err.Error())
+			}
+			if lookupErr == nil {
+				lookupErr = err
+			}
+			continue
+		}
+
+		pods = append(pods, bridgePods...)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Mask(lookupErr)
+	}
+
+	return pods, nil
+}
+
+// bridges returns the names of the bridges to look up. Names which are not
+// glob patterns are returned as they are, so that missing bridges are
+// reported by the lookup.
+func (p *Provider) bridges() ([]string, error) {
+	var names []string
+	var netInterfaces []net.Interface
+	if p.bridgePattern != nil {
+		var err error
+		netInterfaces, err = net.Interfaces()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, i := range netInterfaces {
+			if p.bridgePattern.MatchString(i.Name) {
+				names = append(names, i.Name)
+			}
+		}
+	}
+	for _, n := range p.bridgeNames {
+		if !strings.ContainsAny(n, "*?[") {
+			names = append(names, n)
+			continue
+		}
+
+		if netInterfaces == nil {
+			var err error
+			netInterfaces, err = net.Interfaces()
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+
+		for _, i := range netInterfaces {
+			// The patterns were validated in New already.
+			ok, _ := path.Match(n, i.Name)
+			if ok {
+				names = append(names, i.Name)
+			}
+		}
+	}
+
+	return uniqueNames(names), nil
+}
+
+// selector describes the configured bridges for logging.
+func (p *Provider) selector() string {
+	s := strings.Join(p.bridgeNames, ",")
+	if p.bridgePattern != nil {
+		if s != "" {
+			s += ","
+		}
+		s += p.bridgePatternString
+	}
+
+	return s
+}
+
+// lookupBridge returns the guest VM IPv4 and, in case the bridge has a global
+// IPv6 assigned, the guest VM IPv6. Flannel derives both the same way, so the
+// configured offset is added to the bridge IPv6 as well.
+func (p *Provider) lookupBridge(name string) ([]provider.PodInfo, error) {
 	// We fetch the interface first because it holds all IP addresses associated
 	// with it.
-	netInterface, err := net.InterfaceByName(p.bridgeName)
+	netInterface, err := net.InterfaceByName(name)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
 
 	// The interface addresses have to be parsed to find the actual IPV4 we are
 	// interested in.
-	ip, err := ipv4FromInterface(netInterface)
+	ipNet, err := ipv4FromInterface(netInterface, p.preferredNet)
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
@@ -90,51 +303,240 @@ func (p *Provider) Lookup() (net.IP, error) {
 	//     - The IP address after the IP address of the Flannel bridge is the IP
 	//       address of the guest cluster VM.
 	//
-	next := incrIPV4(ip)
+	// Installations with other address layouts configure another offset.
+	guestIP, err := p.guestIP(ipNet)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	pods := []provider.PodInfo{
+		{IP: guestIP},
+	}
+
+	ipv6, err := ipv6FromInterface(netInterface)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if ipv6 != nil {
+		guestIPv6, err := p.guestIP(ipv6)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pods = append(pods, provider.PodInfo{IP: guestIPv6})
+	} else {
+		_ = p.logger.Log("debug", "bridge has no global IPv6", "bridge", name)
+	}
 
-	return next, nil
+	return pods, nil
 }
 
-func incrIPV4(ip net.IP) net.IP {
-	c := net.ParseIP(ip.String())
+// Watch emits the looked up pods whenever the kernel reports changed links or
+// addresses and the looked up IPs differ from the previously emitted ones, e.g.
+// because the bridge got recreated with another subnet. The returned channel
+// is closed once the given context is cancelled or the subscription fails.
+func (p *Provider) Watch(ctx context.Context) <-chan []provider.PodInfo {
+	changes := make(chan []provider.PodInfo)
 
-	for j := len(c) - 1; j >= 0; j-- {
-		c[j]++
-		if c[j] > 0 {
-			break
+	go func() {
+		defer close(changes)
+
+		// The IPs at the time of subscribing are known to the caller already,
+		// so only deviations from them are emitted.
+		var last string
+		pods, err := p.lookup()
+		if err == nil {
+			last = joinIPs(pods)
 		}
+
+		err = p.subscribe(ctx, func() {
+			pods, err := p.lookup()
+			if err != nil {
+				_ = p.logger.Log("debug", "looking up changed bridge failed", "bridge", p.selector(), "error", err.Error())
+				return
+			}
+			if joinIPs(pods) == last {
+				return
+			}
+			last = joinIPs(pods)
+
+			select {
+			case changes <- pods:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			_ = p.logger.Log("warning", "watching bridge failed", "bridge", p.selector(), "error", err.Error())
+		}
+	}()
+
+	return changes
+}
+
+// guestIP derives the guest VM IP from the given bridge address by adding the
+// configured offset. The derived IP has to be a host address within the
+// subnet of the bridge, so that misconfigured offsets or unexpected subnets do
+// not result in publishing the network, broadcast or next subnet address.
+func (p *Provider) guestIP(bridgeNet *net.IPNet) (net.IP, error) {
+	ip, err := addIP(bridgeNet.IP, p.offset)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	subnet := &net.IPNet{IP: bridgeNet.IP.Mask(bridgeNet.Mask), Mask: bridgeNet.Mask}
+	if !subnet.Contains(ip) {
+		return nil, microerror.Maskf(executionFailedError, "guest VM IP %s derived from bridge IP %s with offset %d is outside of the bridge subnet %s", ip, bridgeNet.IP, p.offset, subnet)
 	}
 
-	return c
+	// Subnets with less than two host bits do not reserve network and
+	// broadcast addresses, see RFC 3021.
+	ones, bits := subnet.Mask.Size()
+	if bits-ones < 2 {
+		return ip, nil
+	}
+
+	if ip.Equal(subnet.IP) {
+		return nil, microerror.Maskf(executionFailedError, "guest VM IP %s derived from bridge IP %s with offset %d is the network address of the bridge subnet %s", ip, bridgeNet.IP, p.offset, subnet)
+	}
+	if ip.To4() != nil && ip.Equal(broadcastIP(subnet)) {
+		return nil, microerror.Maskf(executionFailedError, "guest VM IP %s derived from bridge IP %s with offset %d is the broadcast address of the bridge subnet %s", ip, bridgeNet.IP, p.offset, subnet)
+	}
+
+	return ip, nil
+}
+
+// addIP adds the given, possibly negative, offset to the given IP.
+func addIP(ip net.IP, offset int) (net.IP, error) {
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+
+	n := new(big.Int).SetBytes(ip)
+	n.Add(n, big.NewInt(int64(offset)))
+
+	b := n.Bytes()
+	if n.Sign() < 0 || len(b) > len(ip) {
+		return nil, microerror.Maskf(executionFailedError, "offset %d exceeds the address space of %s", offset, ip.String())
+	}
+
+	c := make(net.IP, len(ip))
+	copy(c[len(c)-len(b):], b)
+
+	return c, nil
 }
 
-func ipv4FromInterface(netInterface *net.Interface) (net.IP, error) {
+// ipv4FromInterface returns the first primary IPv4 of the given interface
+// along with the mask of its subnet. Link-local and loopback addresses are
+// ignored. In case a preferred network is given, the first address inside of
+// it wins over the others.
+func ipv4FromInterface(netInterface *net.Interface, preferredNet *net.IPNet) (*net.IPNet, error) {
 	addrs, err := netInterface.Addrs()
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
+
+	var candidates []*net.IPNet
 	for _, addr := range addrs {
-		var ip net.IP
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
 
-		switch v := addr.(type) {
-		case *net.IPNet:
-			ip = v.IP
-		case *net.IPAddr:
-			ip = v.IP
+		ipv4 := ipNet.IP.To4()
+		if ipv4 == nil {
+			// Not an ipv4 address.
+			continue
+		}
+		if ipv4.IsLinkLocalUnicast() || ipv4.IsLoopback() {
+			continue
 		}
 
-		if ip == nil {
+		mask := ipNet.Mask
+		if len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+
+		// The kernel lists primary addresses before the secondary addresses of
+		// their subnet, so addresses inside the subnet of a previous candidate
+		// are secondary ones.
+		secondary := false
+		for _, c := range candidates {
+			if c.Contains(ipv4) {
+				secondary = true
+				break
+			}
+		}
+		if secondary {
 			continue
 		}
 
-		ipv4 := ip.To4()
-		if ipv4 == nil {
-			// Not an ipv4 address.
+		candidates = append(candidates, &net.IPNet{IP: ipv4, Mask: mask})
+	}
+
+	if len(candidates) == 0 {
+		return nil, errors.New("IPV4 not found")
+	}
+
+	if preferredNet != nil {
+		for _, c := range candidates {
+			if preferredNet.Contains(c.IP) {
+				return c, nil
+			}
+		}
+	}
+
+	return candidates[0], nil
+}
+
+// ipv6FromInterface returns the first global unicast IPv6 of the given
+// interface along with the mask of its subnet, or nil in case there is none.
+func ipv6FromInterface(netInterface *net.Interface) (*net.IPNet, error) {
+	addrs, err := netInterface.Addrs()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() != nil || !ipNet.IP.IsGlobalUnicast() {
 			continue
 		}
 
-		return ipv4, nil
+		return ipNet, nil
+	}
+
+	return nil, nil
+}
+
+func broadcastIP(subnet *net.IPNet) net.IP {
+	ip := make(net.IP, len(subnet.IP))
+	for i := range ip {
+		ip[i] = subnet.IP[i] | ^subnet.Mask[i]
+	}
+
+	return ip
+}
+
+func uniqueNames(names []string) []string {
+	seen := map[string]bool{}
+
+	var unique []string
+	for _, n := range names {
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		unique = append(unique, n)
+	}
+
+	return unique
+}
+
+func joinIPs(pods []provider.PodInfo) string {
+	var ips []string
+	for _, ip := range provider.IPs(pods) {
+		ips = append(ips, ip.String())
 	}
 
-	return nil, errors.New("IPV4 not found")
+	return strings.Join(ips, ",")
 }
@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// Multicast groups of rtnetlink as defined in linux/rtnetlink.h, which the
+// syscall package does not provide.
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPV4IfAddr = 0x10
+	rtmgrpIPV6IfAddr = 0x100
+)
+
+// subscribe calls notify whenever the kernel reports a changed link or
+// address via rtnetlink, until the given context is cancelled. Messages are
+// not filtered by interface, since the bridge might be recreated with a new
+// index. Callers compare the looked up IPs instead.
+func (p *Provider) subscribe(ctx context.Context, notify func()) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer syscall.Close(fd)
+
+	groups := uint32(rtmgrpLink | rtmgrpIPV4IfAddr | rtmgrpIPV6IfAddr)
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// The receive timeout makes sure we periodically check the context even
+	// when there are no events at all.
+	tv := syscall.NsecToTimeval(time.Second.Nanoseconds())
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		} else if err == syscall.ENOBUFS {
+			// Events got dropped because we did not keep up, so we cannot
+			// tell what changed and have to look again.
+			notify()
+			continue
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		var changed bool
+		for _, m := range msgs {
+			switch m.Header.Type {
+			case syscall.RTM_NEWLINK, syscall.RTM_DELLINK, syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+				changed = true
+			}
+		}
+
+		if changed {
+			notify()
+		}
+	}
+}
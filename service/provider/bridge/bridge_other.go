@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package bridge
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/giantswarm/microerror"
+)
+
+func (p *Provider) subscribe(ctx context.Context, notify func()) error {
+	return microerror.Maskf(unsupportedPlatformError, "watching provider %#q is not supported on %s", Kind, runtime.GOOS)
+}
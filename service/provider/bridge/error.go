@@ -2,9 +2,30 @@ package bridge
 
 import "github.com/giantswarm/microerror"
 
+var executionFailedError = microerror.New("execution failed")
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
 var invalidConfigError = microerror.New("invalid config")
 
 // IsInvalidConfig asserts invalidConfigError.
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var notFoundError = microerror.New("not found")
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
+
+var unsupportedPlatformError = microerror.New("unsupported platform")
+
+// IsUnsupportedPlatform asserts unsupportedPlatformError.
+func IsUnsupportedPlatform(err error) bool {
+	return microerror.Cause(err) == unsupportedPlatformError
+}
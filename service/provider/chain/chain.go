@@ -0,0 +1,125 @@
+// Package chain implements a provider which tries an ordered list of
+// providers until one of them finds the endpoint IP. This allows to migrate
+// between discovery mechanisms, e.g. from the flannel bridge to etcd, without
+// a window in which no provider is able to resolve the IP.
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Mode = "fallback"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+	// Providers are tried in the given order.
+	Providers []provider.Provider
+
+	// Settings.
+
+	// Kinds are the kinds of the providers, in the same order. They are only
+	// used for logging and error messages.
+	Kinds []string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger:    nil,
+		Providers: nil,
+
+		// Settings.
+		Kinds: nil,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if len(config.Providers) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Providers must not be empty")
+	}
+
+	// Settings.
+	if len(config.Kinds) != len(config.Providers) {
+		return nil, microerror.Maskf(invalidConfigError, "config.Kinds must have the same length as config.Providers")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger:    config.Logger,
+		providers: config.Providers,
+
+		// Settings.
+		kinds: config.Kinds,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger    micrologger.Logger
+	providers []provider.Provider
+
+	// Settings.
+	kinds []string
+}
+
+// Lookup returns the pod info of the first provider which succeeds and finds
+// at least one pod. An executionFailedError listing the failure of every
+// provider is returned in case none of them does.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	var failures []string
+	for i, c := range p.providers {
+		if ctx.Err() != nil {
+			return nil, microerror.Mask(ctx.Err())
+		}
+
+		pods, err := c.Lookup(ctx)
+		if err != nil {
+			_ = p.logger.Log("debug", "provider lookup failed, trying next provider", "kind", p.kinds[i], "reason", err.Error())
+			failures = append(failures, fmt.Sprintf("%s: %s", p.kinds[i], err.Error()))
+			continue
+		}
+		if len(pods) == 0 {
+			_ = p.logger.Log("debug", "provider found nothing, trying next provider", "kind", p.kinds[i])
+			failures = append(failures, fmt.Sprintf("%s: nothing found", p.kinds[i]))
+			continue
+		}
+
+		if i != 0 {
+			_ = p.logger.Log("info", "fell back to provider", "kind", p.kinds[i])
+		}
+
+		return pods, nil
+	}
+
+	return nil, microerror.Maskf(executionFailedError, "all providers failed: %s", strings.Join(failures, "; "))
+}
+
+// Close closes the chained providers holding connections.
+func (p *Provider) Close() error {
+	err := provider.Close(p.providers...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
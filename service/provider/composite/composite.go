@@ -0,0 +1,172 @@
+// Package composite implements a provider which runs multiple providers
+// concurrently and merges their results. This allows to publish locally
+// discovered VM IPs along with IPs registered in etcd by other nodes.
+package composite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Mode = "composite"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+	// Providers are looked up concurrently. Their results are merged in the
+	// given order.
+	Providers []provider.Provider
+
+	// Settings.
+
+	// Kinds are the kinds of the providers, in the same order. They are only
+	// used for logging and error messages.
+	Kinds []string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger:    nil,
+		Providers: nil,
+
+		// Settings.
+		Kinds: nil,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if len(config.Providers) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Providers must not be empty")
+	}
+
+	// Settings.
+	if len(config.Kinds) != len(config.Providers) {
+		return nil, microerror.Maskf(invalidConfigError, "config.Kinds must have the same length as config.Providers")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger:    config.Logger,
+		providers: config.Providers,
+
+		// Settings.
+		kinds: config.Kinds,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger    micrologger.Logger
+	providers []provider.Provider
+
+	// Settings.
+	kinds []string
+}
+
+// Lookup runs all providers concurrently and returns the union of the pod
+// info they found, deduplicated by IP. Pod info found for the same IP by
+// multiple providers is merged, the earlier provider taking precedence.
+// Failing providers are skipped. An executionFailedError listing the failure
+// of every provider is returned in case none of them finds anything.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	results := make([][]provider.PodInfo, len(p.providers))
+	errs := make([]error, len(p.providers))
+
+	var wg sync.WaitGroup
+	for i, c := range p.providers {
+		wg.Add(1)
+		go func(i int, c provider.Provider) {
+			defer wg.Done()
+			results[i], errs[i] = c.Lookup(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, microerror.Mask(ctx.Err())
+	}
+
+	var merged []provider.PodInfo
+	var failures []string
+	for i := range p.providers {
+		if errs[i] != nil {
+			_ = p.logger.Log("warning", "provider lookup failed, skipping provider", "kind", p.kinds[i], "reason", errs[i].Error())
+			failures = append(failures, fmt.Sprintf("%s: %s", p.kinds[i], errs[i].Error()))
+			continue
+		}
+
+		for _, pod := range results[i] {
+			merged = merge(merged, pod)
+		}
+	}
+
+	if len(merged) == 0 {
+		if len(failures) == 0 {
+			failures = append(failures, "nothing found")
+		}
+		return nil, microerror.Maskf(executionFailedError, "all providers failed: %s", strings.Join(failures, "; "))
+	}
+
+	return merged, nil
+}
+
+// merge adds the given pod info to the given list, unless its IP is already
+// listed. In that case only the fields missing in the listed pod info are
+// filled in.
+func merge(pods []provider.PodInfo, pod provider.PodInfo) []provider.PodInfo {
+	for i, p := range pods {
+		if !p.IP.Equal(pod.IP) {
+			continue
+		}
+
+		if p.MAC == nil {
+			pods[i].MAC = pod.MAC
+		}
+		if p.Name == "" {
+			pods[i].Name = pod.Name
+		}
+		if p.Namespace == "" {
+			pods[i].Namespace = pod.Namespace
+		}
+		if p.NodeName == "" {
+			pods[i].NodeName = pod.NodeName
+		}
+		if p.UID == "" {
+			pods[i].UID = pod.UID
+		}
+
+		return pods
+	}
+
+	return append(pods, pod)
+}
+
+// Close closes the combined providers holding connections.
+func (p *Provider) Close() error {
+	err := provider.Close(p.providers...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+// Package conntrack implements a provider which inspects the conntrack table
+// of the host to find the IP the guest VM actively uses. Flows tracked by the
+// host are visible even when the guest firewall drops ICMP and port probes, so
+// the table can also be used to confirm addresses found by other providers.
+package conntrack
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "conntrack"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Interface is the name of the host bridge the guest VM is attached to.
+	// Its subnet limits which flows are considered. It is only required for
+	// lookups, not for confirmations.
+	Interface string
+	// Path is the conntrack table exposed by the kernel.
+	Path string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Interface: "",
+		Path:      "/proc/net/nf_conntrack",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Path must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		iface: config.Interface,
+		path:  config.Path,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	iface string
+	path  string
+}
+
+// Lookup returns the IP inside the bridge subnet which takes part in the most
+// tracked flows, excluding the bridge IP itself.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	if p.iface == "" {
+		return nil, microerror.Maskf(invalidConfigError, "interface must not be empty for lookups")
+	}
+
+	netInterface, err := net.InterfaceByName(p.iface)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	subnet, own, err := interfaceSubnet(netInterface)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	flows, err := p.flows()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var best string
+	var bestCount int
+	for s, count := range flows {
+		ip := net.ParseIP(s)
+		if !subnet.Contains(ip) || ip.Equal(own) {
+			continue
+		}
+		// Ties are broken by the string representation to keep lookups
+		// deterministic.
+		if count > bestCount || (count == bestCount && s < best) {
+			best = s
+			bestCount = count
+		}
+	}
+
+	if best == "" {
+		return nil, microerror.Maskf(notFoundError, "no flows found from subnet %s", subnet.String())
+	}
+
+	_ = p.logger.Log("debug", "found guest flows in conntrack table", "ip", best, "flows", bestCount)
+
+	return []provider.PodInfo{{IP: net.ParseIP(best).To4()}}, nil
+}
+
+// Confirm checks whether the given IP takes part in any flow tracked by the
+// host. A notFoundError is returned in case it does not.
+func (p *Provider) Confirm(ip net.IP) error {
+	flows, err := p.flows()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if flows[ip.String()] == 0 {
+		return microerror.Maskf(notFoundError, "no flows found of %s", ip.String())
+	}
+
+	_ = p.logger.Log("debug", "confirmed address via conntrack table", "ip", ip.String(), "flows", flows[ip.String()])
+
+	return nil
+}
+
+// flows returns the number of tracked IPv4 flows per IP taking part in them.
+// Guests only accepting connections, e.g. the guest API server, never show up
+// as source of the original direction, so the destination of the original
+// direction and the source of the reply direction are counted as well. Each
+// flow is counted once per IP.
+func (p *Provider) flows() (map[string]int, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer file.Close()
+
+	flows := map[string]int{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "ipv4" {
+			continue
+		}
+
+		// The first src and dst fields belong to the original direction, the
+		// second ones to the reply direction. The reply destination is not
+		// counted, since it only differs from the original source for NATed
+		// flows, in which case it is an address of the host.
+		var ips []string
+		var srcs, dsts int
+		for _, field := range fields {
+			switch {
+			case strings.HasPrefix(field, "src=") && srcs < 2:
+				srcs++
+				ips = appendUnique(ips, strings.TrimPrefix(field, "src="))
+			case strings.HasPrefix(field, "dst=") && dsts < 1:
+				dsts++
+				ips = appendUnique(ips, strings.TrimPrefix(field, "dst="))
+			}
+		}
+		for _, ip := range ips {
+			flows[ip]++
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return flows, nil
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
+
+func interfaceSubnet(netInterface *net.Interface) (*net.IPNet, net.IP, error) {
+	addrs, err := netInterface.Addrs()
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+
+		return ipNet, ipNet.IP.To4(), nil
+	}
+
+	return nil, nil, microerror.Maskf(notFoundError, "no IPv4 subnet found on interface %#q", netInterface.Name)
+}
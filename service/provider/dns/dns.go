@@ -0,0 +1,173 @@
+// Package dns implements a provider which resolves hostnames to endpoint IPs.
+// This is useful when guest VMs register themselves in DNS but not in
+// Kubernetes. The hostnames are either given explicitly or taken from the
+// targets of a SRV record.
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "dns"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Hostnames are resolved to their A and AAAA records.
+	Hostnames []string
+	// Server is the address of the DNS server queried, e.g. 10.0.0.53:53. The
+	// system resolver is used when empty.
+	Server string
+	// SRV is the name of a SRV record whose targets are resolved in addition
+	// to the hostnames, e.g. _https._tcp.master.abc12.example.com.
+	SRV string
+	// Timeout is the maximum duration of a single lookup.
+	Timeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Hostnames: nil,
+		Server:    "",
+		SRV:       "",
+		Timeout:   5 * time.Second,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if len(config.Hostnames) == 0 && config.SRV == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Hostnames or config.SRV must not be empty")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+
+	resolver := net.DefaultResolver
+	if config.Server != "" {
+		server := config.Server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		resolver: resolver,
+
+		// Settings.
+		hostnames: config.Hostnames,
+		srv:       config.SRV,
+		timeout:   config.Timeout,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	resolver *net.Resolver
+
+	// Settings.
+	hostnames []string
+	srv       string
+	timeout   time.Duration
+}
+
+// Lookup resolves the configured hostnames and the targets of the configured
+// SRV record. The IPv4 addresses are returned first. Hostnames which do not
+// resolve are skipped, unless none of them does.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	hostnames := p.hostnames
+	if p.srv != "" {
+		_, records, err := p.resolver.LookupSRV(ctx, "", "", p.srv)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, r := range records {
+			hostnames = append(hostnames, r.Target)
+		}
+	}
+
+	var ipv4 []provider.PodInfo
+	var ipv6 []provider.PodInfo
+	seen := map[string]bool{}
+	for _, h := range hostnames {
+		addrs, err := p.resolver.LookupIPAddr(ctx, h)
+		if err != nil {
+			_ = p.logger.Log("warning", "resolving hostname failed", "hostname", h, "reason", err.Error())
+			continue
+		}
+
+		for _, a := range addrs {
+			if seen[a.IP.String()] {
+				continue
+			}
+			seen[a.IP.String()] = true
+
+			pod := provider.PodInfo{
+				IP:   a.IP,
+				Name: strings.TrimSuffix(h, "."),
+			}
+			if a.IP.To4() != nil {
+				pod.IP = a.IP.To4()
+				ipv4 = append(ipv4, pod)
+			} else {
+				ipv6 = append(ipv6, pod)
+			}
+		}
+	}
+
+	pods := append(ipv4, ipv6...)
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no addresses found for hostnames %#q", strings.Join(hostnames, ","))
+	}
+
+	_ = p.logger.Log("debug", "resolved endpoint IPs", "hostnames", strings.Join(hostnames, ","), "ips", len(pods))
+
+	return pods, nil
+}
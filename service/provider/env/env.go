@@ -0,0 +1,241 @@
+// Package env implements a provider reading the pods from indexed environment
+// variables, so that a single updater instance is able to publish the
+// endpoints of all members of e.g. a StatefulSet. With the default prefix the
+// variables look like
+//
+//	K8S_ENDPOINT_UPDATER_POD_0_NAME=master-0
+//	K8S_ENDPOINT_UPDATER_POD_0_IP=10.0.0.1
+//	K8S_ENDPOINT_UPDATER_POD_1_NAME=master-1
+//	K8S_ENDPOINT_UPDATER_POD_1_IP=10.0.0.2
+//
+// Only the IP is required. The namespace, node name and MAC can be given via
+// the _NAMESPACE, _NODE_NAME and _MAC suffixes. Alternatively a single variable
+// holds a JSON array of pods, which is easier to template, e.g.
+//
+//	K8S_ENDPOINT_UPDATER_PODS=[{"name": "master-0", "ip": "10.0.0.1"}]
+package env
+
+import (
+	"context"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "env"
+)
+
+const (
+	FormatIndexed = "indexed"
+	FormatJSON    = "json"
+)
+
+const (
+	suffixIP        = "IP"
+	suffixMAC       = "MAC"
+	suffixName      = "NAME"
+	suffixNamespace = "NAMESPACE"
+	suffixNodeName  = "NODE_NAME"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Format defines how the pods are given. Either FormatIndexed or
+	// FormatJSON.
+	Format string
+	// Prefix is the prefix of the environment variables, which is followed by
+	// the index of the pod and the suffix of the field, e.g. _0_IP. Only used
+	// with FormatIndexed.
+	Prefix string
+	// Variable is the environment variable holding the JSON array of pods.
+	// Only used with FormatJSON.
+	Variable string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Format:   FormatIndexed,
+		Prefix:   "K8S_ENDPOINT_UPDATER_POD_",
+		Variable: "K8S_ENDPOINT_UPDATER_PODS",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	switch config.Format {
+	case FormatIndexed:
+		if config.Prefix == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.Prefix must not be empty")
+		}
+	case FormatJSON:
+		if config.Variable == "" {
+			return nil, microerror.Maskf(invalidConfigError, "config.Variable must not be empty")
+		}
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Format must be %#q or %#q", FormatIndexed, FormatJSON)
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		format:   config.Format,
+		prefix:   config.Prefix,
+		variable: config.Variable,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	format   string
+	prefix   string
+	variable string
+}
+
+// Lookup returns the pods given by the environment variables in the order
+// they are given.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	if p.format == FormatJSON {
+		pods, err := p.lookupJSON()
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return pods, nil
+	}
+
+	pods, err := p.lookupIndexed()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return pods, nil
+}
+
+func (p *Provider) lookupJSON() ([]provider.PodInfo, error) {
+	v := os.Getenv(p.variable)
+	if v == "" {
+		return nil, microerror.Maskf(notFoundError, "environment variable %#q is empty", p.variable)
+	}
+
+	pods, err := provider.UnmarshalPods([]byte(v))
+	if err != nil {
+		return nil, microerror.Maskf(invalidPodError, "environment variable %#q: %s", p.variable, err.Error())
+	}
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "environment variable %#q holds no pods", p.variable)
+	}
+
+	_ = p.logger.Log("debug", "read pods from environment", "variable", p.variable, "pods", len(pods))
+
+	return pods, nil
+}
+
+// lookupIndexed returns the pods given by the indexed environment variables,
+// ordered by their index.
+func (p *Provider) lookupIndexed() ([]provider.PodInfo, error) {
+	fields := map[int]map[string]string{}
+	for _, e := range os.Environ() {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], p.prefix) {
+			continue
+		}
+
+		// The remainder is the index and the suffix, e.g. 0_NODE_NAME.
+		s := strings.SplitN(strings.TrimPrefix(kv[0], p.prefix), "_", 2)
+		if len(s) != 2 {
+			continue
+		}
+		i, err := strconv.Atoi(s[0])
+		if err != nil || i < 0 {
+			continue
+		}
+
+		if fields[i] == nil {
+			fields[i] = map[string]string{}
+		}
+		fields[i][s[1]] = kv[1]
+	}
+
+	if len(fields) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no environment variable with prefix %#q found", p.prefix)
+	}
+
+	var indexes []int
+	for i := range fields {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	var pods []provider.PodInfo
+	for _, i := range indexes {
+		pod, err := p.podInfo(i, fields[i])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pods = append(pods, pod)
+	}
+
+	_ = p.logger.Log("debug", "read pods from environment", "prefix", p.prefix, "pods", len(pods))
+
+	return pods, nil
+}
+
+func (p *Provider) podInfo(i int, fields map[string]string) (provider.PodInfo, error) {
+	ip := net.ParseIP(fields[suffixIP])
+	if ip == nil {
+		return provider.PodInfo{}, microerror.Maskf(invalidPodError, "%s%d_%s must be a valid IP, got %#q", p.prefix, i, suffixIP, fields[suffixIP])
+	}
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+
+	pod := provider.PodInfo{
+		IP:        ip,
+		Name:      fields[suffixName],
+		Namespace: fields[suffixNamespace],
+		NodeName:  fields[suffixNodeName],
+	}
+
+	if fields[suffixMAC] != "" {
+		mac, err := net.ParseMAC(fields[suffixMAC])
+		if err != nil {
+			return provider.PodInfo{}, microerror.Maskf(invalidPodError, "%s%d_%s must be a valid MAC, got %#q", p.prefix, i, suffixMAC, fields[suffixMAC])
+		}
+		pod.MAC = mac
+	}
+
+	return pod, nil
+}
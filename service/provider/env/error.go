@@ -0,0 +1,24 @@
+package env
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = microerror.New("invalid config")
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var invalidPodError = microerror.New("invalid pod")
+
+// IsInvalidPod asserts invalidPodError.
+func IsInvalidPod(err error) bool {
+	return microerror.Cause(err) == invalidPodError
+}
+
+var notFoundError = microerror.New("not found")
+
+// IsNotFound asserts notFoundError.
+func IsNotFound(err error) bool {
+	return microerror.Cause(err) == notFoundError
+}
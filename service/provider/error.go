@@ -0,0 +1,10 @@
+package provider
+
+import "github.com/giantswarm/microerror"
+
+var invalidPodError = microerror.New("invalid pod")
+
+// IsInvalidPod asserts invalidPodError.
+func IsInvalidPod(err error) bool {
+	return microerror.Cause(err) == invalidPodError
+}
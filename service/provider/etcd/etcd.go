@@ -0,0 +1,330 @@
+// Package etcd implements a provider which reads the endpoint IP from etcd.
+// The IP of a pod is expected as plain value of the key named after the pod
+// below the configured prefix, e.g. /giantswarm/endpoints/<pod>. Only the
+// etcd v3 API is supported.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "etcd"
+)
+
+const (
+	// KindV3 selects the etcd v3 API.
+	KindV3 = "etcdv3"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Address is a comma separated list of etcd endpoints.
+	Address string
+	// CaFile is the certificate authority file used to verify etcd.
+	CaFile string
+	// CrtFile is the client certificate file used to authenticate with etcd.
+	CrtFile string
+	// KeyFile is the client key file used to authenticate with etcd.
+	KeyFile string
+	// Kind is the etcd API version to use. Only KindV3 is supported.
+	Kind string
+	// PodName is the name of the pod whose IP is looked up.
+	PodName string
+	// Prefix is the key prefix below which the pod IPs are stored.
+	Prefix string
+	// Timeout is the maximum duration of dialing and single requests.
+	Timeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Address: "",
+		CaFile:  "",
+		CrtFile: "",
+		KeyFile: "",
+		Kind:    KindV3,
+		PodName: "",
+		Prefix:  "",
+		Timeout: 5 * time.Second,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Address == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Address must not be empty")
+	}
+	if config.Kind != KindV3 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Kind must be %#q", KindV3)
+	}
+	if config.PodName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.PodName must not be empty")
+	}
+	if config.Prefix == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Prefix must not be empty")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+
+	tlsConfig, err := newTLSConfig(config.CaFile, config.CrtFile, config.KeyFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	etcdClient, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(config.Address, ","),
+		DialTimeout: config.Timeout,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		etcdClient: etcdClient,
+
+		// Settings.
+		key:     strings.TrimSuffix(config.Prefix, "/") + "/" + config.PodName,
+		podName: config.PodName,
+		timeout: config.Timeout,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	etcdClient *clientv3.Client
+
+	// Settings.
+	key     string
+	podName string
+	timeout time.Duration
+}
+
+// Lookup returns the IP stored in the key of the configured pod.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	res, err := p.get(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(res.Kvs) == 0 {
+		return nil, microerror.Maskf(notFoundError, "key %#q not found", p.key)
+	}
+
+	ip, err := parseIP(res.Kvs[0].Value)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "found endpoint IP in etcd", "key", p.key, "ip", ip.String())
+
+	return []provider.PodInfo{p.podInfo(ip)}, nil
+}
+
+// Watch watches the key of the configured pod and emits the new pod info
+// whenever it is changed. The watch is re-established when etcd closes it,
+// until the given context is cancelled. It resumes after the last revision
+// observed, so that no change made in between is lost. In case that revision
+// got compacted already, the key is read again and its current value emitted.
+func (p *Provider) Watch(ctx context.Context) <-chan []provider.PodInfo {
+	changes := make(chan []provider.PodInfo)
+
+	emit := func(value []byte) bool {
+		ip, err := parseIP(value)
+		if err != nil {
+			_ = p.logger.Log("warning", "etcd key holds invalid IP", "key", p.key, "value", string(value))
+			return true
+		}
+
+		select {
+		case changes <- []provider.PodInfo{p.podInfo(ip)}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	wait := func() bool {
+		select {
+		case <-time.After(p.timeout):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(changes)
+
+		var revision int64
+		var compacted bool
+		for {
+			if compacted {
+				res, err := p.get(ctx)
+				if err != nil {
+					_ = p.logger.Log("warning", "reading etcd key failed", "key", p.key, "error", err.Error())
+					if !wait() {
+						return
+					}
+					continue
+				}
+
+				compacted = false
+				revision = res.Header.Revision
+				if len(res.Kvs) != 0 && !emit(res.Kvs[0].Value) {
+					return
+				}
+			}
+
+			var options []clientv3.OpOption
+			if revision != 0 {
+				options = append(options, clientv3.WithRev(revision+1))
+			}
+
+			watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+			for res := range p.etcdClient.Watch(watchCtx, p.key, options...) {
+				if res.CompactRevision != 0 {
+					_ = p.logger.Log("warning", "etcd revision got compacted while watching, reading key again", "key", p.key, "revision", revision+1)
+					compacted = true
+					break
+				}
+
+				err := res.Err()
+				if err != nil {
+					_ = p.logger.Log("warning", "watching etcd key failed", "key", p.key, "error", err.Error())
+					continue
+				}
+
+				revision = res.Header.Revision
+
+				for _, event := range res.Events {
+					if event.Type != clientv3.EventTypePut {
+						continue
+					}
+					if !emit(event.Kv.Value) {
+						cancel()
+						return
+					}
+				}
+			}
+			cancel()
+
+			if !wait() {
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+// Close closes the connection to etcd.
+func (p *Provider) Close() error {
+	err := p.etcdClient.Close()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+func (p *Provider) get(ctx context.Context) (*clientv3.GetResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	res, err := p.etcdClient.Get(ctx, p.key)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return res, nil
+}
+
+func (p *Provider) podInfo(ip net.IP) provider.PodInfo {
+	return provider.PodInfo{
+		IP:   ip,
+		Name: p.podName,
+	}
+}
+
+func parseIP(b []byte) (net.IP, error) {
+	ip := net.ParseIP(strings.TrimSpace(string(b)))
+	if ip == nil {
+		return nil, microerror.Maskf(executionFailedError, "invalid IP %#q", string(b))
+	}
+
+	return ip, nil
+}
+
+// newTLSConfig creates the TLS config used to connect to etcd. It is nil in
+// case no files are given, in which case plain connections are used.
+func newTLSConfig(caFile, crtFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && crtFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if caFile != "" {
+		b, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, microerror.Maskf(invalidConfigError, "no certificates found in %#q", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if crtFile != "" || keyFile != "" {
+		crt, err := tls.LoadX509KeyPair(crtFile, keyFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{crt}
+	}
+
+	return tlsConfig, nil
+}
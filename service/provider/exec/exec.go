@@ -0,0 +1,162 @@
+// Package exec implements a provider which runs an external program and
+// parses its output, so that site-specific discovery logic can be plugged in
+// without forking the updater. The program prints either one IP per line or a
+// JSON array of pods, e.g. [{"name": "master-abc12", "ip": "10.0.0.1"}].
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "exec"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Args are the arguments passed to the command.
+	Args []string
+	// Command is the program which is run for every lookup. It is looked up
+	// in PATH unless it contains a path separator.
+	Command string
+	// Timeout is the maximum duration of a single run of the command.
+	Timeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Args:    nil,
+		Command: "",
+		Timeout: 10 * time.Second,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Command == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Command must not be empty")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		args:    config.Args,
+		command: config.Command,
+		timeout: config.Timeout,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	args    []string
+	command string
+	timeout time.Duration
+}
+
+// Lookup runs the configured command and returns the pods it printed to
+// stdout. The command failing or printing nothing results in an error.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "running %#q failed: %s: %s", p.command, err.Error(), strings.TrimSpace(stderr.String()))
+	}
+
+	pods, err := parse(stdout.Bytes())
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "%#q printed no IPs", p.command)
+	}
+
+	_ = p.logger.Log("debug", "found endpoint IPs using command", "command", p.command, "ips", len(pods))
+
+	return pods, nil
+}
+
+// parse parses the given output as JSON array of pods in case it starts with
+// a bracket and as one IP per line otherwise.
+func parse(b []byte) ([]provider.PodInfo, error) {
+	b = bytes.TrimSpace(b)
+
+	if bytes.HasPrefix(b, []byte("[")) {
+		pods, err := provider.UnmarshalPods(b)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		return pods, nil
+	}
+
+	var pods []provider.PodInfo
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		ip := net.ParseIP(line)
+		if ip == nil {
+			return nil, microerror.Maskf(executionFailedError, "invalid IP %#q", line)
+		}
+
+		pods = append(pods, provider.PodInfo{IP: ip})
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return pods, nil
+}
@@ -0,0 +1,179 @@
+// Package fdb implements a provider which resolves the guest VM IP from the
+// MAC the Linux bridge learned on the tap device of the guest VM. The
+// forwarding database of the bridge maps the tap device to the guest VM MAC,
+// the host neighbor table maps the MAC to the guest VM IP. This reflects the
+// actual guest VM instead of deriving its IP from the bridge IP.
+package fdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider/arp"
+)
+
+const (
+	Kind = "fdb"
+)
+
+const (
+	// entrySize is the size of struct __fdb_entry as read from the brforward
+	// sysfs attribute of a bridge.
+	entrySize = 16
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// ARPPath is the IPv4 neighbor table exposed by the kernel.
+	ARPPath string
+	// Bridge is the name of the bridge the tap device is attached to.
+	Bridge string
+	// Device is the name of the tap device of the guest VM.
+	Device string
+	// SysPath is the sysfs directory holding the network interfaces.
+	SysPath string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		ARPPath: "/proc/net/arp",
+		Bridge:  "",
+		Device:  "",
+		SysPath: "/sys/class/net",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.ARPPath == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.ARPPath must not be empty")
+	}
+	if config.Bridge == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Bridge must not be empty")
+	}
+	if config.Device == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Device must not be empty")
+	}
+	if config.SysPath == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.SysPath must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		arpPath: config.ARPPath,
+		bridge:  config.Bridge,
+		device:  config.Device,
+		sysPath: config.SysPath,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	arpPath string
+	bridge  string
+	device  string
+	sysPath string
+}
+
+// Lookup returns the neighbor entries of the bridge whose MACs the bridge
+// learned on the configured tap device.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	macs, err := p.learnedMACs()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(macs) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no MAC learned on device %#q", p.device)
+	}
+
+	neighbors, err := arp.Neighbors(p.arpPath, p.bridge)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var pods []provider.PodInfo
+	for _, n := range neighbors {
+		if !macs[n.MAC.String()] {
+			continue
+		}
+
+		_ = p.logger.Log("debug", "found neighbor for learned MAC", "device", p.device, "ip", n.IP.String(), "mac", n.MAC.String())
+
+		pods = append(pods, n)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no neighbor found for the MACs learned on device %#q", p.device)
+	}
+
+	return pods, nil
+}
+
+// learnedMACs returns the MACs the bridge learned on the bridge port of the
+// tap device. Local entries are skipped since they belong to the tap device
+// itself.
+func (p *Provider) learnedMACs() (map[string]bool, error) {
+	b, err := ioutil.ReadFile(filepath.Join(p.sysPath, p.device, "brport", "port_no"))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	port, err := strconv.ParseUint(strings.TrimSpace(string(b)), 0, 16)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "invalid port number %#q of device %#q", strings.TrimSpace(string(b)), p.device)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(p.sysPath, p.bridge, "brforward"))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	macs := map[string]bool{}
+	for i := 0; i+entrySize <= len(b); i += entrySize {
+		// struct __fdb_entry { mac_addr[6], port_no, is_local,
+		// ageing_timer_value u32, port_hi, pad0, unused u16 }
+		e := b[i : i+entrySize]
+		if e[7] != 0 {
+			continue
+		}
+		if uint64(e[6])|uint64(e[12])<<8 != port {
+			continue
+		}
+
+		macs[net.HardwareAddr(e[0:6]).String()] = true
+	}
+
+	return macs, nil
+}
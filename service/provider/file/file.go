@@ -0,0 +1,163 @@
+// Package file implements a provider which reads the pods from a JSON or YAML
+// file, so that other agents on the host are able to feed the updater by
+// simply writing a file. The file holds an array of pods, e.g.
+// [{"name": "master-abc12", "ip": "10.0.0.1"}]. Changes of the file can
+// optionally be watched using inotify.
+package file
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "file"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Path is the file the pods are read from.
+	Path string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Path: "",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Path must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		path: config.Path,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	path string
+}
+
+// Lookup reads the pods from the configured file.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	b, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	// YAML is a superset of JSON, so both are converted the same way.
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "invalid file %#q: %s", p.path, err.Error())
+	}
+
+	pods, err := provider.UnmarshalPods(j)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "file %#q lists no pods", p.path)
+	}
+
+	return pods, nil
+}
+
+// Watch emits the pods read from the configured file whenever it is written,
+// created or replaced. The directory of the file is watched, so that files
+// replaced atomically by renaming are followed as well. The returned channel
+// is closed once the given context is cancelled or the watch fails.
+func (p *Provider) Watch(ctx context.Context) <-chan []provider.PodInfo {
+	changes := make(chan []provider.PodInfo)
+
+	go func() {
+		defer close(changes)
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			_ = p.logger.Log("warning", "creating file watcher failed", "path", p.path, "error", err.Error())
+			return
+		}
+		defer watcher.Close()
+
+		err = watcher.Add(filepath.Dir(p.path))
+		if err != nil {
+			_ = p.logger.Log("warning", "watching file failed", "path", p.path, "error", err.Error())
+			return
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				_ = p.logger.Log("warning", "watching file failed", "path", p.path, "error", err.Error())
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(p.path) {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+
+				pods, err := p.Lookup(ctx)
+				if err != nil {
+					_ = p.logger.Log("warning", "reading changed file failed", "path", p.path, "error", err.Error())
+					continue
+				}
+
+				select {
+				case changes <- pods:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return changes
+}
@@ -0,0 +1,201 @@
+// Package http implements a provider which fetches the pods from a URL, e.g.
+// an external inventory or IPAM service. The URL has to return a JSON array
+// of pods, e.g. [{"name": "master-abc12", "ip": "10.0.0.1"}].
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "http"
+)
+
+const (
+	// maxBodySize limits the response bodies read, so that a misbehaving
+	// service cannot exhaust our memory.
+	maxBodySize = 1 << 20
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// CaFile is the certificate authority file used to verify the server.
+	CaFile string
+	// CrtFile is the client certificate file used to authenticate.
+	CrtFile string
+	// KeyFile is the client key file used to authenticate.
+	KeyFile string
+	// Timeout is the maximum duration of a single request.
+	Timeout time.Duration
+	// TokenFile is the file holding the bearer token used to authenticate. It
+	// is read for every request, so that rotated tokens are picked up.
+	TokenFile string
+	// URL is the address the pods are fetched from.
+	URL string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		CaFile:    "",
+		CrtFile:   "",
+		KeyFile:   "",
+		Timeout:   5 * time.Second,
+		TokenFile: "",
+		URL:       "",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+	if config.URL == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.URL must not be empty")
+	}
+
+	tlsConfig, err := newTLSConfig(config.CaFile, config.CrtFile, config.KeyFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		httpClient: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+
+		// Settings.
+		tokenFile: config.TokenFile,
+		url:       config.URL,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	httpClient *http.Client
+
+	// Settings.
+	tokenFile string
+	url       string
+}
+
+// Lookup fetches the pods from the configured URL.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	if p.tokenFile != "" {
+		b, err := ioutil.ReadFile(p.tokenFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(b)))
+	}
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, maxBodySize))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, microerror.Maskf(executionFailedError, "fetching %#q returned status %d", p.url, res.StatusCode)
+	}
+
+	pods, err := provider.UnmarshalPods(b)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "%#q returned no pods", p.url)
+	}
+
+	_ = p.logger.Log("debug", "fetched endpoint IPs", "url", p.url, "ips", len(pods))
+
+	return pods, nil
+}
+
+// newTLSConfig creates the TLS config used to connect to the URL. It is nil in
+// case no files are given, in which case the system roots are used.
+func newTLSConfig(caFile, crtFile, keyFile string) (*tls.Config, error) {
+	if caFile == "" && crtFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if caFile != "" {
+		b, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, microerror.Maskf(invalidConfigError, "no certificates found in %#q", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if crtFile != "" || keyFile != "" {
+		crt, err := tls.LoadX509KeyPair(crtFile, keyFile)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{crt}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,199 @@
+// Package kvmconfig implements a provider which reads the guest node IPs from
+// the status of the KVMConfig custom resource of the guest cluster, or any
+// other configured custom resource. This way the updater consumes what the
+// operator managing the resource already knows instead of re-discovering it
+// on the host.
+package kvmconfig
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "kvmconfig"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// Settings.
+
+	// APIVersion is the group and version of the custom resource, e.g.
+	// provider.giantswarm.io/v1alpha1.
+	APIVersion string
+	// Name is the name of the custom resource, usually the guest cluster ID.
+	Name string
+	// Namespace is the namespace of the custom resource.
+	Namespace string
+	// Path is the dot separated path of the field holding the IPs, e.g.
+	// status.cluster.nodes.ip. Arrays along the path are traversed, so that
+	// every element contributes its IPs.
+	Path string
+	// Resource is the plural resource name of the custom resource, e.g.
+	// kvmconfigs.
+	Resource string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		K8sClient: nil,
+		Logger:    nil,
+
+		// Settings.
+		APIVersion: "provider.giantswarm.io/v1alpha1",
+		Name:       "",
+		Namespace:  "default",
+		Path:       "",
+		Resource:   "kvmconfigs",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.APIVersion == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.APIVersion must not be empty")
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Name must not be empty")
+	}
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Namespace must not be empty")
+	}
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Path must not be empty")
+	}
+	if config.Resource == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Resource must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		// Settings.
+		apiVersion: config.APIVersion,
+		fields:     strings.Split(config.Path, "."),
+		name:       config.Name,
+		namespace:  config.Namespace,
+		resource:   config.Resource,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	// Settings.
+	apiVersion string
+	fields     []string
+	name       string
+	namespace  string
+	resource   string
+}
+
+// Lookup returns the IPs found at the configured path of the custom
+// resource.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	restClient := p.k8sClient.Discovery().RESTClient()
+	if restClient == nil {
+		return nil, microerror.Maskf(executionFailedError, "Kubernetes client does not support raw requests")
+	}
+
+	// Core resources live below /api, all others below /apis.
+	prefix := "/apis"
+	if !strings.Contains(p.apiVersion, "/") {
+		prefix = "/api"
+	}
+
+	b, err := restClient.Get().AbsPath(path.Join(prefix, p.apiVersion, "namespaces", p.namespace, p.resource, p.name)).Context(ctx).DoRaw()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var object interface{}
+	err = json.Unmarshal(b, &object)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var pods []provider.PodInfo
+	for _, v := range values(object, p.fields) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, microerror.Maskf(executionFailedError, "field %#q of %s %#q must hold strings", strings.Join(p.fields, "."), p.resource, p.name)
+		}
+
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, microerror.Maskf(executionFailedError, "field %#q of %s %#q must hold IPs, got %#q", strings.Join(p.fields, "."), p.resource, p.name, s)
+		}
+		if ip.To4() != nil {
+			ip = ip.To4()
+		}
+
+		pods = append(pods, provider.PodInfo{IP: ip})
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no IP found in field %#q of %s %#q", strings.Join(p.fields, "."), p.resource, p.name)
+	}
+
+	_ = p.logger.Log("debug", "read IPs from custom resource", "resource", p.resource, "name", p.name, "ips", len(pods))
+
+	return pods, nil
+}
+
+// values returns the values found at the given fields of the given decoded
+// JSON value. Arrays are traversed, so that every element contributes its
+// values.
+func values(v interface{}, fields []string) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		var res []interface{}
+		for _, e := range t {
+			res = append(res, values(e, fields)...)
+		}
+		return res
+	case map[string]interface{}:
+		if len(fields) == 0 {
+			return []interface{}{t}
+		}
+		return values(t[fields[0]], fields[1:])
+	case nil:
+		return nil
+	default:
+		if len(fields) != 0 {
+			return nil
+		}
+		return []interface{}{t}
+	}
+}
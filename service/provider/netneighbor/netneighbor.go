@@ -0,0 +1,136 @@
+// Package netneighbor implements a provider for Windows nodes which resolves
+// the guest VM address from the host neighbor table, either using the
+// Get-NetNeighbor cmdlet or the underlying MSFT_NetNeighbor WMI class.
+package netneighbor
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "netneighbor"
+)
+
+const (
+	SourceCmdlet = "cmdlet"
+	SourceWMI    = "wmi"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// InterfaceAlias is the alias of the host network interface the guest VM
+	// is attached to, e.g. "vEthernet (guest)".
+	InterfaceAlias string
+	// MAC optionally restricts the lookup to the neighbor entry with the given
+	// link layer address, which is the MAC of the guest VM.
+	MAC string
+	// Source defines how the neighbor table is queried. Either SourceCmdlet or
+	// SourceWMI.
+	Source string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		InterfaceAlias: "",
+		MAC:            "",
+		Source:         SourceCmdlet,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.InterfaceAlias == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.InterfaceAlias must not be empty")
+	}
+	if config.Source != SourceCmdlet && config.Source != SourceWMI {
+		return nil, microerror.Maskf(invalidConfigError, "config.Source must be %#q or %#q", SourceCmdlet, SourceWMI)
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		interfaceAlias: config.InterfaceAlias,
+		mac:            normalizeMAC(config.MAC),
+		source:         config.Source,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	interfaceAlias string
+	mac            string
+	source         string
+}
+
+// Lookup returns the first IPv4 address found in the neighbor table of the
+// configured interface, optionally matching the configured MAC, along with
+// the MAC of the neighbor.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	neighbors, err := p.neighbors(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, n := range neighbors {
+		if p.mac != "" && normalizeMAC(n.LinkLayerAddress) != p.mac {
+			continue
+		}
+
+		ip := net.ParseIP(n.IPAddress)
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+
+		// The MAC is informational only, so an unparsable one does not fail
+		// the lookup.
+		mac, _ := net.ParseMAC(normalizeMAC(n.LinkLayerAddress))
+
+		return []provider.PodInfo{{IP: ip.To4(), MAC: mac}}, nil
+	}
+
+	return nil, microerror.Maskf(notFoundError, "no neighbor found on interface %#q", p.interfaceAlias)
+}
+
+// neighbor is the subset of the MSFT_NetNeighbor properties we are interested
+// in.
+type neighbor struct {
+	IPAddress        string
+	LinkLayerAddress string
+}
+
+// normalizeMAC brings MACs as printed by Windows (00-15-5D-...) and Linux
+// (00:15:5d:...) into the same form.
+func normalizeMAC(mac string) string {
+	return strings.ToLower(strings.Replace(mac, "-", ":", -1))
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package netneighbor
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/giantswarm/microerror"
+)
+
+func (p *Provider) neighbors(ctx context.Context) ([]neighbor, error) {
+	return nil, microerror.Maskf(unsupportedPlatformError, "provider %#q is not supported on %s", Kind, runtime.GOOS)
+}
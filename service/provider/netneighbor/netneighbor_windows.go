@@ -0,0 +1,73 @@
+//go:build windows
+// +build windows
+
+package netneighbor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+)
+
+// envArgument is the environment variable passing the interface alias or WQL
+// filter to PowerShell. Referencing it as variable keeps the value out of the
+// parsed script, so quotes and $ in interface names cannot break the command.
+const envArgument = "K8S_ENDPOINT_UPDATER_NETNEIGHBOR_ARGUMENT"
+
+func (p *Provider) neighbors(ctx context.Context) ([]neighbor, error) {
+	var argument string
+	var query string
+	switch p.source {
+	case SourceWMI:
+		argument = fmt.Sprintf("InterfaceAlias='%s' AND AddressFamily=2", escapeWQL(p.interfaceAlias))
+		query = fmt.Sprintf("Get-CimInstance -Namespace root/StandardCimv2 -ClassName MSFT_NetNeighbor -Filter $env:%s", envArgument)
+	default:
+		argument = p.interfaceAlias
+		query = fmt.Sprintf("Get-NetNeighbor -InterfaceAlias $env:%s -AddressFamily IPv4 -State Reachable,Stale,Permanent", envArgument)
+	}
+
+	script := fmt.Sprintf("@(%s | Select-Object IPAddress,LinkLayerAddress) | ConvertTo-Json -Compress", query)
+
+	cmd := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(), envArgument+"="+argument)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	out = []byte(strings.TrimSpace(string(out)))
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	// ConvertTo-Json renders a single element array as plain object in older
+	// PowerShell versions.
+	var neighbors []neighbor
+	if out[0] == '{' {
+		var n neighbor
+		err = json.Unmarshal(out, &n)
+		neighbors = append(neighbors, n)
+	} else {
+		err = json.Unmarshal(out, &neighbors)
+	}
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return neighbors, nil
+}
+
+// escapeWQL escapes the given value for use inside a single quoted WQL string
+// literal, where backslashes and quotes are escaped with a backslash.
+func escapeWQL(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "'", `\'`, -1)
+
+	return s
+}
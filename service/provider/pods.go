@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"encoding/json"
+	"net"
+
+	"github.com/giantswarm/microerror"
+)
+
+// pod is the JSON representation of a pod info as produced by external
+// sources, e.g.
+//
+//	[{"name": "master-abc12", "ip": "10.0.0.1"}]
+//
+// Only the IP is required.
+type pod struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	NodeName  string `json:"nodeName"`
+}
+
+// UnmarshalPods parses the given JSON array of pods.
+func UnmarshalPods(b []byte) ([]PodInfo, error) {
+	var pods []pod
+	err := json.Unmarshal(b, &pods)
+	if err != nil {
+		return nil, microerror.Maskf(invalidPodError, "%s", err.Error())
+	}
+
+	var infos []PodInfo
+	for _, p := range pods {
+		ip := net.ParseIP(p.IP)
+		if ip == nil {
+			return nil, microerror.Maskf(invalidPodError, "invalid IP %#q", p.IP)
+		}
+
+		info := PodInfo{
+			IP:        ip,
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			NodeName:  p.NodeName,
+		}
+
+		if p.MAC != "" {
+			info.MAC, err = net.ParseMAC(p.MAC)
+			if err != nil {
+				return nil, microerror.Maskf(invalidPodError, "invalid MAC %#q", p.MAC)
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
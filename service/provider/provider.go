@@ -1,9 +1,74 @@
 package provider
 
 import (
+	"context"
 	"net"
+
+	"github.com/giantswarm/microerror"
 )
 
+// PodInfo describes the pod backing an endpoint IP. Providers fill in what
+// they are able to observe, so all fields except the IP are optional.
+type PodInfo struct {
+	IP        net.IP
+	MAC       net.HardwareAddr
+	Name      string
+	Namespace string
+	NodeName  string
+	UID       string
+}
+
+// Provider looks up the pods backing the endpoint IPs. Providers able to
+// resolve both address families return one pod info per family, the IPv4 one
+// first. Lookups are aborted once the given context is cancelled.
 type Provider interface {
-	Lookup() (net.IP, error)
+	Lookup(ctx context.Context) ([]PodInfo, error)
+}
+
+// WatchingProvider is implemented by providers which are able to notify about
+// changed pods instead of being polled. The returned channel is closed once
+// the given context is cancelled.
+type WatchingProvider interface {
+	Provider
+	Watch(ctx context.Context) <-chan []PodInfo
+}
+
+// ClosingProvider is implemented by providers holding connections, which have
+// to be closed once the provider is not used anymore.
+type ClosingProvider interface {
+	Provider
+	Close() error
+}
+
+// Close closes the given providers in case they implement ClosingProvider.
+// All of them are closed, even if some of them fail, and the first failure is
+// returned.
+func Close(providers ...Provider) error {
+	var first error
+	for _, p := range providers {
+		c, ok := p.(ClosingProvider)
+		if !ok {
+			continue
+		}
+
+		err := c.Close()
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return microerror.Mask(first)
+	}
+
+	return nil
+}
+
+// IPs returns the IPs of the given pods.
+func IPs(pods []PodInfo) []net.IP {
+	var ips []net.IP
+	for _, p := range pods {
+		ips = append(ips, p.IP)
+	}
+
+	return ips
 }
@@ -0,0 +1,152 @@
+// Package route implements a provider which resolves the endpoint IP from the
+// host routing table. Flannel and most CNI plugins install a /32 host route
+// pointing to the tap or veth device of the guest VM, which is more reliable
+// than deriving the address from the bridge IP.
+package route
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "route"
+)
+
+const (
+	// hostMask is the mask of /32 routes as found in /proc/net/route.
+	hostMask = "FFFFFFFF"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Device is the name of the tap or veth device of the guest VM the host
+	// route points to.
+	Device string
+	// Path is the IPv4 routing table exposed by the kernel.
+	Path string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Device: "",
+		Path:   "/proc/net/route",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Device == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Device must not be empty")
+	}
+	if config.Path == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Path must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		device: config.Device,
+		path:   config.Path,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	device string
+	path   string
+}
+
+// Lookup returns the destination of the first /32 route pointing to the
+// configured device.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	// The first line holds the column headers.
+	scanner.Scan()
+
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask MTU Window IRTT
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		if fields[0] != p.device || strings.ToUpper(fields[7]) != hostMask {
+			continue
+		}
+
+		ip, err := parseHexIPV4(fields[1])
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		_ = p.logger.Log("debug", "found host route", "device", p.device, "ip", ip.String())
+
+		return []provider.PodInfo{{IP: ip}}, nil
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return nil, microerror.Maskf(notFoundError, "no host route found for device %#q", p.device)
+}
+
+// parseHexIPV4 parses addresses as printed in /proc/net/route, which are
+// hex encoded in host byte order, that is little endian on all platforms we
+// run on.
+func parseHexIPV4(s string) (net.IP, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if len(b) != net.IPv4len {
+		return nil, microerror.Maskf(executionFailedError, "invalid route destination %#q", s)
+	}
+
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(b))
+
+	return ip, nil
+}
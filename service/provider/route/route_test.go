@@ -0,0 +1,72 @@
+package route
+
+import (
+	"testing"
+)
+
+func Test_parseHexIPV4(t *testing.T) {
+	testCases := []struct {
+		name         string
+		input        string
+		expected     string
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: host route",
+			input:        "0500000A",
+			expected:     "10.0.0.5",
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 1: default route",
+			input:        "00000000",
+			expected:     "0.0.0.0",
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 2: lower case",
+			input:        "0102a8c0",
+			expected:     "192.168.2.1",
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 3: too short",
+			input:        "0500",
+			errorMatcher: IsExecutionFailed,
+		},
+		{
+			name:         "case 4: IPv6 length",
+			input:        "00000000000000000000000000000001",
+			errorMatcher: IsExecutionFailed,
+		},
+		{
+			name:         "case 5: not hex",
+			input:        "zz00000A",
+			errorMatcher: func(err error) bool { return err != nil },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ip, err := parseHexIPV4(tc.input)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("expected no error, got %#v", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("expected error, got nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("expected matching error, got %#v", err)
+			}
+
+			if tc.errorMatcher != nil {
+				return
+			}
+			if ip.String() != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, ip.String())
+			}
+		})
+	}
+}
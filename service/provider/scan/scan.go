@@ -0,0 +1,247 @@
+// Package scan implements a provider which probes every address of a CIDR
+// using TCP connects and returns the responding hosts. It is meant for
+// environments in which the guest VM IP cannot be derived deterministically,
+// e.g. from the bridge.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "scan"
+)
+
+const (
+	// maxAddresses limits the size of the scanned CIDR, so that a typo does
+	// not make us probe a huge network.
+	maxAddresses = 1 << 16
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// CIDR is the network whose addresses are probed, e.g. 10.0.4.0/24. It
+	// must not contain more than 65536 addresses.
+	CIDR string
+	// Concurrency is the maximum number of probes in flight.
+	Concurrency int
+	// Port is the TCP port probed, e.g. 6443.
+	Port int
+	// Timeout is the maximum duration of a single probe.
+	Timeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		CIDR:        "",
+		Concurrency: 64,
+		Port:        0,
+		Timeout:     time.Second,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.CIDR == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.CIDR must not be empty")
+	}
+	_, network, err := net.ParseCIDR(config.CIDR)
+	if err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.CIDR must be a CIDR, got %#q", config.CIDR)
+	}
+	ones, bits := network.Mask.Size()
+	if bits-ones > 16 {
+		return nil, microerror.Maskf(invalidConfigError, "config.CIDR must not contain more than %d addresses", maxAddresses)
+	}
+	if config.Concurrency <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Concurrency must be greater than zero")
+	}
+	if config.Port <= 0 || config.Port > 65535 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Port must be a valid TCP port")
+	}
+	if config.Timeout <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Timeout must be greater than zero")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		concurrency: config.Concurrency,
+		network:     network,
+		port:        config.Port,
+		timeout:     config.Timeout,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	concurrency int
+	network     *net.IPNet
+	port        int
+	timeout     time.Duration
+}
+
+// Lookup probes all addresses of the configured CIDR and returns the ones
+// accepting TCP connections on the configured port, sorted by address. The
+// network and broadcast addresses of IPv4 networks as well as the addresses of
+// the local host are skipped.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	local, err := localIPs()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var responding []net.IP
+	var mutex sync.Mutex
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.concurrency)
+	for _, ip := range hosts(p.network) {
+		if local[ip.String()] {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(ip net.IP) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if p.probe(ctx, ip) {
+				mutex.Lock()
+				responding = append(responding, ip)
+				mutex.Unlock()
+			}
+		}(ip)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, microerror.Mask(ctx.Err())
+	}
+
+	if len(responding) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no host in %s accepts connections on port %d", p.network.String(), p.port)
+	}
+
+	sort.Slice(responding, func(i, j int) bool {
+		return bytes.Compare(responding[i], responding[j]) < 0
+	})
+
+	var pods []provider.PodInfo
+	for _, ip := range responding {
+		pods = append(pods, provider.PodInfo{IP: ip})
+	}
+
+	_ = p.logger.Log("debug", "found responding hosts", "cidr", p.network.String(), "port", p.port, "ips", len(pods))
+
+	return pods, nil
+}
+
+func (p *Provider) probe(ctx context.Context, ip net.IP) bool {
+	d := net.Dialer{Timeout: p.timeout}
+
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(p.port)))
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+
+	return true
+}
+
+// hosts returns the addresses of the given network. The network and broadcast
+// addresses are excluded for IPv4 networks larger than /31.
+func hosts(network *net.IPNet) []net.IP {
+	ip := network.IP.Mask(network.Mask)
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	var ips []net.IP
+	for c := ip; network.Contains(c); c = next(c) {
+		ips = append(ips, c)
+	}
+
+	ones, bits := network.Mask.Size()
+	if len(ip) == net.IPv4len && bits-ones > 1 {
+		ips = ips[1 : len(ips)-1]
+	}
+
+	return ips
+}
+
+// next returns the address following the given one. It wraps around after the
+// last address, which is outside of any network the loop above iterates.
+func next(ip net.IP) net.IP {
+	c := make(net.IP, len(ip))
+	copy(c, ip)
+
+	for j := len(c) - 1; j >= 0; j-- {
+		c[j]++
+		if c[j] > 0 {
+			break
+		}
+	}
+
+	return c
+}
+
+// localIPs returns the addresses assigned to the interfaces of the local host.
+func localIPs() (map[string]bool, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	local := map[string]bool{}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok {
+			local[ipNet.IP.String()] = true
+		}
+	}
+
+	return local, nil
+}
@@ -0,0 +1,93 @@
+// Package static implements a provider returning explicitly configured IPs,
+// so that operators are able to pin the endpoint IPs in break-glass scenarios
+// without relying on any discovery.
+package static
+
+import (
+	"context"
+	"net"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "static"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// IPs are the endpoint IPs returned by every lookup.
+	IPs []string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		IPs: nil,
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if len(config.IPs) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.IPs must not be empty")
+	}
+
+	var pods []provider.PodInfo
+	for _, s := range config.IPs {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, microerror.Maskf(invalidConfigError, "config.IPs must only contain IPs, got %#q", s)
+		}
+		if ip.To4() != nil {
+			ip = ip.To4()
+		}
+
+		pods = append(pods, provider.PodInfo{IP: ip})
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		pods: pods,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	pods []provider.PodInfo
+}
+
+// Lookup returns the configured IPs.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	pods := make([]provider.PodInfo, len(p.pods))
+	copy(pods, p.pods)
+
+	return pods, nil
+}
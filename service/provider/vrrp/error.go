@@ -0,0 +1,17 @@
+package vrrp
+
+import "github.com/giantswarm/microerror"
+
+var invalidConfigError = microerror.New("invalid config")
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var notMasterError = microerror.New("not master")
+
+// IsNotMaster asserts notMasterError.
+func IsNotMaster(err error) bool {
+	return microerror.Cause(err) == notMasterError
+}
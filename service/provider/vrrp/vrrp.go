@@ -0,0 +1,158 @@
+// Package vrrp implements a provider which publishes a keepalived managed VIP
+// only while the local node holds it. keepalived has to be configured to
+// write its state to a file using a notify script, e.g.
+//
+//	notify "/bin/sh -c 'echo $3 > /run/keepalived/state'"
+//
+// The file then contains MASTER, BACKUP or FAULT.
+package vrrp
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "vrrp"
+)
+
+const (
+	stateMaster = "MASTER"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Interface optionally names the interface the VIP has to be assigned to
+	// in addition to the MASTER state, guarding against stale state files.
+	Interface string
+	// StateFile is the file keepalived writes its current state to.
+	StateFile string
+	// VIP is the virtual IP managed by keepalived.
+	VIP string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Interface: "",
+		StateFile: "",
+		VIP:       "",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.StateFile == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.StateFile must not be empty")
+	}
+	vip := net.ParseIP(config.VIP)
+	if vip == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.VIP must be a valid IP")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		iface:     config.Interface,
+		stateFile: config.StateFile,
+		vip:       vip,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	iface     string
+	stateFile string
+	vip       net.IP
+}
+
+// Lookup returns the VIP in case the local node is MASTER. Otherwise a
+// notMasterError is returned, so that the VIP is never registered from a
+// BACKUP node.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	master, err := p.IsMaster()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if !master {
+		return nil, microerror.Maskf(notMasterError, "VIP %s is not held locally", p.vip.String())
+	}
+
+	return []provider.PodInfo{{IP: p.vip}}, nil
+}
+
+// VIP returns the virtual IP managed by keepalived.
+func (p *Provider) VIP() net.IP {
+	return p.vip
+}
+
+// IsMaster returns whether keepalived reports the MASTER state and, if an
+// interface is configured, the VIP is assigned to it.
+func (p *Provider) IsMaster() (bool, error) {
+	b, err := ioutil.ReadFile(p.stateFile)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	state := strings.ToUpper(strings.TrimSpace(string(b)))
+	if state != stateMaster {
+		_ = p.logger.Log("debug", "keepalived is not master", "state", state)
+		return false, nil
+	}
+
+	if p.iface == "" {
+		return true, nil
+	}
+
+	netInterface, err := net.InterfaceByName(p.iface)
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	addrs, err := netInterface.Addrs()
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.Equal(p.vip) {
+			return true, nil
+		}
+	}
+
+	_ = p.logger.Log("debug", "keepalived is master but VIP is not assigned", "interface", p.iface)
+
+	return false, nil
+}
@@ -0,0 +1,222 @@
+// Package whereabouts implements a provider which reads the IP reserved for
+// the KVM pod from the IPPool custom resources of the Whereabouts IPAM plugin.
+// This keeps the published endpoint IPs consistent with the IPAM source of
+// truth of the cluster instead of re-discovering them on the host.
+package whereabouts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+)
+
+const (
+	Kind = "whereabouts"
+)
+
+const (
+	// ipPoolsPath is the path of the IPPool resources below the API group of
+	// Whereabouts, formatted with the namespace.
+	ipPoolsPath = "/apis/whereabouts.cni.cncf.io/v1alpha1/namespaces/%s/ippools"
+)
+
+// Config represents the configuration used to create a new provider.
+type Config struct {
+	// Dependencies.
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+
+	// Settings.
+
+	// Interface optionally restricts the lookup to reservations of the given
+	// pod interface, e.g. net1. Reservations made by Whereabouts versions not
+	// recording the interface never match a non-empty Interface.
+	Interface string
+	// Namespace is the namespace Whereabouts keeps its IPPool resources in.
+	Namespace string
+	// PodName is the name of the KVM pod the IP is reserved for.
+	PodName string
+	// PodNamespace is the namespace of the KVM pod the IP is reserved for.
+	PodNamespace string
+}
+
+// DefaultConfig provides a default configuration to create a new provider
+// by best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		K8sClient: nil,
+		Logger:    nil,
+
+		// Settings.
+		Interface:    "",
+		Namespace:    "kube-system",
+		PodName:      "",
+		PodNamespace: "",
+	}
+}
+
+// New creates a new provider.
+func New(config Config) (*Provider, error) {
+	// Dependencies.
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Namespace must not be empty")
+	}
+	if config.PodName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.PodName must not be empty")
+	}
+	if config.PodNamespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.PodNamespace must not be empty")
+	}
+
+	newProvider := &Provider{
+		// Dependencies.
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+
+		// Settings.
+		iface:        config.Interface,
+		namespace:    config.Namespace,
+		podName:      config.PodName,
+		podNamespace: config.PodNamespace,
+	}
+
+	return newProvider, nil
+}
+
+type Provider struct {
+	// Dependencies.
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+
+	// Settings.
+	iface        string
+	namespace    string
+	podName      string
+	podNamespace string
+}
+
+// ipPoolList is the subset of the IPPool list we are interested in.
+type ipPoolList struct {
+	Items []ipPool `json:"items"`
+}
+
+type ipPool struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Allocations map[string]allocation `json:"allocations"`
+		Range       string                `json:"range"`
+	} `json:"spec"`
+}
+
+type allocation struct {
+	IfName string `json:"ifname"`
+	PodRef string `json:"podref"`
+}
+
+// Lookup returns the IPs reserved for the configured pod across all IPPools.
+func (p *Provider) Lookup(ctx context.Context) ([]provider.PodInfo, error) {
+	restClient := p.k8sClient.Discovery().RESTClient()
+	if restClient == nil {
+		return nil, microerror.Maskf(executionFailedError, "Kubernetes client does not support raw requests")
+	}
+
+	b, err := restClient.Get().AbsPath(fmt.Sprintf(ipPoolsPath, p.namespace)).Context(ctx).DoRaw()
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var list ipPoolList
+	err = json.Unmarshal(b, &list)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	podRef := p.podNamespace + "/" + p.podName
+
+	var pods []provider.PodInfo
+	for _, pool := range list.Items {
+		for offset, a := range pool.Spec.Allocations {
+			if a.PodRef != podRef || (p.iface != "" && a.IfName != p.iface) {
+				continue
+			}
+
+			ip, err := offsetIP(pool.Spec.Range, offset)
+			if err != nil {
+				return nil, microerror.Maskf(executionFailedError, "invalid allocation %#q of IPPool %#q: %s", offset, pool.Metadata.Name, err.Error())
+			}
+
+			_ = p.logger.Log("debug", "found IP reservation", "ippool", pool.Metadata.Name, "ip", ip.String())
+
+			pods = append(pods, provider.PodInfo{IP: ip, Name: p.podName, Namespace: p.podNamespace})
+		}
+	}
+
+	if len(pods) == 0 {
+		return nil, microerror.Maskf(notFoundError, "no IP reserved for pod %#q", podRef)
+	}
+
+	// Allocations are kept in maps, so the IPs are sorted to keep the order
+	// stable across lookups.
+	sort.Slice(pods, func(i, j int) bool {
+		return bytes.Compare(pods[i].IP.To16(), pods[j].IP.To16()) < 0
+	})
+
+	return pods, nil
+}
+
+// offsetIP returns the IP at the given decimal offset from the network
+// address of the given range, which is how Whereabouts keys its allocations.
+func offsetIP(cidr string, offset string) (net.IP, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	o, err := strconv.ParseUint(offset, 10, 64)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	ip := ipNet.IP
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+
+	n := new(big.Int).SetBytes(ip)
+	n.Add(n, new(big.Int).SetUint64(o))
+
+	b := n.Bytes()
+	if len(b) > len(ip) {
+		return nil, microerror.Maskf(executionFailedError, "offset %s exceeds the address space", offset)
+	}
+
+	res := make(net.IP, len(ip))
+	copy(res[len(res)-len(b):], b)
+
+	if !ipNet.Contains(res) {
+		return nil, microerror.Maskf(executionFailedError, "offset %s exceeds range %#q", offset, cidr)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,45 @@
+package reaper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/metrics"
+)
+
+const (
+	prometheusSubsystem = "reaper"
+)
+
+var (
+	scannedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "scanned_total",
+			Help:      "Number of managed pods scanned for stale endpoint IPs.",
+		},
+	)
+	reapedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "reaped_total",
+			Help:      "Number of stale endpoint IPs removed, or which would have been removed in dry-run mode.",
+		},
+		[]string{"reason", "dry_run"},
+	)
+	reapFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "failures_total",
+			Help:      "Number of stale endpoint IPs which could not be removed.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(scannedTotal)
+	prometheus.MustRegister(reapedTotal)
+	prometheus.MustRegister(reapFailuresTotal)
+}
@@ -0,0 +1,258 @@
+// Package reaper implements the cluster-wide cleanup of stale endpoint IPs.
+// Only the endpoint IPs published as pod annotations by the updater are
+// scanned. An IP is considered stale when the pod carrying it has terminated,
+// or when the pod is not running and its heartbeat annotation is older than a
+// given threshold, e.g. because its node became unreachable. Running pods are
+// never reaped, since updaters not running with a reassert interval do not
+// refresh their heartbeats. Deleted pods carrying the cleanup finalizer get
+// their endpoint IPs removed from the endpoints of their service before the
+// finalizer is removed. IPs left in Endpoints and EndpointSlices by deleted
+// pods without finalizer are removed by the cleaner instead.
+package reaper
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+const (
+	ReasonHeartbeatExpired = "heartbeat_expired"
+	ReasonHeartbeatMissing = "heartbeat_missing"
+	ReasonPodDeleted       = "pod_deleted"
+	ReasonPodTerminated    = "pod_terminated"
+)
+
+// Config represents the configuration used to create a new reaper.
+type Config struct {
+	// Dependencies.
+	K8sClient kubernetes.Interface
+	Logger    micrologger.Logger
+	Updater   *updater.Updater
+
+	// Settings.
+
+	// DryRun only reports stale endpoint IPs without removing them.
+	DryRun bool
+	// Threshold is the age after which a heartbeat is considered expired.
+	Threshold time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new reaper by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		K8sClient: nil,
+		Logger:    nil,
+		Updater:   nil,
+
+		// Settings.
+		DryRun:    false,
+		Threshold: time.Hour,
+	}
+}
+
+// New creates a new reaper.
+func New(config Config) (*Reaper, error) {
+	// Dependencies.
+	if config.K8sClient == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.K8sClient must not be empty")
+	}
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+	if config.Updater == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Updater must not be empty")
+	}
+
+	// Settings.
+	if config.Threshold <= 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.Threshold must be greater than zero")
+	}
+
+	newReaper := &Reaper{
+		// Dependencies.
+		k8sClient: config.K8sClient,
+		logger:    config.Logger,
+		updater:   config.Updater,
+
+		// Settings.
+		dryRun:    config.DryRun,
+		threshold: config.Threshold,
+	}
+
+	return newReaper, nil
+}
+
+type Reaper struct {
+	// Dependencies.
+	k8sClient kubernetes.Interface
+	logger    micrologger.Logger
+	updater   *updater.Updater
+
+	// Settings.
+	dryRun    bool
+	threshold time.Duration
+}
+
+// Reaped describes a stale endpoint IP found by the reaper.
+type Reaped struct {
+	Namespace string
+	Pod       string
+	IP        string
+	Reason    string
+}
+
+// Reap scans all pods carrying an endpoint IP in the given namespace, or all
+// namespaces when empty, and removes the stale ones. The stale endpoint IPs
+// are returned, including those which would have been removed in dry-run
+// mode. Reaping stops once the given context is cancelled.
+func (r *Reaper) Reap(ctx context.Context, namespace string) ([]Reaped, error) {
+	pods, err := r.k8sClient.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	now := time.Now()
+
+	var reaped []Reaped
+	for _, pod := range pods.Items {
+		if ctx.Err() != nil {
+			return nil, microerror.Mask(ctx.Err())
+		}
+
+		if pod.DeletionTimestamp != nil && hasFinalizer(pod) {
+			scannedTotal.Inc()
+
+			ips, err := r.finalize(ctx, pod)
+			if err != nil {
+				reapFailuresTotal.Inc()
+				_ = r.logger.Log("warning", fmt.Sprintf("cleaning up deleted pod '%s/%s' failed: %#v", pod.Namespace, pod.Name, microerror.Mask(err)))
+				continue
+			}
+
+			reapedTotal.WithLabelValues(ReasonPodDeleted, strconv.FormatBool(r.dryRun)).Inc()
+			reaped = append(reaped, Reaped{Namespace: pod.Namespace, Pod: pod.Name, IP: ips, Reason: ReasonPodDeleted})
+			continue
+		}
+
+		ip, ok := pod.GetAnnotations()[updater.AnnotationIP]
+		if !ok {
+			continue
+		}
+
+		scannedTotal.Inc()
+
+		reason := r.staleReason(pod, now)
+		if reason == "" {
+			continue
+		}
+
+		_ = r.logger.Log("info", fmt.Sprintf("found stale endpoint IP on pod '%s/%s'", pod.Namespace, pod.Name), "ip", ip, "reason", reason, "dryRun", r.dryRun)
+
+		if !r.dryRun {
+			err := r.updater.RemoveAnnotations(pod.Namespace, pod.Name)
+			if err != nil {
+				reapFailuresTotal.Inc()
+				_ = r.logger.Log("warning", fmt.Sprintf("removing stale endpoint IP failed: %#v", microerror.Mask(err)))
+				continue
+			}
+		}
+
+		reapedTotal.WithLabelValues(reason, strconv.FormatBool(r.dryRun)).Inc()
+		reaped = append(reaped, Reaped{Namespace: pod.Namespace, Pod: pod.Name, IP: ip, Reason: reason})
+	}
+
+	return reaped, nil
+}
+
+// finalize removes the endpoint IPs the given deleted pod owns from the
+// endpoints of its service and then removes the cleanup finalizer, so that
+// the pod can go away. The removed endpoint IPs are returned comma separated.
+func (r *Reaper) finalize(ctx context.Context, pod corev1.Pod) (string, error) {
+	_ = r.logger.Log("info", fmt.Sprintf("cleaning up deleted pod '%s/%s'", pod.Namespace, pod.Name), "dryRun", r.dryRun)
+
+	if r.dryRun {
+		return "", nil
+	}
+
+	var ips []net.IP
+	for _, service := range strings.Split(pod.GetAnnotations()[updater.AnnotationService], ",") {
+		if service == "" {
+			continue
+		}
+
+		namespace := pod.Namespace
+		if i := strings.Index(service, "/"); i >= 0 {
+			namespace, service = service[:i], service[i+1:]
+		}
+
+		deleted, err := r.updater.DeleteOwned(ctx, namespace, service, string(pod.UID))
+		if err != nil {
+			return "", microerror.Mask(err)
+		}
+		ips = append(ips, deleted...)
+	}
+
+	err := r.updater.RemoveFinalizer(ctx, pod.Namespace, pod.Name)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var s []string
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+
+	return strings.Join(s, ","), nil
+}
+
+func hasFinalizer(pod corev1.Pod) bool {
+	for _, f := range pod.Finalizers {
+		if f == updater.FinalizerCleanup {
+			return true
+		}
+	}
+
+	return false
+}
+
+// staleReason returns why the endpoint IP of the given pod is stale, or an
+// empty string in case it is not.
+func (r *Reaper) staleReason(pod corev1.Pod, now time.Time) string {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return ReasonPodTerminated
+	}
+	if pod.Status.Phase == corev1.PodRunning {
+		return ""
+	}
+
+	heartbeat, ok := pod.GetAnnotations()[updater.AnnotationHeartbeat]
+	if !ok {
+		// Pods annotated by older versions do not carry heartbeats. We fall
+		// back to the pod age to not reap freshly created pods.
+		if now.Sub(pod.CreationTimestamp.Time) > r.threshold {
+			return ReasonHeartbeatMissing
+		}
+		return ""
+	}
+
+	t, err := time.Parse(time.RFC3339, heartbeat)
+	if err != nil || now.Sub(t) > r.threshold {
+		return ReasonHeartbeatExpired
+	}
+
+	return ""
+}
@@ -0,0 +1,184 @@
+// Package systemd implements the sd_notify protocol, so that the updater can
+// run as Type=notify unit when deployed on the host instead of as a pod. The
+// service manager is told once the endpoint IP has been published initially
+// and once the updater is shutting down, and gets watchdog keep-alive pings.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	// SocketEnv is the environment variable systemd passes the notification
+	// socket in.
+	SocketEnv = "NOTIFY_SOCKET"
+	// WatchdogPIDEnv is the environment variable systemd passes the PID of
+	// the process expected to send watchdog pings in.
+	WatchdogPIDEnv = "WATCHDOG_PID"
+	// WatchdogUSecEnv is the environment variable systemd passes the watchdog
+	// timeout in, in microseconds.
+	WatchdogUSecEnv = "WATCHDOG_USEC"
+)
+
+// Config represents the configuration used to create a new notifier.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Socket is the path of the notification socket. Abstract sockets are
+	// prefixed with "@".
+	Socket string
+	// WatchdogTimeout is the watchdog timeout of the unit. The watchdog is
+	// disabled when zero.
+	WatchdogTimeout time.Duration
+}
+
+// DefaultConfig provides a default configuration to create a new notifier by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Socket:          "",
+		WatchdogTimeout: 0,
+	}
+}
+
+// New creates a new notifier.
+func New(config Config) (*Notifier, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Socket == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Socket must not be empty")
+	}
+	if config.WatchdogTimeout < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "config.WatchdogTimeout must not be negative")
+	}
+
+	newNotifier := &Notifier{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Settings.
+		socket:          config.Socket,
+		watchdogTimeout: config.WatchdogTimeout,
+	}
+
+	return newNotifier, nil
+}
+
+// Notifier notifies systemd about state changes. All methods are no-ops on a
+// nil notifier, so callers do not have to distinguish whether the updater runs
+// under systemd.
+type Notifier struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Settings.
+	socket          string
+	watchdogTimeout time.Duration
+}
+
+// Ready tells systemd that the updater finished starting up, which is the
+// case once the endpoint IP has been published initially.
+func (n *Notifier) Ready() {
+	n.notify("READY=1")
+}
+
+// Stopping tells systemd that the updater is shutting down.
+func (n *Notifier) Stopping() {
+	n.notify("STOPPING=1")
+}
+
+// Watchdog sends a watchdog keep-alive ping.
+func (n *Notifier) Watchdog() {
+	if n == nil || n.watchdogTimeout == 0 {
+		return
+	}
+
+	n.notify("WATCHDOG=1")
+}
+
+// WatchdogInterval returns the interval watchdog pings have to be sent in. As
+// recommended by systemd it is half of the watchdog timeout. It is zero when
+// the watchdog is disabled.
+func (n *Notifier) WatchdogInterval() time.Duration {
+	if n == nil {
+		return 0
+	}
+
+	return n.watchdogTimeout / 2
+}
+
+func (n *Notifier) notify(state string) {
+	if n == nil {
+		return
+	}
+
+	err := n.send(state)
+	if err != nil {
+		_ = n.logger.Log("warning", fmt.Sprintf("notifying systemd failed: %#v", microerror.Mask(err)), "state", state)
+		return
+	}
+
+	_ = n.logger.Log("debug", "notified systemd", "state", state)
+}
+
+func (n *Notifier) send(state string) error {
+	name := n.socket
+	if name[0] == '@' {
+		name = "\x00" + name[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// WatchdogTimeout returns the watchdog timeout systemd passed to the current
+// process. It is zero when the watchdog is disabled or meant for another
+// process.
+func WatchdogTimeout() (time.Duration, error) {
+	s := os.Getenv(WatchdogUSecEnv)
+	if s == "" {
+		return 0, nil
+	}
+
+	if p := os.Getenv(WatchdogPIDEnv); p != "" && p != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	usec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, microerror.Maskf(invalidConfigError, "%s must be an integer: %s", WatchdogUSecEnv, err.Error())
+	}
+	if usec <= 0 {
+		return 0, microerror.Maskf(invalidConfigError, "%s must be greater than zero", WatchdogUSecEnv)
+	}
+
+	return time.Duration(usec) * time.Microsecond, nil
+}
@@ -0,0 +1,155 @@
+// Package telemetry implements opt-in anonymous usage reporting. Only
+// aggregate counters and non-identifying labels like the provider kind and the
+// version are ever reported. Namespaces, service names, pod names and IPs are
+// never part of a report.
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+)
+
+const (
+	reportTimeout = 5 * time.Second
+)
+
+// Config represents the configuration used to create a new reporter.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Enabled opts in to telemetry reporting. When false the reporter does not
+	// send anything.
+	Enabled bool
+	// Endpoint is the URL reports are sent to via HTTP POST.
+	Endpoint string
+	// Version is the version of the command line tool included in reports.
+	Version string
+}
+
+// DefaultConfig provides a default configuration to create a new reporter by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Enabled:  false,
+		Endpoint: "",
+		Version:  "",
+	}
+}
+
+// New creates a new reporter.
+func New(config Config) (*Reporter, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Enabled && config.Endpoint == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Endpoint must not be empty")
+	}
+
+	newReporter := &Reporter{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		counters:   map[string]int64{},
+		httpClient: &http.Client{Timeout: reportTimeout},
+		labels:     map[string]string{},
+		mutex:      sync.Mutex{},
+
+		// Settings.
+		enabled:  config.Enabled,
+		endpoint: config.Endpoint,
+		version:  config.Version,
+	}
+
+	return newReporter, nil
+}
+
+type Reporter struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	counters   map[string]int64
+	httpClient *http.Client
+	labels     map[string]string
+	mutex      sync.Mutex
+
+	// Settings.
+	enabled  bool
+	endpoint string
+	version  string
+}
+
+type report struct {
+	Counters map[string]int64  `json:"counters"`
+	Labels   map[string]string `json:"labels"`
+	Version  string            `json:"version"`
+}
+
+// Inc increments the counter with the given name.
+func (r *Reporter) Inc(name string) {
+	if !r.enabled {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.counters[name]++
+}
+
+// SetLabel sets a non-identifying label, e.g. the provider kind.
+func (r *Reporter) SetLabel(key, value string) {
+	if !r.enabled {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.labels[key] = value
+}
+
+// Report sends the current counters and labels to the configured endpoint.
+func (r *Reporter) Report() error {
+	if !r.enabled {
+		return nil
+	}
+
+	r.mutex.Lock()
+	b, err := json.Marshal(report{Counters: r.counters, Labels: r.labels, Version: r.version})
+	r.mutex.Unlock()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	res, err := r.httpClient.Post(r.endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return microerror.Maskf(executionFailedError, "telemetry endpoint responded with status code %d", res.StatusCode)
+	}
+
+	_ = r.logger.Log("debug", "sent telemetry report")
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// Package tracing exports OpenTelemetry spans of lookups, Kubernetes API
+// writes and reconciliations via OTLP, so that slow provider lookups or
+// throttled API writes show up in a tracing backend. Spans are started using
+// Start regardless of whether exporting is enabled. They are dropped as long
+// as no Tracer is created.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation is the name of the tracer all spans are started with.
+const instrumentation = "github.com/giantswarm/k8s-endpoint-updater"
+
+// Config represents the configuration used to create a new tracer.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// Endpoint is the address of the OTLP gRPC collector spans are exported
+	// to, e.g. otel-collector:4317.
+	Endpoint string
+	// Insecure disables TLS when connecting to the collector.
+	Insecure bool
+	// Name is the service name the spans are reported with.
+	Name string
+	// Version is the service version the spans are reported with.
+	Version string
+}
+
+// DefaultConfig provides a default configuration to create a new tracer by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		Endpoint: "",
+		Insecure: false,
+		Name:     "",
+		Version:  "",
+	}
+}
+
+// New creates a new tracer exporting all spans started afterwards. The
+// connection to the collector is established in the background, so that an
+// unavailable collector does not prevent the updater from starting.
+func New(config Config) (*Tracer, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	// Settings.
+	if config.Endpoint == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Endpoint must not be empty")
+	}
+	if config.Name == "" {
+		return nil, microerror.Maskf(invalidConfigError, "config.Name must not be empty")
+	}
+
+	options := []otlpgrpc.Option{
+		otlpgrpc.WithEndpoint(config.Endpoint),
+	}
+	if config.Insecure {
+		options = append(options, otlpgrpc.WithInsecure())
+	}
+
+	exporter, err := otlp.NewExporter(context.Background(), otlpgrpc.NewDriver(options...))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.ServiceNameKey.String(config.Name),
+			semconv.ServiceVersionKey.String(config.Version),
+		)),
+	)
+
+	otel.SetErrorHandler(&errorHandler{logger: config.Logger})
+	otel.SetTracerProvider(provider)
+
+	newTracer := &Tracer{
+		// Internals.
+		provider: provider,
+	}
+
+	return newTracer, nil
+}
+
+// Tracer exports the spans started using Start. All methods are no-ops on a
+// nil tracer.
+type Tracer struct {
+	// Internals.
+	provider *sdktrace.TracerProvider
+}
+
+// Stop exports the spans not exported yet and closes the connection to the
+// collector. It gives up once the given context expired.
+func (t *Tracer) Stop(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	err := t.provider.Shutdown(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// Start starts a span of the given name, which is a child of the span of the
+// given context, if any. The returned context carries the new span.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// Fail marks the given span as failed because of the given error.
+func Fail(span trace.Span, err error) {
+	span.RecordError(microerror.Cause(err))
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// errorHandler logs the errors of exporting spans, e.g. because the collector
+// is unavailable.
+type errorHandler struct {
+	logger micrologger.Logger
+}
+
+func (h *errorHandler) Handle(err error) {
+	_ = h.logger.Log("warning", fmt.Sprintf("exporting spans failed: %#v", microerror.Mask(err)))
+}
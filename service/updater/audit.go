@@ -0,0 +1,109 @@
+package updater
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/audit"
+)
+
+// auditEndpoints records the given mutation of an Endpoints object in the
+// audit log, if any. Either original or endpoints may be nil in case the
+// object got created or deleted.
+func (p *Updater) auditEndpoints(operation string, original, endpoints *corev1.Endpoints, resourceVersion string, err error) {
+	entry := audit.Entry{
+		Operation: operation,
+		Resource:  "endpoints",
+	}
+	if original != nil {
+		entry.Namespace = original.Namespace
+		entry.Name = original.Name
+		entry.Previous = endpointsAddresses(original)
+	}
+	if endpoints != nil {
+		entry.Namespace = endpoints.Namespace
+		entry.Name = endpoints.Name
+		entry.Current = endpointsAddresses(endpoints)
+	}
+
+	p.record(entry, resourceVersion, err)
+}
+
+// auditEndpointSlice records the given mutation of an EndpointSlice in the
+// audit log, if any. Either original or slice may be nil in case the slice got
+// created or deleted.
+func (p *Updater) auditEndpointSlice(operation string, original, slice *discoveryv1alpha1.EndpointSlice, resourceVersion string, err error) {
+	entry := audit.Entry{
+		Operation: operation,
+		Resource:  "endpointslice",
+	}
+	if original != nil {
+		entry.Namespace = original.Namespace
+		entry.Name = original.Name
+		entry.Previous = sliceAddresses(original)
+	}
+	if slice != nil {
+		entry.Namespace = slice.Namespace
+		entry.Name = slice.Name
+		entry.Current = sliceAddresses(slice)
+	}
+
+	p.record(entry, resourceVersion, err)
+}
+
+// record completes the given entry with the response of the API server and
+// appends it to the audit log. Nothing is recorded in dry-run mode, since
+// nothing got mutated. Failing to record the entry does not fail the
+// mutation, which already happened, but is logged.
+func (p *Updater) record(entry audit.Entry, resourceVersion string, err error) {
+	if p.auditLog == nil || p.dryRun {
+		return
+	}
+
+	entry.Time = time.Now().UTC()
+	entry.Response.ResourceVersion = resourceVersion
+	if err != nil {
+		entry.Response.Error = err.Error()
+		if status, ok := microerror.Cause(err).(errors.APIStatus); ok {
+			entry.Response.Code = status.Status().Code
+			entry.Response.Reason = string(status.Status().Reason)
+		}
+	}
+
+	recordErr := p.auditLog.Record(entry)
+	if recordErr != nil {
+		_ = p.logger.Log("warning", fmt.Sprintf("recording audit entry failed: %#v", microerror.Mask(recordErr)), "resource", entry.Resource, "namespace", entry.Namespace, "name", entry.Name)
+	}
+}
+
+func endpointsAddresses(endpoints *corev1.Endpoints) audit.Addresses {
+	var addresses audit.Addresses
+	for _, s := range endpoints.Subsets {
+		for _, a := range s.Addresses {
+			addresses.Ready = append(addresses.Ready, a.IP)
+		}
+		for _, a := range s.NotReadyAddresses {
+			addresses.NotReady = append(addresses.NotReady, a.IP)
+		}
+	}
+
+	return addresses
+}
+
+func sliceAddresses(slice *discoveryv1alpha1.EndpointSlice) audit.Addresses {
+	var addresses audit.Addresses
+	for _, e := range slice.Endpoints {
+		if isReady(e) {
+			addresses.Ready = append(addresses.Ready, e.Addresses...)
+		} else {
+			addresses.NotReady = append(addresses.NotReady, e.Addresses...)
+		}
+	}
+
+	return addresses
+}
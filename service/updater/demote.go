@@ -0,0 +1,113 @@
+package updater
+
+import (
+	"context"
+	"net"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DemoteOwned turns the IPs the given owner claims on the objects managed for
+// the given service into not ready addresses, so that no new connections are
+// routed to them while existing ones drain. Unlike CreateNotReady the
+// addresses are kept as they are otherwise, including their pod references.
+// The demoted IPs are returned.
+func (p *Updater) DemoteOwned(ctx context.Context, namespace, service, owner string) ([]net.IP, error) {
+	ips, err := p.ownedIPs(namespace, service, owner)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(ips) == 0 {
+		return nil, nil
+	}
+
+	if p.kind == KindEndpoints || p.kind == KindBoth {
+		err := p.retryOnConflict(ctx, "endpoints", namespace, service, func() error {
+			return p.demoteEndpoints(namespace, service, ips)
+		})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	}
+
+	if p.kind == KindEndpointSlice || p.kind == KindBoth {
+		for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+			familyIPs := filterFamily(ips, family)
+			if len(familyIPs) == 0 {
+				continue
+			}
+
+			err := p.retryOnConflict(ctx, "endpointslice", namespace, service, func() error {
+				return p.demoteFamilyEndpointSlice(namespace, service, family, familyIPs)
+			})
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+		}
+	}
+
+	return ips, nil
+}
+
+func (p *Updater) demoteEndpoints(namespace, service string, ips []net.IP) error {
+	original, err := p.k8sClient.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	endpoints := original.DeepCopy()
+
+	for i := range endpoints.Subsets {
+		var ready []corev1.EndpointAddress
+		for _, a := range endpoints.Subsets[i].Addresses {
+			if containsIP(ips, a.IP) {
+				endpoints.Subsets[i].NotReadyAddresses = append(endpoints.Subsets[i].NotReadyAddresses, a)
+				continue
+			}
+			ready = append(ready, a)
+		}
+		endpoints.Subsets[i].Addresses = ready
+	}
+
+	err = p.writeEndpoints(original, endpoints)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "demoted IPs in endpoints", "namespace", namespace, "service", service, "ips", JoinIPs(ips))
+
+	return nil
+}
+
+func (p *Updater) demoteFamilyEndpointSlice(namespace, service, family string, ips []net.IP) error {
+	original, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Get(EndpointSliceName(service, family), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	slice := original.DeepCopy()
+
+	for i, e := range slice.Endpoints {
+		if len(e.Addresses) == 0 || !containsIP(ips, e.Addresses[0]) {
+			continue
+		}
+
+		ready := false
+		slice.Endpoints[i].Conditions.Ready = &ready
+	}
+
+	err = p.writeEndpointSlice(original, slice)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "demoted IPs in endpoint slice", "namespace", namespace, "service", service, "family", family, "ips", JoinIPs(ips))
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+)
+
+// printDryRun prints the request which would have been sent in dry-run mode.
+// Patches are printed as is, all other payloads as indented JSON.
+func (p *Updater) printDryRun(verb, resource, namespace, name string, payload interface{}) error {
+	var body string
+	switch v := payload.(type) {
+	case nil:
+		body = ""
+	case string:
+		body = v
+	default:
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		body = string(b) + "\n"
+	}
+
+	_, err := fmt.Fprintf(p.writer, "# dry-run: would %s %s %s/%s\n%s", verb, resource, namespace, name, body)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
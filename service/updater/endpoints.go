@@ -0,0 +1,254 @@
+package updater
+
+import (
+	"net"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// replaceEndpoints removes the given stale IPs from the Endpoints of the given
+// service and adds the given ready and not ready IPs within the same write.
+func (p *Updater) replaceEndpoints(namespace, service string, stale, ready, notReady []net.IP) error {
+	ips := append(append([]net.IP{}, ready...), notReady...)
+
+	original, err := p.k8sClient.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	if errors.IsNotFound(err) && len(ips) == 0 {
+		return nil
+	} else if errors.IsNotFound(err) && p.createMissing {
+		original = nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	var endpoints *corev1.Endpoints
+	if original != nil {
+		endpoints = original.DeepCopy()
+	} else {
+		endpoints, err = p.newEndpoints(namespace, service)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Other updater instances might have added the same IPs, so only the
+	// stale ones nobody else claims are removed.
+	var removed []net.IP
+	if len(stale) != 0 {
+		removed, err = p.release(endpoints, stale)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		endpoints.Subsets = removeEndpointIPs(endpoints.Subsets, removed)
+	}
+
+	ports, err := p.endpointPorts(namespace, service)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(endpoints.Subsets) == 0 && len(ips) != 0 {
+		endpoints.Subsets = []corev1.EndpointSubset{{}}
+	}
+
+	for _, ip := range ready {
+		setEndpointAddress(endpoints.Subsets, p.endpointAddress(ip), true)
+	}
+	for _, ip := range notReady {
+		setEndpointAddress(endpoints.Subsets, p.endpointAddress(ip), false)
+	}
+
+	err = p.claim(endpoints, ips)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	// kube-proxy ignores subsets without ports, so all subsets get the ports
+	// of the service.
+	if ports != nil {
+		for i := range endpoints.Subsets {
+			endpoints.Subsets[i].Ports = ports
+		}
+	}
+
+	err = p.writeEndpoints(original, endpoints)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(removed) != 0 {
+		_ = p.logger.Log("debug", "replaced IPs in endpoints", "namespace", namespace, "service", service, "old", JoinIPs(removed), "new", JoinIPs(ips))
+	} else {
+		_ = p.logger.Log("debug", "added IPs to endpoints", "namespace", namespace, "service", service, "ips", JoinIPs(ips))
+	}
+
+	return nil
+}
+
+// newEndpoints builds the Endpoints object of the given service in case it
+// does not exist yet. The labels of the service are copied like the
+// Kubernetes endpoints controller does. The service itself is optional.
+func (p *Updater) newEndpoints(namespace, service string) (*corev1.Endpoints, error) {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            service,
+			Namespace:       namespace,
+			OwnerReferences: p.ownerReferences(),
+		},
+	}
+
+	s, err := p.k8sClient.CoreV1().Services(namespace).Get(service, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return endpoints, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(s.Labels) != 0 {
+		endpoints.Labels = map[string]string{}
+		for k, v := range s.Labels {
+			endpoints.Labels[k] = v
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (p *Updater) deleteEndpoints(namespace, service string, ips []net.IP) error {
+	original, err := p.k8sClient.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	endpoints := original.DeepCopy()
+
+	// Other updater instances might have added the same IPs, so only the
+	// ones nobody else claims are removed.
+	ips, err = p.release(endpoints, ips)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	endpoints.Subsets = removeEndpointIPs(endpoints.Subsets, ips)
+
+	err = p.writeEndpoints(original, endpoints)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "removed IPs from endpoints", "namespace", namespace, "service", service, "ips", JoinIPs(ips))
+
+	return nil
+}
+
+// endpointAddress creates the address of the given IP, referencing the pod
+// backing it, if known.
+func (p *Updater) endpointAddress(ip net.IP) corev1.EndpointAddress {
+	a := corev1.EndpointAddress{IP: ip.String()}
+
+	if p.pod != nil {
+		a.TargetRef = p.podReference()
+		if p.pod.NodeName != "" {
+			nodeName := p.pod.NodeName
+			a.NodeName = &nodeName
+		}
+	}
+
+	return a
+}
+
+// ownerReferences returns the owner references of created objects, if any.
+func (p *Updater) ownerReferences() []metav1.OwnerReference {
+	if p.ownerReference == nil {
+		return nil
+	}
+
+	return []metav1.OwnerReference{*p.ownerReference}
+}
+
+// podReference returns the object reference of the pod backing the published
+// IPs, or nil if unknown.
+func (p *Updater) podReference() *corev1.ObjectReference {
+	if p.pod == nil {
+		return nil
+	}
+
+	return &corev1.ObjectReference{
+		Kind:      "Pod",
+		Name:      p.pod.Name,
+		Namespace: p.pod.Namespace,
+		UID:       types.UID(p.pod.UID),
+	}
+}
+
+// setEndpointAddress ensures the given address is listed in the ready or not
+// ready addresses of the given subsets. Addresses already listed are updated
+// and moved within their subset, new ones are added to the first subset.
+func setEndpointAddress(subsets []corev1.EndpointSubset, a corev1.EndpointAddress, ready bool) {
+	for i := range subsets {
+		for j, b := range subsets[i].Addresses {
+			if b.IP != a.IP {
+				continue
+			}
+			if ready {
+				subsets[i].Addresses[j] = a
+			} else {
+				subsets[i].Addresses = append(subsets[i].Addresses[:j], subsets[i].Addresses[j+1:]...)
+				subsets[i].NotReadyAddresses = append(subsets[i].NotReadyAddresses, a)
+			}
+			return
+		}
+
+		for j, b := range subsets[i].NotReadyAddresses {
+			if b.IP != a.IP {
+				continue
+			}
+			if ready {
+				subsets[i].NotReadyAddresses = append(subsets[i].NotReadyAddresses[:j], subsets[i].NotReadyAddresses[j+1:]...)
+				subsets[i].Addresses = append(subsets[i].Addresses, a)
+			} else {
+				subsets[i].NotReadyAddresses[j] = a
+			}
+			return
+		}
+	}
+
+	if ready {
+		subsets[0].Addresses = append(subsets[0].Addresses, a)
+	} else {
+		subsets[0].NotReadyAddresses = append(subsets[0].NotReadyAddresses, a)
+	}
+}
+
+// removeEndpointIPs removes the addresses of the given IPs from the given
+// subsets. Subsets left without addresses are dropped.
+func removeEndpointIPs(subsets []corev1.EndpointSubset, ips []net.IP) []corev1.EndpointSubset {
+	var kept []corev1.EndpointSubset
+	for _, subset := range subsets {
+		subset.Addresses = removeEndpointAddresses(subset.Addresses, ips)
+		subset.NotReadyAddresses = removeEndpointAddresses(subset.NotReadyAddresses, ips)
+
+		if len(subset.Addresses) == 0 && len(subset.NotReadyAddresses) == 0 {
+			continue
+		}
+
+		kept = append(kept, subset)
+	}
+
+	return kept
+}
+
+func removeEndpointAddresses(addresses []corev1.EndpointAddress, ips []net.IP) []corev1.EndpointAddress {
+	var kept []corev1.EndpointAddress
+	for _, a := range addresses {
+		if !containsIP(ips, a.IP) {
+			kept = append(kept, a)
+		}
+	}
+
+	return kept
+}
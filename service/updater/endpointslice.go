@@ -0,0 +1,272 @@
+package updater
+
+import (
+	"net"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/audit"
+)
+
+const (
+	// LabelManagedBy is the well known label marking the controller managing
+	// an EndpointSlice.
+	LabelManagedBy = "endpointslice.kubernetes.io/managed-by"
+	// ManagedBy is the value of LabelManagedBy of the EndpointSlices managed
+	// by the updater.
+	ManagedBy = "k8s-endpoint-updater"
+)
+
+// EndpointSliceName returns the name of the EndpointSlice managed by the
+// updater for the given service and address family. The discovery API we
+// use only knows a single address type, so each family gets its own slice.
+func EndpointSliceName(service, family string) string {
+	if family == FamilyIPv6 {
+		return service + "-" + ManagedBy + "-" + FamilyIPv6
+	}
+
+	return service + "-" + ManagedBy
+}
+
+// replaceEndpointSlice removes the given stale IPs from the EndpointSlices of
+// the given service and adds the given ready and not ready IPs within the same
+// write per address family.
+func (p *Updater) replaceEndpointSlice(namespace, service string, stale, ready, notReady []net.IP) error {
+	for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+		familyStale := filterFamily(stale, family)
+		familyReady := filterFamily(ready, family)
+		familyNotReady := filterFamily(notReady, family)
+		if len(familyStale) == 0 && len(familyReady) == 0 && len(familyNotReady) == 0 {
+			continue
+		}
+
+		err := p.replaceFamilyEndpointSlice(namespace, service, family, familyStale, familyReady, familyNotReady)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Updater) deleteEndpointSlice(namespace, service string, ips []net.IP) error {
+	for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+		familyIPs := filterFamily(ips, family)
+		if len(familyIPs) == 0 {
+			continue
+		}
+
+		err := p.deleteFamilyEndpointSlice(namespace, service, family, familyIPs)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Updater) replaceFamilyEndpointSlice(namespace, service, family string, stale, ready, notReady []net.IP) error {
+	ips := append(append([]net.IP{}, ready...), notReady...)
+
+	ports, err := p.endpointPorts(namespace, service)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	original, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Get(EndpointSliceName(service, family), metav1.GetOptions{})
+	if errors.IsNotFound(err) && len(ips) == 0 {
+		return nil
+	} else if errors.IsNotFound(err) {
+		addressType := discoveryv1alpha1.AddressTypeIP
+		slice := &discoveryv1alpha1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      EndpointSliceName(service, family),
+				Namespace: namespace,
+				Labels: map[string]string{
+					discoveryv1alpha1.LabelServiceName: service,
+					LabelManagedBy:                     ManagedBy,
+				},
+			},
+			AddressType: &addressType,
+		}
+		slice.OwnerReferences = p.ownerReferences()
+		slice.Endpoints = p.setSliceEndpoints(slice.Endpoints, ready, true)
+		slice.Endpoints = p.setSliceEndpoints(slice.Endpoints, notReady, false)
+		slice.Ports = toSlicePorts(ports)
+
+		err = p.claim(slice, ips)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		err = p.writeEndpointSlice(nil, slice)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		_ = p.logger.Log("debug", "created endpoint slice", "namespace", namespace, "service", service, "family", family, "ips", JoinIPs(ips))
+
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	slice := original.DeepCopy()
+
+	// Other updater instances might have added the same IPs, so only the
+	// stale ones nobody else claims are removed.
+	var removed []net.IP
+	if len(stale) != 0 {
+		removed, err = p.release(slice, stale)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		slice.Endpoints = removeSliceEndpoints(slice.Endpoints, removed)
+	}
+
+	slice.Endpoints = p.setSliceEndpoints(slice.Endpoints, ready, true)
+	slice.Endpoints = p.setSliceEndpoints(slice.Endpoints, notReady, false)
+	if ports != nil {
+		slice.Ports = toSlicePorts(ports)
+	}
+
+	err = p.claim(slice, ips)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(slice.Endpoints) == 0 {
+		return p.deleteSlice(namespace, service, family, original)
+	}
+
+	err = p.writeEndpointSlice(original, slice)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if len(removed) != 0 {
+		_ = p.logger.Log("debug", "replaced IPs in endpoint slice", "namespace", namespace, "service", service, "family", family, "old", JoinIPs(removed), "new", JoinIPs(ips))
+	} else {
+		_ = p.logger.Log("debug", "added IPs to endpoint slice", "namespace", namespace, "service", service, "family", family, "ips", JoinIPs(ips))
+	}
+
+	return nil
+}
+
+func (p *Updater) deleteFamilyEndpointSlice(namespace, service, family string, ips []net.IP) error {
+	original, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Get(EndpointSliceName(service, family), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	slice := original.DeepCopy()
+
+	// Other updater instances might have added the same IPs, so only the
+	// ones nobody else claims are removed.
+	ips, err = p.release(slice, ips)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	endpoints := removeSliceEndpoints(slice.Endpoints, ips)
+
+	// EndpointSlices without endpoints are useless, so we remove our slice
+	// entirely once the last IP is gone.
+	if len(endpoints) == 0 {
+		return p.deleteSlice(namespace, service, family, original)
+	}
+
+	slice.Endpoints = endpoints
+
+	err = p.writeEndpointSlice(original, slice)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "removed IPs from endpoint slice", "namespace", namespace, "service", service, "family", family, "ips", JoinIPs(ips))
+
+	return nil
+}
+
+// deleteSlice deletes the given EndpointSlice, e.g. because its last endpoint
+// got removed.
+func (p *Updater) deleteSlice(namespace, service, family string, original *discoveryv1alpha1.EndpointSlice) error {
+	if p.dryRun {
+		return p.printDryRun("delete", "endpointslice", namespace, original.Name, nil)
+	}
+
+	err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Delete(original.Name, &metav1.DeleteOptions{})
+	if !errors.IsNotFound(err) {
+		p.auditEndpointSlice(audit.OperationDelete, original, nil, "", err)
+	}
+	if errors.IsNotFound(err) {
+		// fall through
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = p.logger.Log("debug", "deleted endpoint slice", "namespace", namespace, "service", service, "family", family)
+
+	return nil
+}
+
+// removeSliceEndpoints removes the endpoints of the given IPs.
+func removeSliceEndpoints(endpoints []discoveryv1alpha1.Endpoint, ips []net.IP) []discoveryv1alpha1.Endpoint {
+	var kept []discoveryv1alpha1.Endpoint
+	for _, e := range endpoints {
+		if len(e.Addresses) != 0 && containsIP(ips, e.Addresses[0]) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+
+	return kept
+}
+
+// setSliceEndpoints ensures an endpoint with the given readiness exists for
+// each of the given IPs.
+func (p *Updater) setSliceEndpoints(endpoints []discoveryv1alpha1.Endpoint, ips []net.IP, ready bool) []discoveryv1alpha1.Endpoint {
+	for _, ip := range ips {
+		e := p.sliceEndpoint(ip, ready)
+
+		var found bool
+		for i := range endpoints {
+			if len(endpoints[i].Addresses) != 0 && endpoints[i].Addresses[0] == ip.String() {
+				endpoints[i] = e
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints
+}
+
+// sliceEndpoint creates the endpoint of the given IP, referencing the pod
+// backing it, if known.
+func (p *Updater) sliceEndpoint(ip net.IP, ready bool) discoveryv1alpha1.Endpoint {
+	e := discoveryv1alpha1.Endpoint{
+		Addresses: []string{ip.String()},
+		Conditions: discoveryv1alpha1.EndpointConditions{
+			Ready: &ready,
+		},
+		TargetRef: p.podReference(),
+	}
+
+	if p.pod != nil && p.pod.NodeName != "" {
+		e.Topology = map[string]string{
+			corev1.LabelHostname: p.pod.NodeName,
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,177 @@
+package updater
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationService is the annotation of the KVM pod holding the comma
+	// separated names of the services its endpoint IPs are published for.
+	// Services outside of the namespace of the pod are given as
+	// namespace/service. It is set next to FinalizerCleanup, so that the
+	// cleanup knows where to remove them from.
+	AnnotationService = "endpoint.kvm.giantswarm.io/service"
+	// FinalizerCleanup is the finalizer of the KVM pod keeping it around until
+	// its endpoint IPs have been removed.
+	FinalizerCleanup = "endpoint.kvm.giantswarm.io/cleanup"
+)
+
+// AddFinalizer puts FinalizerCleanup on the given pod, so that the pod is not
+// removed before DeleteOwned and RemoveFinalizer ran for it. The given services
+// are recorded as AnnotationService.
+func (p *Updater) AddFinalizer(ctx context.Context, namespace, podName string, services []string) error {
+	service := strings.Join(services, ",")
+
+	err := p.retryOnConflict(ctx, "pod", namespace, podName, func() error {
+		pod, err := p.k8sClient.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if containsString(pod.Finalizers, FinalizerCleanup) && pod.Annotations[AnnotationService] == service {
+			return nil
+		}
+
+		if !containsString(pod.Finalizers, FinalizerCleanup) {
+			pod.Finalizers = append(pod.Finalizers, FinalizerCleanup)
+		}
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[AnnotationService] = service
+
+		err = p.updatePod(pod)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// RemoveFinalizer removes FinalizerCleanup from the given pod, which lets
+// Kubernetes remove the pod once it is deleted.
+func (p *Updater) RemoveFinalizer(ctx context.Context, namespace, podName string) error {
+	err := p.retryOnConflict(ctx, "pod", namespace, podName, func() error {
+		pod, err := p.k8sClient.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if !containsString(pod.Finalizers, FinalizerCleanup) {
+			return nil
+		}
+
+		pod.Finalizers = removeString(pod.Finalizers, FinalizerCleanup)
+
+		err = p.updatePod(pod)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// DeleteOwned removes the IPs the given owner added to the endpoints of the
+// given service, like the updater instance of the owner would using Delete.
+// The removed IPs are returned. Owners are only known for IPs added with
+// ownership tracking enabled.
+func (p *Updater) DeleteOwned(ctx context.Context, namespace, service, owner string) ([]net.IP, error) {
+	ips, err := p.ownedIPs(namespace, service, owner)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if len(ips) == 0 {
+		return nil, nil
+	}
+
+	u := *p
+	u.owner = owner
+
+	err = u.Delete(ctx, namespace, service, ips)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ips, nil
+}
+
+// ownedIPs returns the IPs the given owner claims on any of the objects
+// managed for the given service.
+func (p *Updater) ownedIPs(namespace, service, owner string) ([]net.IP, error) {
+	var metas []metav1.Object
+
+	if p.kind == KindEndpoints || p.kind == KindBoth {
+		endpoints, err := p.k8sClient.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			// fall through
+		} else if err != nil {
+			return nil, microerror.Mask(err)
+		} else {
+			metas = append(metas, endpoints)
+		}
+	}
+
+	if p.kind == KindEndpointSlice || p.kind == KindBoth {
+		for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+			slice, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Get(EndpointSliceName(service, family), metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			metas = append(metas, slice)
+		}
+	}
+
+	var ips []net.IP
+	for _, meta := range metas {
+		o, err := readOwners(meta)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		for _, s := range o[owner] {
+			ip := net.ParseIP(s)
+			if ip != nil && !containsIP(ips, s) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips, nil
+}
+
+func (p *Updater) updatePod(pod *corev1.Pod) error {
+	if p.dryRun {
+		return p.printDryRun("update", "pod", pod.Namespace, pod.Name, pod)
+	}
+
+	_, err := p.k8sClient.CoreV1().Pods(pod.Namespace).Update(pod)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package updater
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	// FamilyIPv4 is the IPv4 address family.
+	FamilyIPv4 = "ipv4"
+	// FamilyIPv6 is the IPv6 address family.
+	FamilyIPv6 = "ipv6"
+)
+
+// Family returns the address family of the given IP.
+func Family(ip net.IP) string {
+	if ip.To4() != nil {
+		return FamilyIPv4
+	}
+
+	return FamilyIPv6
+}
+
+func filterFamily(ips []net.IP, family string) []net.IP {
+	var filtered []net.IP
+	for _, ip := range ips {
+		if Family(ip) == family {
+			filtered = append(filtered, ip)
+		}
+	}
+
+	return filtered
+}
+
+func containsIP(ips []net.IP, s string) bool {
+	for _, ip := range ips {
+		if ip.String() == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JoinIPs returns the given IPs as comma separated list, the way they are
+// logged and printed.
+func JoinIPs(ips []net.IP) string {
+	var s []string
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+
+	return strings.Join(s, ",")
+}
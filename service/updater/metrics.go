@@ -0,0 +1,27 @@
+package updater
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/metrics"
+)
+
+const (
+	prometheusSubsystem = "updater"
+)
+
+var (
+	updateDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "update_duration_seconds",
+			Help:      "Time taken to write endpoint information to Kubernetes.",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(updateDuration)
+}
@@ -0,0 +1,252 @@
+package updater
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AnnotationOwners is the annotation of Endpoints and EndpointSlices
+	// recording which updater instance added which IPs. It holds a JSON
+	// object mapping owners to lists of IPs.
+	AnnotationOwners = "endpoint.kvm.giantswarm.io/owners"
+	// AnnotationAdded is the annotation of Endpoints and EndpointSlices
+	// recording when the owned IPs were added. It holds a JSON object mapping
+	// IPs to RFC 3339 timestamps.
+	AnnotationAdded = "endpoint.kvm.giantswarm.io/added"
+)
+
+// owners maps updater instances, identified e.g. by their pod UID, to the IPs
+// they added.
+type owners map[string][]string
+
+// Owners returns the IPs claimed by each owner on the given object according
+// to the ownership annotation.
+func Owners(meta metav1.Object) (map[string][]string, error) {
+	o, err := readOwners(meta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return o, nil
+}
+
+func readOwners(meta metav1.Object) (owners, error) {
+	o := owners{}
+
+	v, ok := meta.GetAnnotations()[AnnotationOwners]
+	if !ok || v == "" {
+		return o, nil
+	}
+
+	err := json.Unmarshal([]byte(v), &o)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "invalid annotation %#q: %s", AnnotationOwners, err.Error())
+	}
+
+	return o, nil
+}
+
+func writeOwners(meta metav1.Object, o owners) error {
+	err := writeAnnotation(meta, AnnotationOwners, o, len(o) == 0)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// readAdded returns when the owned IPs of the given object were added, keyed
+// by IP.
+func readAdded(meta metav1.Object) (map[string]string, error) {
+	a := map[string]string{}
+
+	v, ok := meta.GetAnnotations()[AnnotationAdded]
+	if !ok || v == "" {
+		return a, nil
+	}
+
+	err := json.Unmarshal([]byte(v), &a)
+	if err != nil {
+		return nil, microerror.Maskf(executionFailedError, "invalid annotation %#q: %s", AnnotationAdded, err.Error())
+	}
+
+	return a, nil
+}
+
+func writeAdded(meta metav1.Object, a map[string]string) error {
+	err := writeAnnotation(meta, AnnotationAdded, a, len(a) == 0)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// writeAnnotation sets the given annotation to the JSON encoded value, or
+// removes it in case the value is empty.
+func writeAnnotation(meta metav1.Object, key string, value interface{}, empty bool) error {
+	annotations := meta.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if empty {
+		delete(annotations, key)
+	} else {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		annotations[key] = string(b)
+	}
+
+	meta.SetAnnotations(annotations)
+
+	return nil
+}
+
+// claim records the given IPs as added by the given owner.
+func (o owners) claim(owner string, ips []net.IP) {
+	for _, ip := range ips {
+		if !containsString(o[owner], ip.String()) {
+			o[owner] = append(o[owner], ip.String())
+		}
+	}
+
+	sort.Strings(o[owner])
+}
+
+// release drops the claims of the given owner on the given IPs. The IPs which
+// were claimed by the owner and are not claimed by anyone else anymore are
+// returned. Only those may be removed.
+func (o owners) release(owner string, ips []net.IP) []net.IP {
+	var released []net.IP
+	for _, ip := range ips {
+		if !containsString(o[owner], ip.String()) {
+			continue
+		}
+
+		o[owner] = removeString(o[owner], ip.String())
+
+		var claimed bool
+		for _, others := range o {
+			if containsString(others, ip.String()) {
+				claimed = true
+				break
+			}
+		}
+		if !claimed {
+			released = append(released, ip)
+		}
+	}
+
+	if len(o[owner]) == 0 {
+		delete(o, owner)
+	}
+
+	return released
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	var result []string
+	for _, e := range list {
+		if e != s {
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+// claim records the given IPs as added by this updater instance on the given
+// object, along with the time they were first added. Nothing is recorded when
+// ownership tracking is disabled.
+func (p *Updater) claim(meta metav1.Object, ips []net.IP) error {
+	if p.owner == "" {
+		return nil
+	}
+
+	o, err := readOwners(meta)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	o.claim(p.owner, ips)
+
+	err = writeOwners(meta, o)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	a, err := readAdded(meta)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, ip := range ips {
+		if _, ok := a[ip.String()]; !ok {
+			a[ip.String()] = now
+		}
+	}
+
+	err = writeAdded(meta, a)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// release drops the claims of this updater instance on the given IPs and
+// returns the IPs which may be removed from the given object. All IPs may be
+// removed when ownership tracking is disabled.
+func (p *Updater) release(meta metav1.Object, ips []net.IP) ([]net.IP, error) {
+	if p.owner == "" {
+		return ips, nil
+	}
+
+	o, err := readOwners(meta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	released := o.release(p.owner, ips)
+
+	err = writeOwners(meta, o)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	a, err := readAdded(meta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for _, ip := range released {
+		delete(a, ip.String())
+	}
+
+	err = writeAdded(meta, a)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return released, nil
+}
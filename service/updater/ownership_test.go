@@ -0,0 +1,283 @@
+package updater
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_owners_claim(t *testing.T) {
+	testCases := []struct {
+		name     string
+		owners   owners
+		owner    string
+		ips      []net.IP
+		expected owners
+	}{
+		{
+			name:     "case 0: first claim",
+			owners:   owners{},
+			owner:    "a",
+			ips:      parseIPs("10.0.0.2", "10.0.0.1"),
+			expected: owners{"a": {"10.0.0.1", "10.0.0.2"}},
+		},
+		{
+			name:     "case 1: claiming again does not duplicate IPs",
+			owners:   owners{"a": {"10.0.0.1"}},
+			owner:    "a",
+			ips:      parseIPs("10.0.0.1", "10.0.0.2"),
+			expected: owners{"a": {"10.0.0.1", "10.0.0.2"}},
+		},
+		{
+			name:     "case 2: shared claim of another owner",
+			owners:   owners{"a": {"10.0.0.1"}},
+			owner:    "b",
+			ips:      parseIPs("10.0.0.1"),
+			expected: owners{"a": {"10.0.0.1"}, "b": {"10.0.0.1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.owners.claim(tc.owner, tc.ips)
+
+			if !reflect.DeepEqual(tc.owners, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, tc.owners)
+			}
+		})
+	}
+}
+
+func Test_owners_release(t *testing.T) {
+	testCases := []struct {
+		name             string
+		owners           owners
+		owner            string
+		ips              []net.IP
+		expectedOwners   owners
+		expectedReleased []string
+	}{
+		{
+			name:             "case 0: releasing the only claim",
+			owners:           owners{"a": {"10.0.0.1"}},
+			owner:            "a",
+			ips:              parseIPs("10.0.0.1"),
+			expectedOwners:   owners{},
+			expectedReleased: []string{"10.0.0.1"},
+		},
+		{
+			name:             "case 1: shared claims are kept",
+			owners:           owners{"a": {"10.0.0.1"}, "b": {"10.0.0.1"}},
+			owner:            "a",
+			ips:              parseIPs("10.0.0.1"),
+			expectedOwners:   owners{"b": {"10.0.0.1"}},
+			expectedReleased: nil,
+		},
+		{
+			name:             "case 2: partial release keeps the other IPs of the owner",
+			owners:           owners{"a": {"10.0.0.1", "10.0.0.2"}},
+			owner:            "a",
+			ips:              parseIPs("10.0.0.1"),
+			expectedOwners:   owners{"a": {"10.0.0.2"}},
+			expectedReleased: []string{"10.0.0.1"},
+		},
+		{
+			name:             "case 3: IPs claimed by others only are not released",
+			owners:           owners{"b": {"10.0.0.1"}},
+			owner:            "a",
+			ips:              parseIPs("10.0.0.1"),
+			expectedOwners:   owners{"b": {"10.0.0.1"}},
+			expectedReleased: nil,
+		},
+		{
+			name:             "case 4: unclaimed IPs are not released",
+			owners:           owners{},
+			owner:            "a",
+			ips:              parseIPs("10.0.0.1"),
+			expectedOwners:   owners{},
+			expectedReleased: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			released := tc.owners.release(tc.owner, tc.ips)
+
+			if !reflect.DeepEqual(tc.owners, tc.expectedOwners) {
+				t.Fatalf("expected owners %v, got %v", tc.expectedOwners, tc.owners)
+			}
+			if !reflect.DeepEqual(ipStrings(released), tc.expectedReleased) {
+				t.Fatalf("expected released %v, got %v", tc.expectedReleased, ipStrings(released))
+			}
+		})
+	}
+}
+
+func Test_readOwners(t *testing.T) {
+	testCases := []struct {
+		name         string
+		annotations  map[string]string
+		expected     owners
+		errorMatcher func(error) bool
+	}{
+		{
+			name:         "case 0: missing annotation",
+			annotations:  nil,
+			expected:     owners{},
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 1: empty annotation",
+			annotations:  map[string]string{AnnotationOwners: ""},
+			expected:     owners{},
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 2: shared claims",
+			annotations:  map[string]string{AnnotationOwners: `{"a":["10.0.0.1"],"b":["10.0.0.1","10.0.0.2"]}`},
+			expected:     owners{"a": {"10.0.0.1"}, "b": {"10.0.0.1", "10.0.0.2"}},
+			errorMatcher: nil,
+		},
+		{
+			name:         "case 3: malformed JSON",
+			annotations:  map[string]string{AnnotationOwners: `{"a":["10.0.0.1"]`},
+			expected:     nil,
+			errorMatcher: IsExecutionFailed,
+		},
+		{
+			name:         "case 4: wrong type",
+			annotations:  map[string]string{AnnotationOwners: `["10.0.0.1"]`},
+			expected:     nil,
+			errorMatcher: IsExecutionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := &metav1.ObjectMeta{Annotations: tc.annotations}
+
+			o, err := readOwners(meta)
+
+			switch {
+			case err == nil && tc.errorMatcher == nil:
+				// correct; carry on
+			case err != nil && tc.errorMatcher == nil:
+				t.Fatalf("expected no error, got %#v", err)
+			case err == nil && tc.errorMatcher != nil:
+				t.Fatalf("expected error, got nil")
+			case !tc.errorMatcher(err):
+				t.Fatalf("expected matching error, got %#v", err)
+			}
+
+			if !reflect.DeepEqual(o, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, o)
+			}
+		})
+	}
+}
+
+func Test_Updater_claimRelease(t *testing.T) {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				AnnotationOwners: `{"b":["10.0.0.1"]}`,
+			},
+		},
+	}
+
+	p := &Updater{owner: "a"}
+
+	err := p.claim(endpoints, parseIPs("10.0.0.1", "10.0.0.2"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	expected := `{"a":["10.0.0.1","10.0.0.2"],"b":["10.0.0.1"]}`
+	if endpoints.Annotations[AnnotationOwners] != expected {
+		t.Fatalf("expected owners %s, got %s", expected, endpoints.Annotations[AnnotationOwners])
+	}
+	added, err := readAdded(endpoints)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	if len(added) != 2 {
+		t.Fatalf("expected 2 added IPs, got %v", added)
+	}
+
+	released, err := p.release(endpoints, parseIPs("10.0.0.1", "10.0.0.2"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	// 10.0.0.1 is still claimed by b, so only 10.0.0.2 may be removed.
+	if !reflect.DeepEqual(ipStrings(released), []string{"10.0.0.2"}) {
+		t.Fatalf("expected released %v, got %v", []string{"10.0.0.2"}, ipStrings(released))
+	}
+	expected = `{"b":["10.0.0.1"]}`
+	if endpoints.Annotations[AnnotationOwners] != expected {
+		t.Fatalf("expected owners %s, got %s", expected, endpoints.Annotations[AnnotationOwners])
+	}
+	added, err = readAdded(endpoints)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	if _, ok := added["10.0.0.2"]; ok {
+		t.Fatalf("expected 10.0.0.2 to be dropped from the added IPs, got %v", added)
+	}
+
+	released, err = (&Updater{owner: "b"}).release(endpoints, parseIPs("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	if !reflect.DeepEqual(ipStrings(released), []string{"10.0.0.1"}) {
+		t.Fatalf("expected released %v, got %v", []string{"10.0.0.1"}, ipStrings(released))
+	}
+	if _, ok := endpoints.Annotations[AnnotationOwners]; ok {
+		t.Fatalf("expected owners annotation to be removed, got %s", endpoints.Annotations[AnnotationOwners])
+	}
+}
+
+func Test_Updater_release_Malformed(t *testing.T) {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				AnnotationOwners: "not json",
+			},
+		},
+	}
+
+	_, err := (&Updater{owner: "a"}).release(endpoints, parseIPs("10.0.0.1"))
+	if !IsExecutionFailed(err) {
+		t.Fatalf("expected execution failed error, got %#v", err)
+	}
+
+	// Without ownership tracking the annotation is not read at all.
+	released, err := (&Updater{}).release(endpoints, parseIPs("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	if !reflect.DeepEqual(ipStrings(released), []string{"10.0.0.1"}) {
+		t.Fatalf("expected released %v, got %v", []string{"10.0.0.1"}, ipStrings(released))
+	}
+}
+
+func parseIPs(s ...string) []net.IP {
+	var ips []net.IP
+	for _, ip := range s {
+		ips = append(ips, net.ParseIP(ip))
+	}
+
+	return ips
+}
+
+func ipStrings(ips []net.IP) []string {
+	var s []string
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+
+	return s
+}
@@ -0,0 +1,117 @@
+package updater
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ParsePort parses endpoint ports given as name:port[:protocol], e.g.
+// https:443:TCP. The protocol defaults to TCP.
+func ParsePort(s string) (corev1.EndpointPort, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return corev1.EndpointPort{}, microerror.Maskf(invalidConfigError, "port %#q must be formatted as name:port[:protocol]", s)
+	}
+
+	number, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil || number < 1 || number > 65535 {
+		return corev1.EndpointPort{}, microerror.Maskf(invalidConfigError, "port %#q must have a port number between 1 and 65535", s)
+	}
+
+	protocol := corev1.ProtocolTCP
+	if len(parts) == 3 {
+		protocol = corev1.Protocol(strings.ToUpper(parts[2]))
+	}
+	switch protocol {
+	case corev1.ProtocolSCTP, corev1.ProtocolTCP, corev1.ProtocolUDP:
+	default:
+		return corev1.EndpointPort{}, microerror.Maskf(invalidConfigError, "port %#q must have protocol TCP, UDP or SCTP", s)
+	}
+
+	port := corev1.EndpointPort{
+		Name:     parts[0],
+		Port:     int32(number),
+		Protocol: protocol,
+	}
+
+	return port, nil
+}
+
+// endpointPorts returns the configured endpoint ports, or the ones derived
+// from the service spec in case none are configured.
+func (p *Updater) endpointPorts(namespace, service string) ([]corev1.EndpointPort, error) {
+	if len(p.ports) != 0 {
+		return p.ports, nil
+	}
+
+	ports, err := p.servicePorts(namespace, service)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return ports, nil
+}
+
+// servicePorts derives the endpoint ports from the spec of the given service.
+// Numeric target ports are used as is. Named target ports cannot be resolved
+// without a backing pod, so an invalidConfigError is returned for them, rather
+// than guessing a port. In case the service does not exist no ports are
+// returned.
+func (p *Updater) servicePorts(namespace, service string) ([]corev1.EndpointPort, error) {
+	s, err := p.k8sClient.CoreV1().Services(namespace).Get(service, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_ = p.logger.Log("warning", "service not found, publishing endpoints without ports", "namespace", namespace, "service", service)
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var ports []corev1.EndpointPort
+	for _, sp := range s.Spec.Ports {
+		if sp.TargetPort.Type == intstr.String {
+			return nil, microerror.Maskf(invalidConfigError, "service %#q has the named target port %#q which cannot be resolved, the endpoint ports have to be given explicitly, e.g. using --service.kubernetes.cluster.ports", namespace+"/"+service, sp.TargetPort.StrVal)
+		}
+
+		port := sp.Port
+		if sp.TargetPort.IntVal != 0 {
+			port = sp.TargetPort.IntVal
+		}
+
+		protocol := sp.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+
+		ports = append(ports, corev1.EndpointPort{
+			Name:     sp.Name,
+			Port:     port,
+			Protocol: protocol,
+		})
+	}
+
+	return ports, nil
+}
+
+func toSlicePorts(ports []corev1.EndpointPort) []discoveryv1alpha1.EndpointPort {
+	var slicePorts []discoveryv1alpha1.EndpointPort
+	for _, port := range ports {
+		name := port.Name
+		protocol := port.Protocol
+		number := port.Port
+
+		slicePorts = append(slicePorts, discoveryv1alpha1.EndpointPort{
+			Name:     &name,
+			Protocol: &protocol,
+			Port:     &number,
+		})
+	}
+
+	return slicePorts
+}
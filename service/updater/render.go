@@ -0,0 +1,73 @@
+package updater
+
+import (
+	"context"
+	"net"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// Render returns the Endpoints, EndpointSlices or both, depending on the
+// configured kind, which Create would write for the given service in case
+// they did not exist yet. Nothing is read from or written to the cluster, the
+// objects are built against an in-memory fake instead. The update annotation
+// is dropped, since it would change on every render.
+func (p *Updater) Render(namespace, service string, ips []net.IP) ([]runtime.Object, error) {
+	u := *p
+	u.createMissing = true
+	u.dryRun = false
+	u.k8sClient = fake.NewSimpleClientset()
+	u.patchStrategy = PatchStrategyUpdate
+	u.serverSideApply = false
+	u.versions = newResourceVersions()
+
+	// The fake clientset never blocks, so there is nothing to cancel.
+	err := u.replace(context.Background(), namespace, service, nil, ips, nil)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var objects []runtime.Object
+
+	if u.kind == KindEndpoints || u.kind == KindBoth {
+		endpoints, err := u.k8sClient.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+
+		endpoints.TypeMeta = metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Endpoints",
+		}
+		delete(endpoints.Annotations, AnnotationUpdated)
+
+		objects = append(objects, endpoints)
+	}
+
+	if u.kind == KindEndpointSlice || u.kind == KindBoth {
+		for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+			slice, err := u.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Get(EndpointSliceName(service, family), metav1.GetOptions{})
+			if errors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return nil, microerror.Mask(err)
+			}
+
+			slice.TypeMeta = metav1.TypeMeta{
+				APIVersion: discoveryv1alpha1.SchemeGroupVersion.String(),
+				Kind:       "EndpointSlice",
+			}
+			delete(slice.Annotations, AnnotationUpdated)
+
+			objects = append(objects, slice)
+		}
+	}
+
+	return objects, nil
+}
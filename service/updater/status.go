@@ -0,0 +1,143 @@
+package updater
+
+import (
+	"sort"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AddressStatus describes a single address currently published for a
+// service.
+type AddressStatus struct {
+	// Added is the time the address was added by an updater instance. It is
+	// zero in case the address was not added by us or ownership tracking was
+	// disabled.
+	Added time.Time
+	IP    string
+	// Owners are the updater instances which added the address, according to
+	// the ownership annotation.
+	Owners []string
+	Ready  bool
+	// Resource is the object holding the address, formatted as
+	// resource/name.
+	Resource string
+}
+
+// Status returns the addresses currently published for the given service in
+// the Endpoints, EndpointSlices or both, depending on the configured kind.
+// Objects which do not exist are skipped.
+func (p *Updater) Status(namespace, service string) ([]AddressStatus, error) {
+	var statuses []AddressStatus
+
+	if p.kind == KindEndpoints || p.kind == KindBoth {
+		addresses, err := p.endpointsStatus(namespace, service)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		statuses = append(statuses, addresses...)
+	}
+
+	if p.kind == KindEndpointSlice || p.kind == KindBoth {
+		for _, family := range []string{FamilyIPv4, FamilyIPv6} {
+			addresses, err := p.endpointSliceStatus(namespace, EndpointSliceName(service, family))
+			if err != nil {
+				return nil, microerror.Mask(err)
+			}
+			statuses = append(statuses, addresses...)
+		}
+	}
+
+	return statuses, nil
+}
+
+func (p *Updater) endpointsStatus(namespace, service string) ([]AddressStatus, error) {
+	endpoints, err := p.k8sClient.CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var addresses []AddressStatus
+	for _, subset := range endpoints.Subsets {
+		for _, a := range subset.Addresses {
+			addresses = append(addresses, AddressStatus{IP: a.IP, Ready: true})
+		}
+		for _, a := range subset.NotReadyAddresses {
+			addresses = append(addresses, AddressStatus{IP: a.IP, Ready: false})
+		}
+	}
+
+	addresses, err = addressStatuses(endpoints, "endpoints/"+endpoints.Name, addresses)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return addresses, nil
+}
+
+func (p *Updater) endpointSliceStatus(namespace, name string) ([]AddressStatus, error) {
+	slice, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	var addresses []AddressStatus
+	for _, e := range slice.Endpoints {
+		for _, ip := range e.Addresses {
+			addresses = append(addresses, AddressStatus{IP: ip, Ready: isReady(e)})
+		}
+	}
+
+	addresses, err = addressStatuses(slice, "endpointslice/"+slice.Name, addresses)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return addresses, nil
+}
+
+// addressStatuses completes the given addresses of the given object with
+// their owners and the time they were added.
+func addressStatuses(meta metav1.Object, resource string, addresses []AddressStatus) ([]AddressStatus, error) {
+	o, err := readOwners(meta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	a, err := readAdded(meta)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	for i := range addresses {
+		addresses[i].Resource = resource
+
+		for owner, ips := range o {
+			if containsString(ips, addresses[i].IP) {
+				addresses[i].Owners = append(addresses[i].Owners, owner)
+			}
+		}
+		sort.Strings(addresses[i].Owners)
+
+		if v, ok := a[addresses[i].IP]; ok {
+			t, err := time.Parse(time.RFC3339, v)
+			if err == nil {
+				addresses[i].Added = t
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+// isReady returns whether the given endpoint is ready. Endpoints without
+// condition are ready as defined by the discovery API.
+func isReady(e discoveryv1alpha1.Endpoint) bool {
+	return e.Conditions.Ready == nil || *e.Conditions.Ready
+}
@@ -1,26 +1,93 @@
 package updater
 
 import (
+	"context"
 	"github.com/giantswarm/microerror"
 	"github.com/giantswarm/micrologger"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"time"
 
 	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/audit"
+	"github.com/giantswarm/k8s-endpoint-updater/service/provider"
+	"github.com/giantswarm/k8s-endpoint-updater/service/tracing"
 )
 
 const (
-	annotationIp = "endpoint.kvm.giantswarm.io/ip"
+	// AnnotationHeartbeat is the annotation holding the time the endpoint IP
+	// was last asserted, formatted as RFC3339.
+	AnnotationHeartbeat = "endpoint.kvm.giantswarm.io/heartbeat"
+	// AnnotationIP is the annotation holding the endpoint IP.
+	AnnotationIP = "endpoint.kvm.giantswarm.io/ip"
+)
+
+const (
+	// KindAnnotation publishes endpoint IPs as annotations of the KVM pod.
+	KindAnnotation = "annotation"
+	// KindBoth publishes endpoint IPs using Endpoints and EndpointSlices.
+	KindBoth = "both"
+	// KindEndpoints publishes endpoint IPs using legacy v1 Endpoints.
+	KindEndpoints = "endpoints"
+	// KindEndpointSlice publishes endpoint IPs using EndpointSlices.
+	KindEndpointSlice = "endpointslice"
 )
 
 // Config represents the configuration used to create a new updater.
 type Config struct {
 	// Dependencies.
+
+	// AuditLog optionally records all mutations of Endpoints and
+	// EndpointSlices.
+	AuditLog  *audit.Log
 	K8sClient kubernetes.Interface
 	Logger    micrologger.Logger
+
+	// Settings.
+
+	// CreateMissing creates the Endpoints object of the service in case it
+	// does not exist yet, instead of failing.
+	CreateMissing bool
+	// DryRun prints the requests which would be sent to Writer instead of
+	// mutating the cluster. Read requests are still sent.
+	DryRun bool
+	// Kind defines which resources Create and Delete manage. One of
+	// KindEndpoints, KindEndpointSlice or KindBoth.
+	Kind string
+	// Owner identifies this updater instance, e.g. by its pod UID. When set,
+	// the IPs added by Create are recorded on the managed objects and Delete
+	// only removes IPs added by this instance and not claimed by any other.
+	Owner string
+	// OwnerReference is set on the Endpoints and EndpointSlices created by
+	// Create, so that they are garbage collected once the owner is gone. The
+	// owner has to live in the namespace of the service.
+	OwnerReference *metav1.OwnerReference
+	// PatchStrategy defines how existing Endpoints and EndpointSlices are
+	// written. One of PatchStrategyJSONPatch, PatchStrategyMerge or
+	// PatchStrategyUpdate. It does not apply to server-side apply.
+	PatchStrategy string
+	// Pod optionally describes the pod backing the published IPs. Published
+	// addresses then reference it via TargetRef and NodeName.
+	Pod *provider.PodInfo
+	// Ports are the endpoint ports written by Create. The ports are derived
+	// from the spec of the service when empty.
+	Ports []corev1.EndpointPort
+	// ServerSideApply writes Endpoints and EndpointSlices using server-side
+	// apply with FieldManager, instead of updating them.
+	ServerSideApply bool
+	// Writer is where requests are printed to in dry-run mode.
+	Writer io.Writer
 }
 
 // DefaultConfig provides a default configuration to create a new updater
@@ -28,9 +95,34 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		// Dependencies.
+		AuditLog:  nil,
 		K8sClient: nil,
 		Logger:    nil,
+
+		// Settings.
+		CreateMissing:   false,
+		DryRun:          false,
+		Kind:            KindEndpoints,
+		Owner:           "",
+		OwnerReference:  nil,
+		PatchStrategy:   PatchStrategyUpdate,
+		Pod:             nil,
+		Ports:           nil,
+		ServerSideApply: false,
+		Writer:          os.Stdout,
+	}
+}
+
+// ResourceKind returns the Config.Kind for commands accepting KindAnnotation
+// along with the resource kinds, and whether the given kind manages resources
+// at all. The annotation kind does not, so the default kind is returned along
+// with false.
+func ResourceKind(kind string) (string, bool) {
+	if kind == KindAnnotation {
+		return DefaultConfig().Kind, false
 	}
+
+	return kind, true
 }
 
 // New creates a new updater.
@@ -43,10 +135,46 @@ func New(config Config) (*Updater, error) {
 		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
 	}
 
+	// Settings.
+	switch config.Kind {
+	case KindBoth, KindEndpoints, KindEndpointSlice:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.Kind must be one of %#q, %#q or %#q", KindEndpoints, KindEndpointSlice, KindBoth)
+	}
+
+	switch config.PatchStrategy {
+	case PatchStrategyJSONPatch, PatchStrategyMerge, PatchStrategyUpdate:
+	default:
+		return nil, microerror.Maskf(invalidConfigError, "config.PatchStrategy must be one of %#q, %#q or %#q", PatchStrategyJSONPatch, PatchStrategyMerge, PatchStrategyUpdate)
+	}
+	if config.ServerSideApply && config.PatchStrategy != PatchStrategyUpdate {
+		return nil, microerror.Maskf(invalidConfigError, "config.PatchStrategy must be %#q with config.ServerSideApply", PatchStrategyUpdate)
+	}
+
+	if config.DryRun && config.Writer == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Writer must not be empty in dry-run mode")
+	}
+
 	newUpdater := &Updater{
 		// Dependencies.
+		auditLog:  config.AuditLog,
 		k8sClient: config.K8sClient,
 		logger:    config.Logger,
+
+		// Internals.
+		versions: newResourceVersions(),
+
+		// Settings.
+		createMissing:   config.CreateMissing,
+		dryRun:          config.DryRun,
+		kind:            config.Kind,
+		owner:           config.Owner,
+		ownerReference:  config.OwnerReference,
+		patchStrategy:   config.PatchStrategy,
+		pod:             config.Pod,
+		ports:           config.Ports,
+		serverSideApply: config.ServerSideApply,
+		writer:          config.Writer,
 	}
 
 	return newUpdater, nil
@@ -54,20 +182,207 @@ func New(config Config) (*Updater, error) {
 
 type Updater struct {
 	// Dependencies.
+	auditLog  *audit.Log
 	k8sClient kubernetes.Interface
 	logger    micrologger.Logger
+
+	// Internals.
+	versions *resourceVersions
+
+	// Settings.
+	createMissing   bool
+	dryRun          bool
+	kind            string
+	owner           string
+	ownerReference  *metav1.OwnerReference
+	patchStrategy   string
+	pod             *provider.PodInfo
+	ports           []corev1.EndpointPort
+	serverSideApply bool
+	writer          io.Writer
+}
+
+// Create adds the given IPs to the endpoints of the given service, using
+// Endpoints, EndpointSlices or both, depending on the configured kind.
+func (p *Updater) Create(ctx context.Context, namespace, service string, ips []net.IP) error {
+	start := time.Now()
+
+	err := p.replace(ctx, namespace, service, nil, ips, nil)
+	if err != nil {
+		updateDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		return microerror.Mask(err)
+	}
+	updateDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+// CreateNotReady works like Create, but publishes the given IPs as not ready,
+// so that no traffic is routed to them yet. Ready IPs are demoted. Calling
+// Create afterwards promotes them.
+func (p *Updater) CreateNotReady(ctx context.Context, namespace, service string, ips []net.IP) error {
+	start := time.Now()
+
+	err := p.replace(ctx, namespace, service, nil, nil, ips)
+	if err != nil {
+		updateDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		return microerror.Mask(err)
+	}
+	updateDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+// Replace removes the given stale IPs and adds the given ready and not ready
+// IPs to the endpoints of the given service. Both happen within a single write
+// per object, so that the service never lacks endpoints while its IPs change,
+// e.g. because the guest VM got rebooted onto another IP.
+func (p *Updater) Replace(ctx context.Context, namespace, service string, stale, ready, notReady []net.IP) error {
+	start := time.Now()
+
+	err := p.replace(ctx, namespace, service, stale, ready, notReady)
+	if err != nil {
+		updateDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		return microerror.Mask(err)
+	}
+	updateDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+// Delete removes the given IPs from the endpoints of the given service, using
+// Endpoints, EndpointSlices or both, depending on the configured kind.
+func (p *Updater) Delete(ctx context.Context, namespace, service string, ips []net.IP) error {
+	start := time.Now()
+
+	err := p.delete(ctx, namespace, service, ips)
+	if err != nil {
+		updateDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		return microerror.Mask(err)
+	}
+	updateDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+func (p *Updater) replace(ctx context.Context, namespace, service string, stale, ready, notReady []net.IP) error {
+	if p.kind == KindEndpoints || p.kind == KindBoth {
+		err := p.retryOnConflict(ctx, "endpoints", namespace, service, func() error {
+			return p.replaceEndpoints(namespace, service, stale, ready, notReady)
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if p.kind == KindEndpointSlice || p.kind == KindBoth {
+		err := p.retryOnConflict(ctx, "endpointslice", namespace, service, func() error {
+			return p.replaceEndpointSlice(namespace, service, stale, ready, notReady)
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Updater) delete(ctx context.Context, namespace, service string, ips []net.IP) error {
+	if p.kind == KindEndpoints || p.kind == KindBoth {
+		err := p.retryOnConflict(ctx, "endpoints", namespace, service, func() error {
+			return p.deleteEndpoints(namespace, service, ips)
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	if p.kind == KindEndpointSlice || p.kind == KindBoth {
+		err := p.retryOnConflict(ctx, "endpointslice", namespace, service, func() error {
+			return p.deleteEndpointSlice(namespace, service, ips)
+		})
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
+
+// retryOnConflict runs the given read-modify-write function and retries it
+// whenever the write failed because another writer, e.g. the
+// kube-controller-manager or another updater, modified or created the object
+// concurrently. The function has to read the current object on every call.
+// All attempts are traced as a single span, which includes the time spent
+// waiting for the client side rate limiter. The given name is the one of the
+// object, or of the service for EndpointSlices.
+func (p *Updater) retryOnConflict(ctx context.Context, resource, namespace, name string, fn func() error) error {
+	_, span := tracing.Start(ctx, "kubernetes write",
+		attribute.String("resource", resource),
+		attribute.String("namespace", namespace),
+		attribute.String("name", name),
+	)
+	defer span.End()
+
+	var attempts int
+	isConflict := func(err error) bool {
+		cause := microerror.Cause(err)
+		if errors.IsConflict(cause) || errors.IsAlreadyExists(cause) {
+			_ = p.logger.Log("debug", "object modified concurrently, retrying", "reason", cause.Error())
+			return true
+		}
+
+		return false
+	}
+
+	// The context is checked before every attempt, so that a cancelled
+	// context stops retrying conflicts.
+	attempt := func() error {
+		if ctx.Err() != nil {
+			return microerror.Mask(ctx.Err())
+		}
+
+		attempts++
+		return fn()
+	}
+
+	err := retry.OnError(retry.DefaultRetry, isConflict, attempt)
+	span.SetAttributes(attribute.Int("attempts", attempts))
+	if err != nil {
+		tracing.Fail(span, err)
+		return microerror.Mask(err)
+	}
+
+	return nil
 }
 
 func (p *Updater) AddAnnotations(namespace, service string, podName string, podIP net.IP) error {
+	start := time.Now()
+
+	err := p.addAnnotations(namespace, service, podName, podIP)
+	if err != nil {
+		updateDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
+		return microerror.Mask(err)
+	}
+	updateDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+
+	return nil
+}
+
+func (p *Updater) addAnnotations(namespace, service string, podName string, podIP net.IP) error {
 	kvmPod, err := p.k8sClient.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
 
 	if err != nil {
 		_ = p.logger.Log("error", fmt.Sprintf("Fetching kvm pod failed: %#v.", err))
 		return microerror.Mask(err)
 	}
-	patch := fmt.Sprintf("{\"metadata\":{\"annotations\":{\"%s\":\"%s\"}}}\n", annotationIp, podIP.String())
 
-	_, err = p.k8sClient.CoreV1().Pods(namespace).Patch(kvmPod.Name, types.StrategicMergePatchType, []byte(patch))
+	annotations := map[string]string{
+		AnnotationIP:        podIP.String(),
+		AnnotationHeartbeat: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	err = p.SetPodAnnotations(namespace, kvmPod.Name, annotations)
 	if err != nil {
 		_ = p.logger.Log("error", fmt.Sprintf("Updating pod annotation failed: %#v.", err))
 		return microerror.Mask(err)
@@ -75,3 +390,82 @@ func (p *Updater) AddAnnotations(namespace, service string, podName string, podI
 
 	return nil
 }
+
+// RemoveAnnotations removes the endpoint annotations from the given pod, which
+// withdraws the endpoint IP previously added using AddAnnotations.
+func (p *Updater) RemoveAnnotations(namespace, podName string) error {
+	err := p.RemovePodAnnotations(namespace, podName, AnnotationIP, AnnotationHeartbeat)
+	if err != nil {
+		_ = p.logger.Log("error", fmt.Sprintf("Removing pod annotation failed: %#v.", err))
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// SetPodAnnotations sets the given annotations on the given pod. Other
+// annotations of the pod are kept.
+func (p *Updater) SetPodAnnotations(namespace, podName string, annotations map[string]string) error {
+	values := map[string]*string{}
+	for k, v := range annotations {
+		v := v
+		values[k] = &v
+	}
+
+	err := p.patchPodAnnotations(namespace, podName, values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// RemovePodAnnotations removes the annotations with the given keys from the
+// given pod. Other annotations of the pod are kept.
+func (p *Updater) RemovePodAnnotations(namespace, podName string, keys ...string) error {
+	values := map[string]*string{}
+	for _, k := range keys {
+		values[k] = nil
+	}
+
+	err := p.patchPodAnnotations(namespace, podName, values)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// annotationsPatch is a strategic merge patch of the annotations of an
+// object. Nil values remove the respective annotation.
+type annotationsPatch struct {
+	Metadata annotationsPatchMetadata `json:"metadata"`
+}
+
+type annotationsPatchMetadata struct {
+	Annotations map[string]*string `json:"annotations"`
+}
+
+func (p *Updater) patchPodAnnotations(namespace, podName string, annotations map[string]*string) error {
+	patch := annotationsPatch{
+		Metadata: annotationsPatchMetadata{
+			Annotations: annotations,
+		},
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if p.dryRun {
+		return p.printDryRun("patch", "pod", namespace, podName, string(data)+"\n")
+	}
+
+	_, err = p.k8sClient.CoreV1().Pods(namespace).Patch(podName, types.StrategicMergePatchType, data)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
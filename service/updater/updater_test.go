@@ -0,0 +1,46 @@
+package updater
+
+import (
+	"testing"
+)
+
+func Test_ResourceKind(t *testing.T) {
+	testCases := []struct {
+		name            string
+		kind            string
+		expectedKind    string
+		expectedManaged bool
+	}{
+		{
+			name:            "case 0: annotations keep the default kind",
+			kind:            KindAnnotation,
+			expectedKind:    KindEndpoints,
+			expectedManaged: false,
+		},
+		{
+			name:            "case 1: both",
+			kind:            KindBoth,
+			expectedKind:    KindBoth,
+			expectedManaged: true,
+		},
+		{
+			name:            "case 2: endpoint slices",
+			kind:            KindEndpointSlice,
+			expectedKind:    KindEndpointSlice,
+			expectedManaged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, managed := ResourceKind(tc.kind)
+
+			if kind != tc.expectedKind {
+				t.Fatalf("expected kind %#q, got %#q", tc.expectedKind, kind)
+			}
+			if managed != tc.expectedManaged {
+				t.Fatalf("expected managed %t, got %t", tc.expectedManaged, managed)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package updater
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ResourceVersions returns the resource versions of the Endpoints and
+// EndpointSlices of the given service as last written or found up to date by
+// this updater, keyed by resource/name.
+func (p *Updater) ResourceVersions(namespace, service string) map[string]string {
+	p.versions.mutex.Lock()
+	defer p.versions.mutex.Unlock()
+
+	keys := []string{
+		versionKey("endpoints", namespace, service),
+		versionKey("endpointslice", namespace, EndpointSliceName(service, FamilyIPv4)),
+		versionKey("endpointslice", namespace, EndpointSliceName(service, FamilyIPv6)),
+	}
+
+	versions := map[string]string{}
+	for _, k := range keys {
+		v, ok := p.versions.versions[k]
+		if !ok {
+			continue
+		}
+		versions[v.name] = v.resourceVersion
+	}
+
+	if len(versions) == 0 {
+		return nil
+	}
+
+	return versions
+}
+
+// resourceVersions is shared between copies of an updater, so that versions
+// written by a copy are visible to the original.
+type resourceVersions struct {
+	mutex    sync.Mutex
+	versions map[string]version
+}
+
+func newResourceVersions() *resourceVersions {
+	return &resourceVersions{
+		versions: map[string]version{},
+	}
+}
+
+type version struct {
+	name            string
+	resourceVersion string
+}
+
+func (p *Updater) recordResourceVersion(resource, namespace, name, resourceVersion string) {
+	if resourceVersion == "" {
+		return
+	}
+
+	p.versions.mutex.Lock()
+	defer p.versions.mutex.Unlock()
+
+	p.versions.versions[versionKey(resource, namespace, name)] = version{
+		name:            resource + "/" + name,
+		resourceVersion: resourceVersion,
+	}
+}
+
+func versionKey(resource, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", resource, namespace, name)
+}
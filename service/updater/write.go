@@ -0,0 +1,383 @@
+package updater
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1alpha1 "k8s.io/api/discovery/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/rest"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/audit"
+)
+
+const (
+	// AnnotationUpdated is the annotation of Endpoints and EndpointSlices
+	// recording when they were last changed by an updater instance, formatted
+	// as RFC 3339 timestamp.
+	AnnotationUpdated = "endpoint.kvm.giantswarm.io/updated"
+	// FieldManager is the field manager owning the fields written using
+	// server-side apply.
+	FieldManager = "k8s-endpoint-updater"
+)
+
+const (
+	// PatchStrategyJSONPatch writes changes as JSON patch, which tests the
+	// resource version of the object read before. Concurrent modifications
+	// make the patch fail.
+	PatchStrategyJSONPatch = "json"
+	// PatchStrategyMerge writes changes as strategic merge patch, which only
+	// contains the changed fields. Concurrent modifications of other fields
+	// are kept, the last writer wins for the changed ones.
+	PatchStrategyMerge = "merge"
+	// PatchStrategyUpdate writes the whole modified object. Concurrent
+	// modifications are detected via the resource version and retried.
+	PatchStrategyUpdate = "update"
+)
+
+// writeEndpoints persists the given Endpoints object, which is a modified copy
+// of original. The object is created in case original is nil. Nothing is
+// written in case nothing changed.
+func (p *Updater) writeEndpoints(original, endpoints *corev1.Endpoints) error {
+	namespace := endpoints.Namespace
+
+	// Writing unchanged objects would only churn the resource version and
+	// wake up every kube-proxy in the cluster.
+	if original != nil && equality.Semantic.DeepEqual(original, endpoints) {
+		_ = p.logger.Log("debug", "already up to date", "resource", "endpoints", "namespace", namespace, "name", endpoints.Name)
+		p.recordResourceVersion("endpoints", namespace, endpoints.Name, original.ResourceVersion)
+		return nil
+	}
+
+	setUpdated(endpoints)
+
+	if p.serverSideApply {
+		// Only the fields we manage are applied. The labels and owner
+		// references are only ours in case we create the object.
+		applied := &corev1.Endpoints{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       "Endpoints",
+			},
+			ObjectMeta: appliedObjectMeta(endpoints.ObjectMeta),
+			Subsets:    endpoints.Subsets,
+		}
+		if original == nil {
+			applied.Labels = endpoints.Labels
+			applied.OwnerReferences = endpoints.OwnerReferences
+		}
+
+		resourceVersion, err := p.apply(p.k8sClient.CoreV1().RESTClient(), "endpoints", namespace, endpoints.Name, applied)
+		p.auditEndpoints(audit.OperationApply, original, endpoints, resourceVersion, err)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.recordResourceVersion("endpoints", namespace, endpoints.Name, resourceVersion)
+
+		return nil
+	}
+
+	if original == nil {
+		if p.dryRun {
+			return p.printDryRun("create", "endpoints", namespace, endpoints.Name, endpoints)
+		}
+
+		written, err := p.k8sClient.CoreV1().Endpoints(namespace).Create(endpoints)
+		if err != nil {
+			p.auditEndpoints(audit.OperationCreate, nil, endpoints, "", err)
+			return microerror.Mask(err)
+		}
+		p.auditEndpoints(audit.OperationCreate, nil, endpoints, written.ResourceVersion, nil)
+		p.recordResourceVersion("endpoints", namespace, written.Name, written.ResourceVersion)
+
+		return nil
+	}
+
+	switch p.patchStrategy {
+	case PatchStrategyJSONPatch, PatchStrategyMerge:
+		var ops []jsonPatchOperation
+		ops = append(ops, annotationsOperation(original.ObjectMeta, endpoints.ObjectMeta)...)
+		ops = append(ops, fieldOperation("/subsets", len(original.Subsets) == 0, len(endpoints.Subsets) == 0, endpoints.Subsets)...)
+
+		pt, data, err := p.newPatch(original.ResourceVersion, original, endpoints, corev1.Endpoints{}, ops)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if p.dryRun {
+			return p.printDryRun("patch", "endpoints", namespace, endpoints.Name, string(data)+"\n")
+		}
+
+		written, err := p.k8sClient.CoreV1().Endpoints(namespace).Patch(endpoints.Name, pt, data)
+		if err != nil {
+			p.auditEndpoints(audit.OperationPatch, original, endpoints, "", err)
+			return microerror.Mask(err)
+		}
+		p.auditEndpoints(audit.OperationPatch, original, endpoints, written.ResourceVersion, nil)
+		p.recordResourceVersion("endpoints", namespace, written.Name, written.ResourceVersion)
+	default:
+		if p.dryRun {
+			return p.printDryRun("update", "endpoints", namespace, endpoints.Name, endpoints)
+		}
+
+		written, err := p.k8sClient.CoreV1().Endpoints(namespace).Update(endpoints)
+		if err != nil {
+			p.auditEndpoints(audit.OperationUpdate, original, endpoints, "", err)
+			return microerror.Mask(err)
+		}
+		p.auditEndpoints(audit.OperationUpdate, original, endpoints, written.ResourceVersion, nil)
+		p.recordResourceVersion("endpoints", namespace, written.Name, written.ResourceVersion)
+	}
+
+	return nil
+}
+
+// writeEndpointSlice persists the given EndpointSlice, which is a modified copy
+// of original. The slice is created in case original is nil. Nothing is
+// written in case nothing changed.
+func (p *Updater) writeEndpointSlice(original, slice *discoveryv1alpha1.EndpointSlice) error {
+	namespace := slice.Namespace
+
+	if original != nil && equality.Semantic.DeepEqual(original, slice) {
+		_ = p.logger.Log("debug", "already up to date", "resource", "endpointslice", "namespace", namespace, "name", slice.Name)
+		p.recordResourceVersion("endpointslice", namespace, slice.Name, original.ResourceVersion)
+		return nil
+	}
+
+	setUpdated(slice)
+
+	if p.serverSideApply {
+		// Our slices are created by us, so all of their labels are ours.
+		meta := appliedObjectMeta(slice.ObjectMeta)
+		meta.Labels = slice.Labels
+		if original == nil {
+			meta.OwnerReferences = slice.OwnerReferences
+		}
+
+		applied := &discoveryv1alpha1.EndpointSlice{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: discoveryv1alpha1.SchemeGroupVersion.String(),
+				Kind:       "EndpointSlice",
+			},
+			ObjectMeta:  meta,
+			AddressType: slice.AddressType,
+			Endpoints:   slice.Endpoints,
+			Ports:       slice.Ports,
+		}
+
+		resourceVersion, err := p.apply(p.k8sClient.DiscoveryV1alpha1().RESTClient(), "endpointslices", namespace, slice.Name, applied)
+		p.auditEndpointSlice(audit.OperationApply, original, slice, resourceVersion, err)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		p.recordResourceVersion("endpointslice", namespace, slice.Name, resourceVersion)
+
+		return nil
+	}
+
+	if original == nil {
+		if p.dryRun {
+			return p.printDryRun("create", "endpointslice", namespace, slice.Name, slice)
+		}
+
+		written, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Create(slice)
+		if err != nil {
+			p.auditEndpointSlice(audit.OperationCreate, nil, slice, "", err)
+			return microerror.Mask(err)
+		}
+		p.auditEndpointSlice(audit.OperationCreate, nil, slice, written.ResourceVersion, nil)
+		p.recordResourceVersion("endpointslice", namespace, written.Name, written.ResourceVersion)
+
+		return nil
+	}
+
+	switch p.patchStrategy {
+	case PatchStrategyJSONPatch, PatchStrategyMerge:
+		var ops []jsonPatchOperation
+		ops = append(ops, annotationsOperation(original.ObjectMeta, slice.ObjectMeta)...)
+		ops = append(ops, fieldOperation("/endpoints", len(original.Endpoints) == 0, len(slice.Endpoints) == 0, slice.Endpoints)...)
+		ops = append(ops, fieldOperation("/ports", len(original.Ports) == 0, len(slice.Ports) == 0, slice.Ports)...)
+
+		pt, data, err := p.newPatch(original.ResourceVersion, original, slice, discoveryv1alpha1.EndpointSlice{}, ops)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+
+		if p.dryRun {
+			return p.printDryRun("patch", "endpointslice", namespace, slice.Name, string(data)+"\n")
+		}
+
+		written, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Patch(slice.Name, pt, data)
+		if err != nil {
+			p.auditEndpointSlice(audit.OperationPatch, original, slice, "", err)
+			return microerror.Mask(err)
+		}
+		p.auditEndpointSlice(audit.OperationPatch, original, slice, written.ResourceVersion, nil)
+		p.recordResourceVersion("endpointslice", namespace, written.Name, written.ResourceVersion)
+	default:
+		if p.dryRun {
+			return p.printDryRun("update", "endpointslice", namespace, slice.Name, slice)
+		}
+
+		written, err := p.k8sClient.DiscoveryV1alpha1().EndpointSlices(namespace).Update(slice)
+		if err != nil {
+			p.auditEndpointSlice(audit.OperationUpdate, original, slice, "", err)
+			return microerror.Mask(err)
+		}
+		p.auditEndpointSlice(audit.OperationUpdate, original, slice, written.ResourceVersion, nil)
+		p.recordResourceVersion("endpointslice", namespace, written.Name, written.ResourceVersion)
+	}
+
+	return nil
+}
+
+// apply sends the given object as server-side apply patch using our field
+// manager. Conflicts with other field managers are not forced, but returned
+// as conflict errors. The resource version of the applied object is returned.
+func (p *Updater) apply(client rest.Interface, resource, namespace, name string, obj interface{}) (string, error) {
+	if p.dryRun {
+		return "", p.printDryRun("apply", resource, namespace, name, obj)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	raw, err := client.Patch(types.ApplyPatchType).
+		Namespace(namespace).
+		Resource(resource).
+		Name(name).
+		Param("fieldManager", FieldManager).
+		Body(data).
+		Do().
+		Raw()
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	var applied metav1.PartialObjectMetadata
+	err = json.Unmarshal(raw, &applied)
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	return applied.ResourceVersion, nil
+}
+
+// appliedObjectMeta returns the metadata we manage of the given object. The
+// resource version is kept, so that concurrent modifications are detected.
+// The ownership and update annotations are the only annotations we manage.
+func appliedObjectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	applied := metav1.ObjectMeta{
+		Name:            meta.Name,
+		Namespace:       meta.Namespace,
+		ResourceVersion: meta.ResourceVersion,
+	}
+
+	for _, k := range []string{AnnotationAdded, AnnotationOwners, AnnotationUpdated} {
+		v, ok := meta.Annotations[k]
+		if !ok {
+			continue
+		}
+		if applied.Annotations == nil {
+			applied.Annotations = map[string]string{}
+		}
+		applied.Annotations[k] = v
+	}
+
+	return applied
+}
+
+// setUpdated records the current time as the last change of the given
+// object.
+func setUpdated(meta metav1.Object) {
+	annotations := meta.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[AnnotationUpdated] = time.Now().UTC().Format(time.RFC3339)
+
+	meta.SetAnnotations(annotations)
+}
+
+// LastUpdated returns when the given object was last changed by an updater
+// instance. The zero time is returned in case this is unknown.
+func LastUpdated(meta metav1.Object) time.Time {
+	t, err := time.Parse(time.RFC3339, meta.GetAnnotations()[AnnotationUpdated])
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// newPatch creates the patch turning original into modified using the
+// configured patch strategy. The given operations describe the change as JSON
+// patch, the data struct is used to compute the strategic merge patch.
+func (p *Updater) newPatch(resourceVersion string, original, modified, dataStruct interface{}, ops []jsonPatchOperation) (types.PatchType, []byte, error) {
+	if p.patchStrategy == PatchStrategyJSONPatch {
+		// The test operation makes the patch fail in case the object has
+		// been modified since it was read.
+		if resourceVersion != "" {
+			test := jsonPatchOperation{Op: "test", Path: "/metadata/resourceVersion", Value: resourceVersion}
+			ops = append([]jsonPatchOperation{test}, ops...)
+		}
+
+		data, err := json.Marshal(ops)
+		if err != nil {
+			return "", nil, microerror.Mask(err)
+		}
+
+		return types.JSONPatchType, data, nil
+	}
+
+	originalData, err := json.Marshal(original)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+	modifiedData, err := json.Marshal(modified)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	data, err := strategicpatch.CreateTwoWayMergePatch(originalData, modifiedData, dataStruct)
+	if err != nil {
+		return "", nil, microerror.Mask(err)
+	}
+
+	return types.StrategicMergePatchType, data, nil
+}
+
+// jsonPatchOperation is a single operation of a JSON patch as defined by RFC
+// 6902.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// fieldOperation returns the JSON patch operations setting the field at the
+// given path to the given value, or removing it in case it became empty.
+func fieldOperation(path string, originalEmpty, modifiedEmpty bool, value interface{}) []jsonPatchOperation {
+	switch {
+	case originalEmpty && modifiedEmpty:
+		return nil
+	case modifiedEmpty:
+		return []jsonPatchOperation{{Op: "remove", Path: path}}
+	default:
+		return []jsonPatchOperation{{Op: "add", Path: path, Value: value}}
+	}
+}
+
+// annotationsOperation returns the JSON patch operations updating the
+// annotations of an object, which are guarded by the resource version test.
+func annotationsOperation(original, modified metav1.ObjectMeta) []jsonPatchOperation {
+	return fieldOperation("/metadata/annotations", len(original.Annotations) == 0, len(modified.Annotations) == 0, modified.Annotations)
+}
@@ -0,0 +1,126 @@
+package updater
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_fieldOperation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		originalEmpty bool
+		modifiedEmpty bool
+		expected      []jsonPatchOperation
+	}{
+		{
+			name:          "case 0: empty before and after",
+			originalEmpty: true,
+			modifiedEmpty: true,
+			expected:      nil,
+		},
+		{
+			name:          "case 1: field became empty",
+			originalEmpty: false,
+			modifiedEmpty: true,
+			expected:      []jsonPatchOperation{{Op: "remove", Path: "/subsets"}},
+		},
+		{
+			name:          "case 2: field was empty",
+			originalEmpty: true,
+			modifiedEmpty: false,
+			expected:      []jsonPatchOperation{{Op: "add", Path: "/subsets", Value: "v"}},
+		},
+		{
+			name:          "case 3: field changed",
+			originalEmpty: false,
+			modifiedEmpty: false,
+			expected:      []jsonPatchOperation{{Op: "add", Path: "/subsets", Value: "v"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ops := fieldOperation("/subsets", tc.originalEmpty, tc.modifiedEmpty, "v")
+
+			if len(ops) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, ops)
+			}
+			for i := range ops {
+				if ops[i] != tc.expected[i] {
+					t.Fatalf("expected %v, got %v", tc.expected, ops)
+				}
+			}
+		})
+	}
+}
+
+func Test_Updater_newPatch(t *testing.T) {
+	original := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "master",
+			ResourceVersion: "7",
+		},
+		Subsets: []corev1.EndpointSubset{
+			{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}},
+		},
+	}
+	modified := original.DeepCopy()
+	modified.Annotations = map[string]string{AnnotationUpdated: "2020-01-01T00:00:00Z"}
+	modified.Subsets = nil
+
+	ops := append(
+		annotationsOperation(original.ObjectMeta, modified.ObjectMeta),
+		fieldOperation("/subsets", len(original.Subsets) == 0, len(modified.Subsets) == 0, modified.Subsets)...,
+	)
+
+	testCases := []struct {
+		name            string
+		patchStrategy   string
+		resourceVersion string
+		expectedType    types.PatchType
+		expectedData    string
+	}{
+		{
+			name:            "case 0: JSON patch tests the resource version",
+			patchStrategy:   PatchStrategyJSONPatch,
+			resourceVersion: "7",
+			expectedType:    types.JSONPatchType,
+			expectedData:    `[{"op":"test","path":"/metadata/resourceVersion","value":"7"},{"op":"add","path":"/metadata/annotations","value":{"endpoint.kvm.giantswarm.io/updated":"2020-01-01T00:00:00Z"}},{"op":"remove","path":"/subsets"}]`,
+		},
+		{
+			name:            "case 1: JSON patch without resource version",
+			patchStrategy:   PatchStrategyJSONPatch,
+			resourceVersion: "",
+			expectedType:    types.JSONPatchType,
+			expectedData:    `[{"op":"add","path":"/metadata/annotations","value":{"endpoint.kvm.giantswarm.io/updated":"2020-01-01T00:00:00Z"}},{"op":"remove","path":"/subsets"}]`,
+		},
+		{
+			name:            "case 2: strategic merge patch only contains the changed fields",
+			patchStrategy:   PatchStrategyMerge,
+			resourceVersion: "7",
+			expectedType:    types.StrategicMergePatchType,
+			expectedData:    `{"metadata":{"annotations":{"endpoint.kvm.giantswarm.io/updated":"2020-01-01T00:00:00Z"}},"subsets":null}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Updater{patchStrategy: tc.patchStrategy}
+
+			pt, data, err := p.newPatch(tc.resourceVersion, original, modified, corev1.Endpoints{}, ops)
+			if err != nil {
+				t.Fatalf("expected no error, got %#v", err)
+			}
+
+			if pt != tc.expectedType {
+				t.Fatalf("expected patch type %s, got %s", tc.expectedType, pt)
+			}
+			if string(data) != tc.expectedData {
+				t.Fatalf("expected patch %s, got %s", tc.expectedData, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package harness
+
+import "github.com/giantswarm/microerror"
+
+var executionFailedError = microerror.New("execution failed")
+
+// IsExecutionFailed asserts executionFailedError.
+func IsExecutionFailed(err error) bool {
+	return microerror.Cause(err) == executionFailedError
+}
+
+var invalidConfigError = microerror.New("invalid config")
+
+// IsInvalidConfig asserts invalidConfigError.
+func IsInvalidConfig(err error) bool {
+	return microerror.Cause(err) == invalidConfigError
+}
+
+var notStartedError = microerror.New("not started")
+
+// IsNotStarted asserts notStartedError.
+func IsNotStarted(err error) bool {
+	return microerror.Cause(err) == notStartedError
+}
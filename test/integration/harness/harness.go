@@ -0,0 +1,256 @@
+// Package harness implements an envtest based control plane which can be used
+// to run the real updater code paths against an actual Kubernetes API server.
+// The harness is meant to be reused by provider and updater implementations
+// for their own integration tests.
+//
+// The control plane binaries (etcd, kube-apiserver) are looked up the way
+// envtest does it, that is below KUBEBUILDER_ASSETS or
+// /usr/local/kubebuilder/bin.
+package harness
+
+import (
+	"sync"
+
+	"github.com/giantswarm/microerror"
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/giantswarm/k8s-endpoint-updater/service/updater"
+)
+
+// Config represents the configuration used to create a new harness.
+type Config struct {
+	// Dependencies.
+	Logger micrologger.Logger
+
+	// Settings.
+
+	// AttachControlPlaneOutput attaches the etcd and kube-apiserver output to
+	// os.Stdout and os.Stderr.
+	AttachControlPlaneOutput bool
+}
+
+// DefaultConfig provides a default configuration to create a new harness by
+// best effort.
+func DefaultConfig() Config {
+	return Config{
+		// Dependencies.
+		Logger: nil,
+
+		// Settings.
+		AttachControlPlaneOutput: false,
+	}
+}
+
+// New creates a new harness. The control plane is not started until Start is
+// called.
+func New(config Config) (*Harness, error) {
+	// Dependencies.
+	if config.Logger == nil {
+		return nil, microerror.Maskf(invalidConfigError, "config.Logger must not be empty")
+	}
+
+	newHarness := &Harness{
+		// Dependencies.
+		logger: config.Logger,
+
+		// Internals.
+		environment: &envtest.Environment{
+			AttachControlPlaneOutput: config.AttachControlPlaneOutput,
+		},
+		k8sClient:  nil,
+		restConfig: nil,
+	}
+
+	return newHarness, nil
+}
+
+type Harness struct {
+	// Dependencies.
+	logger micrologger.Logger
+
+	// Internals.
+	environment *envtest.Environment
+	k8sClient   kubernetes.Interface
+	restConfig  *rest.Config
+}
+
+// Start brings up the control plane and creates the Kubernetes client used by
+// the harness.
+func (h *Harness) Start() error {
+	_ = h.logger.Log("debug", "starting control plane")
+
+	restConfig, err := h.environment.Start()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	h.k8sClient = k8sClient
+	h.restConfig = restConfig
+
+	_ = h.logger.Log("debug", "started control plane", "host", restConfig.Host)
+
+	return nil
+}
+
+// Stop tears down the control plane.
+func (h *Harness) Stop() error {
+	_ = h.logger.Log("debug", "stopping control plane")
+
+	err := h.environment.Stop()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	_ = h.logger.Log("debug", "stopped control plane")
+
+	return nil
+}
+
+// K8sClient returns the client connected to the control plane. It is only
+// available after Start returned successfully.
+func (h *Harness) K8sClient() kubernetes.Interface {
+	return h.k8sClient
+}
+
+// RestConfig returns the rest config of the control plane. It is only
+// available after Start returned successfully.
+func (h *Harness) RestConfig() *rest.Config {
+	return h.restConfig
+}
+
+// Updater creates a new updater backed by the control plane of the harness.
+func (h *Harness) Updater() (*updater.Updater, error) {
+	if h.k8sClient == nil {
+		return nil, microerror.Maskf(notStartedError, "harness must be started")
+	}
+
+	updaterConfig := updater.DefaultConfig()
+
+	updaterConfig.K8sClient = h.k8sClient
+	updaterConfig.Logger = h.logger
+
+	newUpdater, err := updater.New(updaterConfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return newUpdater, nil
+}
+
+// EnsureNamespace creates the given namespace in case it does not exist yet.
+func (h *Harness) EnsureNamespace(name string) error {
+	if h.k8sClient == nil {
+		return microerror.Maskf(notStartedError, "harness must be started")
+	}
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+
+	_, err := h.k8sClient.CoreV1().Namespaces().Create(namespace)
+	if errors.IsAlreadyExists(err) {
+		// fall through
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return nil
+}
+
+// EnsurePod creates a minimal pod with the given name in the given namespace
+// in case it does not exist yet. The namespace is created as well if
+// necessary. The pod is never scheduled since the control plane of the
+// harness does not run any nodes.
+func (h *Harness) EnsurePod(namespace, name string) (*corev1.Pod, error) {
+	err := h.EnsureNamespace(namespace)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "k8s-endpoint-updater",
+					Image: "quay.io/giantswarm/k8s-endpoint-updater",
+				},
+			},
+		},
+	}
+
+	created, err := h.k8sClient.CoreV1().Pods(namespace).Create(pod)
+	if errors.IsAlreadyExists(err) {
+		created, err = h.k8sClient.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return created, nil
+}
+
+// PodAnnotation returns the value of the given annotation of the given pod.
+// An executionFailedError is returned in case the annotation is not set.
+func (h *Harness) PodAnnotation(namespace, name, key string) (string, error) {
+	if h.k8sClient == nil {
+		return "", microerror.Maskf(notStartedError, "harness must be started")
+	}
+
+	pod, err := h.k8sClient.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	value, ok := pod.GetAnnotations()[key]
+	if !ok {
+		return "", microerror.Maskf(executionFailedError, "annotation %#q not found on pod %#q", key, name)
+	}
+
+	return value, nil
+}
+
+// Concurrently executes the given action n times in parallel and returns the
+// first error observed, if any. It is used to provoke write conflicts against
+// the control plane.
+func (h *Harness) Concurrently(n int, action func(i int) error) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- action(i)
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+//go:build integration
+// +build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/giantswarm/micrologger"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/giantswarm/k8s-endpoint-updater/test/integration/harness"
+)
+
+const (
+	namespace = "integration"
+)
+
+// h is the harness shared by all tests. It is nil when KUBEBUILDER_ASSETS is
+// not set, in which case all tests are skipped.
+var h *harness.Harness
+
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		os.Exit(m.Run())
+	}
+
+	logger, err := micrologger.New(micrologger.Config{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating logger failed: %#v\n", err)
+		os.Exit(1)
+	}
+
+	harnessConfig := harness.DefaultConfig()
+	harnessConfig.Logger = logger
+
+	h, err = harness.New(harnessConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating harness failed: %#v\n", err)
+		os.Exit(1)
+	}
+
+	err = h.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "starting harness failed: %#v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	err = h.Stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stopping harness failed: %#v\n", err)
+	}
+
+	os.Exit(code)
+}
+
+func Test_Updater_CreatePatchDelete(t *testing.T) {
+	ctx := context.Background()
+	service := ensureService(t, "create-patch-delete")
+
+	u, err := h.Updater()
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	err = u.Create(ctx, namespace, service, ips("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	assertAddresses(t, service, "10.0.0.1")
+
+	err = u.Replace(ctx, namespace, service, ips("10.0.0.1"), ips("10.0.0.2"), nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	assertAddresses(t, service, "10.0.0.2")
+
+	err = u.Delete(ctx, namespace, service, ips("10.0.0.2"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	assertAddresses(t, service)
+}
+
+func Test_Updater_Ports(t *testing.T) {
+	ctx := context.Background()
+	service := ensureService(t, "ports")
+
+	u, err := h.Updater()
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	err = u.Create(ctx, namespace, service, ips("10.0.0.1"))
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	endpoints, err := h.K8sClient().CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+	for _, s := range endpoints.Subsets {
+		if len(s.Ports) != 1 || s.Ports[0].Port != 6443 {
+			t.Fatalf("expected port 6443, got %#v", s.Ports)
+		}
+	}
+}
+
+func Test_Updater_Conflict(t *testing.T) {
+	ctx := context.Background()
+	service := ensureService(t, "conflict")
+
+	u, err := h.Updater()
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	// All writers read the same resource version, so all but one of them run
+	// into a conflict and have to retry.
+	n := 3
+	err = h.Concurrently(n, func(i int) error {
+		return u.Create(ctx, namespace, service, ips(fmt.Sprintf("10.0.1.%d", i+1)))
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	var expected []string
+	for i := 0; i < n; i++ {
+		expected = append(expected, fmt.Sprintf("10.0.1.%d", i+1))
+	}
+	assertAddresses(t, service, expected...)
+}
+
+// ensureService creates a service with the given name along with its empty
+// Endpoints object, like the endpoints controller would, and returns the
+// name. Tests are skipped when the harness is not running.
+func ensureService(t *testing.T, name string) string {
+	t.Helper()
+
+	if h == nil {
+		t.Skip("KUBEBUILDER_ASSETS not set")
+	}
+
+	err := h.EnsureNamespace(namespace)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "https",
+					Port:       443,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(6443),
+				},
+			},
+		},
+	}
+	_, err = h.K8sClient().CoreV1().Services(namespace).Create(service)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	_, err = h.K8sClient().CoreV1().Endpoints(namespace).Create(endpoints)
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	return name
+}
+
+// assertAddresses fails the test unless the Endpoints of the given service
+// list exactly the given ready addresses.
+func assertAddresses(t *testing.T, service string, expected ...string) {
+	t.Helper()
+
+	endpoints, err := h.K8sClient().CoreV1().Endpoints(namespace).Get(service, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %#v", err)
+	}
+
+	var actual []string
+	for _, s := range endpoints.Subsets {
+		for _, a := range s.Addresses {
+			actual = append(actual, a.IP)
+		}
+	}
+	sort.Strings(actual)
+	sort.Strings(expected)
+
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Fatalf("expected addresses %v, got %v", expected, actual)
+	}
+}
+
+func ips(s ...string) []net.IP {
+	var ips []net.IP
+	for _, ip := range s {
+		ips = append(ips, net.ParseIP(ip))
+	}
+
+	return ips
+}